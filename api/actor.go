@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type actorKey struct{}
+
+// Actor
+// Identifies who made a request, recorded in the audit log.
+type Actor struct {
+	// Basic auth user, empty for unauthenticated or background work.
+	Name string
+	// Client IP address, the forwarded address behind a proxy.
+	IP string
+	// Client User-Agent header.
+	UserAgent string
+}
+
+// ConfigureActor
+// Attaches middleware carrying the request Actor
+// through the request context.
+// Must be attached after ConfigureBasicAuth.
+func ConfigureActor(app *fiber.App) {
+	app.Use(func(ctx *fiber.Ctx) error {
+		name, _ := ctx.Locals("username").(string)
+		ctx.SetUserContext(WithActor(ctx.UserContext(), Actor{
+			Name:      name,
+			IP:        getRequestIP(ctx),
+			UserAgent: ctx.Get(fiber.HeaderUserAgent),
+		}))
+		return ctx.Next()
+	})
+}
+
+// WithActor
+// Returns a copy of ctx carrying actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext
+// Returns the Actor carried by ctx, the zero Actor when absent.
+func ActorFromContext(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/basicauth"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_ConfigureActor
+// The authenticated user, client IP and user agent
+// should be carried through the request context.
+func Test_ConfigureActor(t *testing.T) {
+	app := fiber.New()
+	app.Use(basicauth.New(basicauth.Config{Users: map[string]string{"admin": "secret"}}))
+	ConfigureActor(app)
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		actor := ActorFromContext(ctx.UserContext())
+		return ctx.SendString(actor.Name + " " + actor.IP + " " + actor.UserAgent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("admin", "secret")
+	req.Header.Set(fiber.HeaderUserAgent, "curl/7.79.1")
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "admin 0.0.0.0 curl/7.79.1", string(body))
+
+	// Behind a proxy the forwarded client address is recorded.
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("admin", "secret")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	resp, err = app.Test(req)
+	assert.Nil(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, "admin 203.0.113.7 ", string(body))
+
+	assert.Equal(t, Actor{}, ActorFromContext(req.Context()))
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"crypto/subtle"
 	"os"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,20 +13,33 @@ import (
 func ConfigureBasicAuth(app *fiber.App) {
 	baseUser := os.Getenv("MDSNIPS_USER")
 	basePass := os.Getenv("MDSNIPS_PASS")
-	users := map[string]string{
-		baseUser: basePass,
+	app.Use(basicAuth(baseUser, basePass))
+}
+
+// AdminRouter
+// Returns a router under `/admin` guarded by basic auth with the
+// admin credentials, which the API user cannot pass. Returns nil
+// when no admin user is configured, leaving the admin routes disabled.
+// Register it before ConfigureBasicAuth when app also serves the API.
+func AdminRouter(app fiber.Router, user string, pass string) fiber.Router {
+	if user == "" {
+		return nil
 	}
-	app.Use(basicauth.New(basicauth.Config{
-		Users: users,
+	return app.Group("/admin", basicAuth(user, pass))
+}
+
+// basicAuth
+// Returns gofiber basic auth middleware accepting user and pass.
+func basicAuth(user string, pass string) fiber.Handler {
+	return basicauth.New(basicauth.Config{
 		Realm: "Forbidden",
-		Authorizer: func(user, pass string) bool {
-			creds, ok := users[user]
-			if ok {
-				if pass == creds {
-					return true
-				}
-			}
-			return false
+		Authorizer: func(u, p string) bool {
+			return subtle.ConstantTimeCompare([]byte(u), []byte(user)) == 1 &&
+				subtle.ConstantTimeCompare([]byte(p), []byte(pass)) == 1
+		},
+		Unauthorized: func(ctx *fiber.Ctx) error {
+			ctx.Set(fiber.HeaderWWWAuthenticate, `basic realm="Forbidden"`)
+			return fiber.ErrUnauthorized
 		},
-	}))
+	})
 }
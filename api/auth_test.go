@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_AdminRouter
+// Admin routes take the admin credentials, which the API
+// routes do not accept, and are disabled without an admin user.
+func Test_AdminRouter(t *testing.T) {
+	ok := func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusNoContent)
+	}
+
+	app := fiber.New()
+	assert.Nil(t, AdminRouter(app, "", ""))
+	AdminRouter(app, "admin", "secret").Get("/audit", ok)
+	app.Use(basicAuth("user", "pass"))
+	app.Get("/md", ok)
+
+	for _, tc := range []struct {
+		path, user, pass string
+		status           int
+	}{
+		{"/admin/audit", "admin", "secret", http.StatusNoContent},
+		{"/admin/audit", "user", "pass", http.StatusUnauthorized},
+		{"/admin/audit", "admin", "wrong", http.StatusUnauthorized},
+		{"/md", "user", "pass", http.StatusNoContent},
+		{"/md", "admin", "secret", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.SetBasicAuth(tc.user, tc.pass)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.user+" "+tc.path)
+	}
+}
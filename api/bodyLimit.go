@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ConfigureBodyLimit
+// Attaches middleware rejecting request bodies over limit with 413,
+// unless large reports the request may carry a bigger body.
+// Those are bounded by the server BodyLimit instead, which is
+// enforced before routing and so must fit the largest request.
+func ConfigureBodyLimit(app *fiber.App, limit int, large func(*fiber.Ctx) bool) {
+	app.Use(func(ctx *fiber.Ctx) error {
+		if len(ctx.Body()) > limit && !large(ctx) {
+			return fiber.NewError(http.StatusRequestEntityTooLarge, "Request Body Too Large")
+		}
+		return ctx.Next()
+	})
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_BodyLimit
+// Bodies over the limit are rejected, except on large routes.
+func Test_BodyLimit(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler, BodyLimit: 64})
+	ConfigureBodyLimit(app, 8, func(ctx *fiber.Ctx) bool { return ctx.Path() == "/upload" })
+	ok := func(ctx *fiber.Ctx) error { return ctx.SendStatus(http.StatusNoContent) }
+	app.Post("/upload", ok)
+	app.Post("/md", ok)
+
+	for path, status := range map[string]int{"/upload": http.StatusNoContent, "/md": http.StatusRequestEntityTooLarge} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, path, strings.NewReader(strings.Repeat("x", 16))))
+		assert.Nil(t, err)
+		assert.Equal(t, status, resp.StatusCode, path)
+	}
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/md", strings.NewReader("small")))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
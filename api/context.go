@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/logging"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// disconnectPoll Interval the connection of an in-flight request
+// is checked for the client having disconnected.
+const disconnectPoll = 200 * time.Millisecond
+
+// ConfigureRequestContext
+// Attaches middleware deriving the request context returned by
+// ctx.UserContext(). The context is bounded by timeout and cancelled
+// once the handler chain returns or the client disconnects. It is
+// deliberately not tied to server shutdown, so in-flight requests can drain.
+// Disconnects are only noticed on plain TCP connections of unix servers.
+func ConfigureRequestContext(app *fiber.App, timeout time.Duration) {
+	app.Use(func(ctx *fiber.Ctx) error {
+		reqCtx := context.Background()
+		var cancel context.CancelFunc
+		if timeout > 0 {
+			reqCtx, cancel = context.WithTimeout(reqCtx, timeout)
+		} else {
+			reqCtx, cancel = context.WithCancel(reqCtx)
+		}
+		defer cancel()
+		go cancelOnDisconnect(reqCtx, ctx.Context().Conn(), cancel)
+
+		ctx.SetUserContext(reqCtx)
+		return ctx.Next()
+	})
+}
+
+// cancelOnDisconnect
+// Calls cancel once the client closes conn, until ctx is done.
+func cancelOnDisconnect(ctx context.Context, conn net.Conn, cancel context.CancelFunc) {
+	ticker := time.NewTicker(disconnectPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if peerClosed(conn) {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// Detach
+// Returns a context carrying the request id and trace of ctx
+// without its deadline or cancellation, for work outliving the
+// handler chain such as streamed response bodies.
+func Detach(ctx context.Context) context.Context {
+	detached := logging.WithRequestID(context.Background(), logging.RequestID(ctx))
+	return trace.ContextWithSpanContext(detached, trace.SpanContextFromContext(ctx))
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_RequestContext
+// Handlers should receive a deadline bound context,
+// cancelled once the handler chain returns.
+func Test_RequestContext(t *testing.T) {
+	app := fiber.New()
+	ConfigureRequestContext(app, time.Second)
+
+	var reqCtx context.Context
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		reqCtx = ctx.UserContext()
+		_, ok := reqCtx.Deadline()
+		assert.True(t, ok)
+		assert.Nil(t, reqCtx.Err())
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, context.Canceled, reqCtx.Err())
+}
+
+// Test_RequestContextDisconnect
+// The request context is cancelled when the client
+// disconnects while the handler is still running.
+func Test_RequestContextDisconnect(t *testing.T) {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	ConfigureRequestContext(app, time.Minute)
+
+	started, cancelled := make(chan struct{}), make(chan error, 1)
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		close(started)
+		select {
+		case <-ctx.UserContext().Done():
+			cancelled <- ctx.UserContext().Err()
+		case <-time.After(5 * time.Second):
+			cancelled <- nil
+		}
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.Nil(t, err)
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	assert.Nil(t, err)
+	<-started
+	conn.Close()
+
+	assert.Equal(t, context.Canceled, <-cancelled)
+}
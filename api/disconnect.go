@@ -0,0 +1,34 @@
+//go:build !windows && !plan9 && !js
+// +build !windows,!plan9,!js
+
+package api
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerClosed
+// Reports whether the client closed conn, peeking at its socket
+// without consuming pipelined requests. Connections without a
+// socket, such as TLS or in-memory ones, are never reported closed.
+func peerClosed(conn net.Conn) bool {
+	sysConn, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	raw, err := sysConn.SyscallConn()
+	if err != nil {
+		return false
+	}
+	closed := false
+	raw.Read(func(fd uintptr) bool {
+		var buf [1]byte
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		// A zero length read is the end of the stream, a reset is an error.
+		closed = (n == 0 && err == nil) || err == syscall.ECONNRESET
+		// Never wait for the socket to become readable.
+		return true
+	})
+	return closed
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9 || js
+// +build windows plan9 js
+
+package api
+
+import "net"
+
+// peerClosed
+// Disconnects are not detected on this platform.
+func peerClosed(conn net.Conn) bool {
+	return false
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/logging"
+)
+
+// ProblemContentType RFC 7807 media type for error responses.
+const ProblemContentType = "application/problem+json"
+
+// ErrorHandler
+// fiber.ErrorHandler emitting every error as an ErrorResponse.
+// *fiber.Error keeps its status and message, ValidationErrors
+// are reported field by field, anything else is a 500.
+func ErrorHandler(ctx *fiber.Ctx, err error) error {
+	problem := ErrorResponse{
+		Type:     "about:blank",
+		Status:   http.StatusInternalServerError,
+		Detail:   "An unexpected error occurred",
+		Instance: ctx.OriginalURL(),
+	}
+
+	var fiberErr *fiber.Error
+	var validationErrs ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		problem.Status = http.StatusBadRequest
+		problem.Detail = validationErrs.Error()
+		problem.Errors = validationErrs
+	case errors.As(err, &fiberErr):
+		problem.Status = fiberErr.Code
+		problem.Detail = fiberErr.Message
+	default:
+		logging.FromContext(ctx.UserContext()).
+			WithError(err).
+			WithField("path", ctx.Path()).
+			Error("Unhandled error")
+	}
+	problem.Title = http.StatusText(problem.Status)
+
+	ctx.Status(problem.Status)
+	if err := ctx.JSON(problem); err != nil {
+		return err
+	}
+	ctx.Set(fiber.HeaderContentType, ProblemContentType)
+	return nil
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_ErrorHandler
+// Every error should be rendered as problem+json.
+func Test_ErrorHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		status   int
+		detail   string
+		numField int
+	}{
+		{"fiber error", fiber.NewError(http.StatusNotFound, "Markdown Snippet Not Found"), http.StatusNotFound, "Markdown Snippet Not Found", 0},
+		{"validation", ValidationErrors{{FailedField: "CreateMDReq.Title", Tag: "required"}}, http.StatusBadRequest, "CreateMDReq.Title failed `required` validation", 1},
+		{"unknown", errors.New("connection reset"), http.StatusInternalServerError, "An unexpected error occurred", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+			app.Get("/md", func(ctx *fiber.Ctx) error {
+				return tt.err
+			})
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/md", nil))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Equal(t, ProblemContentType, resp.Header.Get(fiber.HeaderContentType))
+
+			problem := new(ErrorResponse)
+			assert.Nil(t, json.NewDecoder(resp.Body).Decode(problem))
+			assert.Equal(t, "about:blank", problem.Type)
+			assert.Equal(t, http.StatusText(tt.status), problem.Title)
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Equal(t, tt.detail, problem.Detail)
+			assert.Equal(t, "/md", problem.Instance)
+			assert.Len(t, problem.Errors, tt.numField)
+		})
+	}
+}
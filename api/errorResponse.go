@@ -1,7 +1,18 @@
 package api
 
 // ErrorResponse
+// RFC 7807 problem details returned for every error.
 type ErrorResponse struct {
-	Code    int32  `json:"code,omitempty" format:"int" example:"400"`
-	Message string `json:"message,omitempty" format:"string"`
+	// URI reference identifying the problem type.
+	Type string `json:"type" format:"uri" example:"about:blank"`
+	// Short summary of the problem type.
+	Title string `json:"title" example:"Bad Request"`
+	// HTTP status code.
+	Status int `json:"status" format:"int" example:"400"`
+	// Explanation specific to this occurrence of the problem.
+	Detail string `json:"detail,omitempty" example:"Request failed validation"`
+	// Request path the problem occurred on.
+	Instance string `json:"instance,omitempty" example:"/md"`
+	// Field level validation failures.
+	Errors []*ValidationError `json:"errors,omitempty"`
 }
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReadinessCheck
+// Named check that must pass for the service to receive traffic.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthResponse
+type HealthResponse struct {
+	// `ok` when healthy, otherwise `unavailable`.
+	Status string `json:"status" example:"ok"`
+	// Result of each readiness check.
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// ConfigureHealth
+// Registers `/healthz` liveness and `/readyz` readiness routes.
+// Must be configured before authentication so probes are not challenged.
+func ConfigureHealth(app *fiber.App, checks ...ReadinessCheck) {
+	app.Get("/healthz", func(ctx *fiber.Ctx) error {
+		return ctx.JSON(HealthResponse{Status: "ok"})
+	})
+
+	app.Get("/readyz", func(ctx *fiber.Ctx) error {
+		checkCtx, cancel := context.WithTimeout(ctx.UserContext(), 2*time.Second)
+		defer cancel()
+
+		resp := HealthResponse{Status: "ok", Checks: make(map[string]string)}
+		for _, check := range checks {
+			if err := check.Check(checkCtx); err != nil {
+				resp.Status = "unavailable"
+				resp.Checks[check.Name] = err.Error()
+				continue
+			}
+			resp.Checks[check.Name] = "ok"
+		}
+
+		if resp.Status != "ok" {
+			ctx.Status(http.StatusServiceUnavailable)
+		}
+		return ctx.JSON(resp)
+	})
+}
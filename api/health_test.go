@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_Healthz
+// Liveness should always report ok.
+func Test_Healthz(t *testing.T) {
+	app := fiber.New()
+	ConfigureHealth(app)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// Test_Readyz
+// Readiness should fail when any check fails, reporting each check.
+func Test_Readyz(t *testing.T) {
+	mongoErr := errors.New("server selection timeout")
+	tests := []struct {
+		name     string
+		mongoErr error
+		status   int
+	}{
+		{"ready", nil, http.StatusOK},
+		{"unavailable", mongoErr, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			ConfigureHealth(app,
+				ReadinessCheck{Name: "mongo", Check: func(context.Context) error { return tt.mongoErr }},
+				ReadinessCheck{Name: "indexes", Check: func(context.Context) error { return nil }},
+			)
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+
+			health := new(HealthResponse)
+			assert.Nil(t, json.NewDecoder(resp.Body).Decode(health))
+			assert.Equal(t, "ok", health.Checks["indexes"])
+			if tt.mongoErr != nil {
+				assert.Equal(t, "unavailable", health.Status)
+				assert.Equal(t, tt.mongoErr.Error(), health.Checks["mongo"])
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_MetricsEndpoint
+// Requests should be counted by route and status,
+// including requests rejected by the error handler.
+func Test_MetricsEndpoint(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	ConfigureMiddleware(app)
+	app.Get("/metrics", metrics.Handler())
+	app.Get("/md/:id", func(ctx *fiber.Ctx) error {
+		return fiber.NewError(http.StatusNotFound, "Markdown Snippet Not Found")
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/md/abc", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), `mdsnips_http_requests_total{method="GET",route="/md/:id",status="404"} 1`)
+	assert.Contains(t, string(body), `mdsnips_http_request_duration_seconds_bucket{method="GET",route="/md/:id",status="404",le="+Inf"} 1`)
+}
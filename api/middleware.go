@@ -1,15 +1,17 @@
 package api
 
 import (
-	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/sirupsen/logrus"
+	"github.com/soulxburn/mdsnips/logging"
+	"github.com/soulxburn/mdsnips/metrics"
 )
 
 // ConfigureMiddleware
@@ -20,6 +22,9 @@ func ConfigureMiddleware(app *fiber.App) {
 		EnableStackTrace: true,
 	}))
 
+	app.Use(observeMetrics)
+	app.Use(accessLog)
+
 	app.Use(cors.New())
 
 	app.Use(limiter.New(limiter.Config{
@@ -29,8 +34,11 @@ func ConfigureMiddleware(app *fiber.App) {
 			return getRequestIP(ctx)
 		},
 		LimitReached: func(ctx *fiber.Ctx) error {
-			log.Printf("Too many requests received from: %s\n", getRequestIP(ctx))
-			return ctx.SendStatus(http.StatusTooManyRequests)
+			metrics.RateLimited.Inc()
+			logging.FromContext(ctx.UserContext()).
+				WithField("ip", getRequestIP(ctx)).
+				Warn("Too many requests received")
+			return fiber.NewError(http.StatusTooManyRequests, "Too many requests, try again later")
 		},
 		Next: func(c *fiber.Ctx) bool {
 			method := c.Method()
@@ -42,11 +50,59 @@ func ConfigureMiddleware(app *fiber.App) {
 			return false
 		},
 	}))
+}
 
-	app.Use(logger.New(logger.Config{
-		TimeFormat: "2006-01-02T15:04:05-0700",
-		TimeZone:   "UTC",
-	}))
+// observeMetrics
+// Records request count and latency by route, method and status.
+func observeMetrics(ctx *fiber.Ctx) error {
+	start := time.Now()
+	renderError(ctx, ctx.Next())
+
+	labels := []string{
+		ctx.Route().Path,
+		ctx.Method(),
+		strconv.Itoa(ctx.Response().StatusCode()),
+	}
+	metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+	metrics.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// accessLog
+// Logs a structured line for every completed request.
+func accessLog(ctx *fiber.Ctx) error {
+	start := time.Now()
+	renderError(ctx, ctx.Next())
+
+	status := ctx.Response().StatusCode()
+	entry := logging.FromContext(ctx.UserContext()).WithFields(logrus.Fields{
+		"method":    ctx.Method(),
+		"path":      ctx.OriginalURL(),
+		"status":    status,
+		"latencyMs": time.Since(start).Milliseconds(),
+		"ip":        getRequestIP(ctx),
+	})
+	switch {
+	case status >= http.StatusInternalServerError:
+		entry.Error("request completed")
+	case status >= http.StatusBadRequest:
+		entry.Warn("request completed")
+	default:
+		entry.Info("request completed")
+	}
+	return nil
+}
+
+// renderError
+// Renders err with the app ErrorHandler, so that
+// middleware observing the response sees the final status.
+func renderError(ctx *fiber.Ctx, err error) {
+	if err == nil {
+		return
+	}
+	if handlerErr := ctx.App().Config().ErrorHandler(ctx, err); handlerErr != nil {
+		ctx.Status(http.StatusInternalServerError)
+	}
 }
 
 // getRequestIP
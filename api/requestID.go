@@ -0,0 +1,31 @@
+package api
+
+import (
+	"regexp"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/soulxburn/mdsnips/logging"
+)
+
+// RequestIDHeader Header carrying the request correlation id.
+const RequestIDHeader = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[a-zA-Z0-9._:-]{1,128}$`)
+
+// ConfigureRequestID
+// Attaches middleware accepting a well formed `X-Request-ID` header,
+// or generating one, and carrying it through the request context.
+// Must be attached after ConfigureRequestContext.
+func ConfigureRequestID(app *fiber.App) {
+	app.Use(func(ctx *fiber.Ctx) error {
+		requestID := ctx.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		ctx.Set(RequestIDHeader, requestID)
+		ctx.SetUserContext(logging.WithRequestID(ctx.UserContext(), requestID))
+		return ctx.Next()
+	})
+}
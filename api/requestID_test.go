@@ -0,0 +1,64 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupRequestIDApp
+// Creates a fiber app echoing the request id carried by the user context.
+func setupRequestIDApp() *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	ConfigureRequestContext(app, time.Second)
+	ConfigureRequestID(app)
+	app.Use(accessLog)
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		return ctx.SendString(logging.RequestID(ctx.UserContext()))
+	})
+	return app
+}
+
+// Test_RequestIDAccepted
+// A well formed X-Request-ID should be carried through unchanged,
+// and appear on the access log line.
+func Test_RequestIDAccepted(t *testing.T) {
+	out := new(bytes.Buffer)
+	logging.Logger.SetOutput(out)
+	defer logging.Logger.SetOutput(os.Stdout)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	resp, err := setupRequestIDApp().Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc-123", resp.Header.Get(RequestIDHeader))
+
+	line := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &line))
+	assert.Equal(t, "abc-123", line["requestId"])
+	assert.Equal(t, "info", line["level"])
+	assert.EqualValues(t, http.StatusOK, line["status"])
+}
+
+// Test_RequestIDGenerated
+// Missing or malformed request ids should be replaced.
+func Test_RequestIDGenerated(t *testing.T) {
+	app := setupRequestIDApp()
+	for _, header := range []string{"", "not a valid id\n"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(RequestIDHeader, header)
+		}
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Len(t, resp.Header.Get(RequestIDHeader), 36)
+	}
+}
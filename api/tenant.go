@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/soulxburn/mdsnips/config"
+)
+
+const tenantLocal = "tenant"
+
+// tenantScoped Path prefixes whose data belongs to a tenant.
+var tenantScoped = []string{"/md", "/webhooks"}
+
+// ConfigureTenancy
+// Attaches middleware resolving the request tenant
+// from the host header, path prefix or tenant token.
+// Requests under `/md` and `/webhooks` are rejected
+// when no valid, configured tenant can be resolved.
+func ConfigureTenancy(app *fiber.App, cfg config.Tenant) {
+	if !cfg.Enabled() {
+		return
+	}
+
+	app.Use(func(ctx *fiber.Ctx) error {
+		var tenant string
+		switch cfg.Resolver {
+		case "host":
+			if labels := strings.Split(ctx.Hostname(), "."); len(labels) > 2 {
+				tenant = strings.ToLower(labels[0])
+			}
+		case "path":
+			tenant = resolvePathTenant(ctx)
+		case "token":
+			tenant = cfg.Tokens[ctx.Get("X-Tenant-Token")]
+		}
+
+		if !isTenantScoped(ctx.Path()) {
+			return ctx.Next()
+		}
+		if !cfg.Allowed(tenant) {
+			return fiber.NewError(http.StatusBadRequest, "Unable to resolve tenant")
+		}
+
+		ctx.Locals(tenantLocal, tenant)
+		return ctx.Next()
+	})
+}
+
+// Tenant
+// Returns the tenant resolved for the request,
+// empty when tenancy is disabled.
+func Tenant(ctx *fiber.Ctx) string {
+	tenant, _ := ctx.Locals(tenantLocal).(string)
+	return tenant
+}
+
+// resolvePathTenant
+// Strips a `/t/{tenant}` prefix from the request path
+// and returns the tenant. The path is copied first, since
+// ctx.Path shares the buffer the rewrite overwrites.
+func resolvePathTenant(ctx *fiber.Ctx) string {
+	path := utils.CopyString(ctx.Path())
+	if !strings.HasPrefix(path, "/t/") {
+		return ""
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(path, "/t/"), "/", 2)
+	rest := "/"
+	if len(parts) == 2 {
+		rest += parts[1]
+	}
+	ctx.Path(rest)
+	return parts[0]
+}
+
+// isTenantScoped
+// Reports whether path is under a tenant scoped prefix.
+func isTenantScoped(path string) bool {
+	for _, prefix := range tenantScoped {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
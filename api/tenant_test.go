@@ -0,0 +1,114 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupTenantApp
+// Creates a fiber app echoing the resolved tenant under `/md`.
+func setupTenantApp(cfg config.Tenant) *fiber.App {
+	app := fiber.New()
+	ConfigureTenancy(app, cfg)
+	echo := func(ctx *fiber.Ctx) error {
+		return ctx.SendString(Tenant(ctx))
+	}
+	app.Get("/md", echo)
+	app.Get("/md/*", echo)
+	return app
+}
+
+// Test_TenantResolvers
+// Each resolver should extract the tenant from its source.
+func Test_TenantResolvers(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.Tenant
+		req      func() *http.Request
+		expected string
+	}{
+		{
+			name: "host",
+			cfg:  config.Tenant{Mode: config.TenancyFilter, Resolver: "host"},
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/md", nil)
+				req.Host = "acme.mdsnips.io"
+				return req
+			},
+			expected: "acme",
+		},
+		{
+			name: "path",
+			cfg:  config.Tenant{Mode: config.TenancyFilter, Resolver: "path"},
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/t/acme/md", nil)
+			},
+			expected: "acme",
+		},
+		{
+			name: "path with snippet",
+			cfg:  config.Tenant{Mode: config.TenancyFilter, Resolver: "path"},
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/t/acme/md/abc/attachments", nil)
+			},
+			expected: "acme",
+		},
+		{
+			name: "token",
+			cfg: config.Tenant{Mode: config.TenancyFilter, Resolver: "token",
+				Tokens: map[string]string{"secret": "acme"}},
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/md", nil)
+				req.Header.Set("X-Tenant-Token", "secret")
+				return req
+			},
+			expected: "acme",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := setupTenantApp(tt.cfg).Test(tt.req())
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, string(body))
+		})
+	}
+}
+
+// Test_TenantUnresolved
+// Requests without a tenant should be rejected.
+func Test_TenantUnresolved(t *testing.T) {
+	app := setupTenantApp(config.Tenant{Mode: config.TenancyFilter, Resolver: "token"})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/md", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// Test_TenantUnknown
+// Tenants that are invalid or not configured should be rejected.
+func Test_TenantUnknown(t *testing.T) {
+	app := setupTenantApp(config.Tenant{Mode: config.TenancyDatabase, Resolver: "path", Names: []string{"acme"}})
+
+	for path, status := range map[string]int{
+		"/t/acme/md":    http.StatusOK,
+		"/t/globex/md":  http.StatusBadRequest,
+		"/t/Acme/md":    http.StatusBadRequest,
+		"/t/admin.x/md": http.StatusBadRequest,
+		"/t/-acme-/md":  http.StatusBadRequest,
+	} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, status, resp.StatusCode, path)
+	}
+}
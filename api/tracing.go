@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ConfigureTracing
+// Attaches middleware continuing W3C trace-context from incoming
+// request headers and starting a server span per request.
+// The span is carried through ctx.UserContext().
+// Must be attached after ConfigureRequestID.
+func ConfigureTracing(app *fiber.App) {
+	tracer := otel.Tracer("github.com/soulxburn/mdsnips/api")
+
+	app.Use(func(ctx *fiber.Ctx) error {
+		headers := make(http.Header)
+		ctx.Request().Header.VisitAll(func(key, value []byte) {
+			headers.Add(string(key), string(value))
+		})
+		reqCtx := otel.GetTextMapPropagator().Extract(ctx.UserContext(), propagation.HeaderCarrier(headers))
+
+		reqCtx, span := tracer.Start(reqCtx, "HTTP "+ctx.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(ctx.Method()),
+				semconv.HTTPTargetKey.String(ctx.OriginalURL()),
+				semconv.HTTPClientIPKey.String(getRequestIP(ctx)),
+				attribute.String("http.request_id", logging.RequestID(reqCtx)),
+			),
+		)
+		defer span.End()
+
+		ctx.SetUserContext(reqCtx)
+		renderError(ctx, ctx.Next())
+
+		status := ctx.Response().StatusCode()
+		span.SetName("HTTP " + ctx.Method() + " " + ctx.Route().Path)
+		span.SetAttributes(
+			semconv.HTTPRouteKey.String(ctx.Route().Path),
+			semconv.HTTPStatusCodeKey.Int(status),
+		)
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		return nil
+	})
+}
@@ -1,19 +1,34 @@
 package api
 
-import "github.com/go-playground/validator"
+import (
+	"fmt"
+
+	"github.com/go-playground/validator"
+)
 
 // ValidationError
 // Represents detailed validation error message
 type ValidationError struct {
-	FailedField string
-	Tag         string
-	Value       string
+	FailedField string `json:"field" example:"CreateMDReq.Title"`
+	Tag         string `json:"tag" example:"max"`
+	Value       string `json:"value" example:"64"`
+}
+
+// ValidationErrors
+// Validation failures returned as a single error.
+type ValidationErrors []*ValidationError
+
+func (v ValidationErrors) Error() string {
+	if len(v) == 1 {
+		return fmt.Sprintf("%s failed `%s` validation", v[0].FailedField, v[0].Tag)
+	}
+	return fmt.Sprintf("%d fields failed validation", len(v))
 }
 
 // ValidateStruct
 // Validates the struct values based on struct tags.
-func ValidateStruct(v interface{}) []*ValidationError {
-	var errors []*ValidationError
+func ValidateStruct(v interface{}) ValidationErrors {
+	var errors ValidationErrors
 	validate := validator.New()
 	err := validate.Struct(v)
 	if err != nil {
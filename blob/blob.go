@@ -0,0 +1,61 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/soulxburn/mdsnips/config"
+)
+
+const (
+	// StoreLocal keeps blobs as files below config.Blob.Dir.
+	StoreLocal = "local"
+	// StoreS3 keeps blobs in a bucket of an S3-compatible service.
+	StoreS3 = "s3"
+)
+
+// ErrNotFound is returned when no blob is stored under a key.
+var ErrNotFound = errors.New("blob not found")
+
+// Store
+// Stores opaque blobs under slash separated keys, e.g. `snippets/8d2b7f10/body/<sha256>`.
+type Store interface {
+	// Put stores size bytes read from r under key, replacing any blob already there.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get returns a reader of the blob under key, which the caller must close.
+	// Returns ErrNotFound when there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key, if any.
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix removes every blob below prefix, which must end in a slash.
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// New
+// Returns the store configured by cfg, nil when none is.
+func New(ctx context.Context, cfg config.Blob) (Store, error) {
+	switch cfg.Store {
+	case "":
+		return nil, nil
+	case StoreLocal:
+		return NewLocalStore(cfg.Dir)
+	case StoreS3:
+		return NewS3Store(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unknown blob store `%s`", cfg.Store)
+	}
+}
+
+// ReadString
+// Returns the blob under key as a string.
+func ReadString(ctx context.Context, store Store, key string) (string, error) {
+	r, err := store.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	return string(content), err
+}
@@ -0,0 +1,77 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// testStore
+// Exercises the Store contract shared by every backend.
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	_, err := store.Get(ctx, "snippets/a/body/missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.Nil(t, store.Delete(ctx, "snippets/a/body/missing"))
+
+	assert.Nil(t, store.Put(ctx, "snippets/a/body/1", strings.NewReader("first"), 5))
+	assert.Nil(t, store.Put(ctx, "snippets/a/attachments/1", strings.NewReader("image"), 5))
+	assert.Nil(t, store.Put(ctx, "snippets/ab/body/1", strings.NewReader("other"), 5))
+	content, err := ReadString(ctx, store, "snippets/a/body/1")
+	assert.Nil(t, err)
+	assert.Equal(t, "first", content)
+
+	assert.Nil(t, store.Put(ctx, "snippets/a/body/1", strings.NewReader("replaced"), 8))
+	content, err = ReadString(ctx, store, "snippets/a/body/1")
+	assert.Nil(t, err)
+	assert.Equal(t, "replaced", content)
+
+	assert.Nil(t, store.Delete(ctx, "snippets/a/attachments/1"))
+	_, err = store.Get(ctx, "snippets/a/attachments/1")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	assert.Nil(t, store.DeletePrefix(ctx, "snippets/a/"))
+	_, err = store.Get(ctx, "snippets/a/body/1")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	content, err = ReadString(ctx, store, "snippets/ab/body/1")
+	assert.Nil(t, err)
+	assert.Equal(t, "other", content)
+	assert.Nil(t, store.DeletePrefix(ctx, "snippets/missing/"))
+}
+
+// Test_LocalStore
+func Test_LocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	assert.Nil(t, err)
+	testStore(t, store)
+}
+
+// Test_LocalStoreKeys
+// Keys may not escape the store root.
+func Test_LocalStoreKeys(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "snippets/../../outside", "snippets//a"} {
+		assert.NotNil(t, store.Put(ctx, key, strings.NewReader("x"), 1), key)
+		_, err := store.Get(ctx, key)
+		assert.NotNil(t, err, key)
+	}
+	assert.NotNil(t, store.Put(ctx, "snippets/a", strings.NewReader("short"), 10))
+}
+
+// Test_New
+func Test_New(t *testing.T) {
+	store, err := New(context.Background(), config.Blob{})
+	assert.Nil(t, err)
+	assert.Nil(t, store)
+
+	_, err = New(context.Background(), config.Blob{Store: "ftp"})
+	assert.EqualError(t, err, "unknown blob store `ftp`")
+}
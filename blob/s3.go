@@ -0,0 +1,121 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/soulxburn/mdsnips/config"
+)
+
+// S3Store
+// Keeps blobs as objects in a bucket of an S3-compatible
+// service, such as AWS S3 or MinIO, each key an object name.
+type S3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Store Creates a S3Store of the configured bucket
+// Creates the bucket when it does not exist.
+func NewS3Store(ctx context.Context, cfg config.Blob) (*S3Store, error) {
+	if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+		return nil, errors.New("s3 blob store requires an endpoint and bucket")
+	}
+	client, err := minio.New(cfg.S3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.S3AccessKey, cfg.S3SecretKey, ""),
+		Secure: !cfg.S3Insecure,
+		Region: cfg.S3Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(ctx, cfg.S3Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, cfg.S3Bucket, minio.MakeBucketOptions{Region: cfg.S3Region}); err != nil {
+			return nil, err
+		}
+	}
+	return &S3Store{client: client, bucket: cfg.S3Bucket}, nil
+}
+
+// Put
+// Uploads the blob as an object, in parts when it is large.
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	opts := minio.PutObjectOptions{ContentType: "application/octet-stream"}
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, opts)
+	return err
+}
+
+// Get
+// Returns a reader of the object under key.
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s.err(err)
+	}
+	// Objects are fetched lazily, stat to report a missing object now.
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, s.err(err)
+	}
+	return object, nil
+}
+
+// Delete
+// Removes the object under key.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+// DeletePrefix
+// Removes every object listed below prefix.
+func (s *S3Store) DeletePrefix(ctx context.Context, prefix string) error {
+	if !strings.HasSuffix(prefix, "/") {
+		return fmt.Errorf("blob prefix %s must end in a slash", prefix)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	objects := s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true})
+	// Listing errors arrive as objects, removal stops at the first.
+	listed := make(chan minio.ObjectInfo)
+	var listErr error
+	go func() {
+		defer close(listed)
+		for object := range objects {
+			if object.Err != nil {
+				listErr = object.Err
+				return
+			}
+			select {
+			case listed <- object:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for removeErr := range s.client.RemoveObjects(ctx, s.bucket, listed, minio.RemoveObjectsOptions{}) {
+		if removeErr.Err != nil {
+			return removeErr.Err
+		}
+	}
+	return listErr
+}
+
+// err
+// Maps a missing object to ErrNotFound.
+func (s *S3Store) err(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+	return err
+}
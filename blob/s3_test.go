@@ -0,0 +1,30 @@
+package blob
+
+import (
+	"context"
+	"testing"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_S3Store
+func Test_S3Store(t *testing.T) {
+	testutils.SkipContainers(t)
+	mCont, err := testutils.SetupMinioTestContainer()
+	if err != nil {
+		t.Fatalf("Failed to initialize minio container: %s", err)
+	}
+	defer mCont.Container.Terminate(context.Background())
+
+	store, err := NewS3Store(context.Background(), config.Blob{
+		S3Endpoint:  mCont.Endpoint,
+		S3Bucket:    "mdsnips",
+		S3AccessKey: mCont.AccessKey,
+		S3SecretKey: mCont.SecretKey,
+		S3Insecure:  true,
+	})
+	assert.Nil(t, err)
+	testStore(t, store)
+}
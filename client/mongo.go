@@ -2,10 +2,19 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
 	"time"
 
+	"github.com/soulxburn/mdsnips/config"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
 // InitMongoClient Returns a reference to a mongo.Client
@@ -14,9 +23,7 @@ func InitMongoClient(connectionString string) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	clientOptions := options.Client().ApplyURI(connectionString)
-
-	client, err := mongo.Connect(ctx, clientOptions)
+	client, err := NewMongoClient(config.Mongo{Conn: connectionString})
 	if err != nil {
 		return nil, err
 	}
@@ -28,3 +35,166 @@ func InitMongoClient(connectionString string) (*mongo.Client, error) {
 
 	return client, nil
 }
+
+// NewMongoClient Returns a reference to a mongo.Client
+// configured from cfg, without waiting for the server to be reachable.
+// Errors are only returned for invalid settings.
+func NewMongoClient(cfg config.Mongo) (*mongo.Client, error) {
+	clientOptions, err := MongoOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return mongo.Connect(context.Background(), clientOptions)
+}
+
+// MongoOptions
+// Builds mongo.Client options from cfg. Settings left at their
+// zero value keep the connection string or driver default.
+func MongoOptions(cfg config.Mongo) (*options.ClientOptions, error) {
+	clientOptions := options.Client().
+		ApplyURI(cfg.Conn).
+		SetMonitor(NewCommandMonitor(cfg.SlowQueryThreshold))
+
+	if cfg.AppName != "" {
+		clientOptions.SetAppName(cfg.AppName)
+	}
+	if cfg.MaxPoolSize > 0 {
+		clientOptions.SetMaxPoolSize(cfg.MaxPoolSize)
+	}
+	if cfg.MinPoolSize > 0 {
+		clientOptions.SetMinPoolSize(cfg.MinPoolSize)
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		clientOptions.SetMaxConnIdleTime(cfg.MaxConnIdleTime)
+	}
+	if cfg.ConnectTimeout > 0 {
+		clientOptions.SetConnectTimeout(cfg.ConnectTimeout)
+	}
+	if cfg.ServerSelectionTimeout > 0 {
+		clientOptions.SetServerSelectionTimeout(cfg.ServerSelectionTimeout)
+	}
+	if cfg.SocketTimeout > 0 {
+		clientOptions.SetSocketTimeout(cfg.SocketTimeout)
+	}
+	if cfg.RetryReads != nil {
+		clientOptions.SetRetryReads(*cfg.RetryReads)
+	}
+	if cfg.RetryWrites != nil {
+		clientOptions.SetRetryWrites(*cfg.RetryWrites)
+	}
+
+	if cfg.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(cfg.ReadPreference)
+		if err != nil {
+			return nil, fmt.Errorf("read preference: %w", err)
+		}
+		readPref, err := readpref.New(mode)
+		if err != nil {
+			return nil, fmt.Errorf("read preference: %w", err)
+		}
+		clientOptions.SetReadPreference(readPref)
+	}
+	if cfg.ReadConcern != "" {
+		clientOptions.SetReadConcern(readconcern.New(readconcern.Level(cfg.ReadConcern)))
+	}
+	if wc := writeConcern(cfg); wc != nil {
+		clientOptions.SetWriteConcern(wc)
+	}
+
+	if cfg.TLSCAFile != "" || cfg.TLSCertFile != "" {
+		tlsConfig, err := tlsConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
+
+	return clientOptions, clientOptions.Validate()
+}
+
+// writeConcern
+// Returns the configured write concern, nil when none is set.
+func writeConcern(cfg config.Mongo) *writeconcern.WriteConcern {
+	var opts []writeconcern.Option
+	switch w := cfg.WriteConcern; {
+	case w == "majority":
+		opts = append(opts, writeconcern.WMajority())
+	case w != "":
+		if n, err := strconv.Atoi(w); err == nil {
+			opts = append(opts, writeconcern.W(n))
+		} else {
+			opts = append(opts, writeconcern.WTagSet(w))
+		}
+	}
+	if cfg.WriteConcernJournal != nil {
+		opts = append(opts, writeconcern.J(*cfg.WriteConcernJournal))
+	}
+	if cfg.WriteConcernTimeout > 0 {
+		opts = append(opts, writeconcern.WTimeout(cfg.WriteConcernTimeout))
+	}
+	if len(opts) == 0 {
+		return nil
+	}
+	return writeconcern.New(opts...)
+}
+
+// tlsConfig
+// Loads the CA bundle and client certificate into a tls.Config.
+func tlsConfig(cfg config.Mongo) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls ca file: no certificates found in %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSCertFile != "" {
+		keyFile := cfg.TLSKeyFile
+		if keyFile == "" {
+			keyFile = cfg.TLSCertFile
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// WaitForMongo
+// Pings the server until it responds, doubling the wait between
+// attempts from initial up to max. onRetry is called after each failure.
+// Returns the context error when ctx ends first.
+func WaitForMongo(ctx context.Context, client *mongo.Client, initial time.Duration, max time.Duration, onRetry func(attempt int, err error)) error {
+	wait := initial
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := client.Ping(pingCtx, nil)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if onRetry != nil {
+			onRetry(attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > max {
+			wait = max
+		}
+	}
+}
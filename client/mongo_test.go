@@ -0,0 +1,136 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/logging"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Test_WaitForMongoBacksOff
+// Unreachable servers should be retried until the context ends.
+func Test_WaitForMongoBacksOff(t *testing.T) {
+	mClient, err := NewMongoClient(config.Mongo{
+		Conn:                   "mongodb://127.0.0.1:1",
+		ServerSelectionTimeout: 50 * time.Millisecond,
+	})
+	assert.Nil(t, err)
+	defer mClient.Disconnect(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	err = WaitForMongo(ctx, mClient, 10*time.Millisecond, 40*time.Millisecond, func(attempt int, err error) {
+		attempts = attempt
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Greater(t, attempts, 2)
+}
+
+// Test_MongoOptions
+// Configured settings should be applied over the connection string.
+func Test_MongoOptions(t *testing.T) {
+	retryWrites := false
+	opts, err := MongoOptions(config.Mongo{
+		Conn:                "mongodb://127.0.0.1:27017/?maxPoolSize=5",
+		AppName:             "mdsnips-test",
+		MaxPoolSize:         50,
+		SocketTimeout:       3 * time.Second,
+		RetryWrites:         &retryWrites,
+		ReadPreference:      "secondaryPreferred",
+		ReadConcern:         "majority",
+		WriteConcern:        "majority",
+		WriteConcernTimeout: time.Second,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "mdsnips-test", *opts.AppName)
+	assert.Equal(t, uint64(50), *opts.MaxPoolSize)
+	assert.Equal(t, 3*time.Second, *opts.SocketTimeout)
+	assert.False(t, *opts.RetryWrites)
+	assert.Nil(t, opts.RetryReads)
+	assert.Equal(t, readpref.SecondaryPreferredMode, opts.ReadPreference.Mode())
+	assert.Equal(t, "majority", opts.ReadConcern.GetLevel())
+	assert.NotNil(t, opts.WriteConcern)
+	assert.NotNil(t, opts.Monitor)
+}
+
+// Test_MongoOptionsInvalid
+// Invalid settings should be reported rather than ignored.
+func Test_MongoOptionsInvalid(t *testing.T) {
+	_, err := MongoOptions(config.Mongo{Conn: "mongodb://127.0.0.1", ReadPreference: "fastest"})
+	assert.NotNil(t, err)
+
+	_, err = MongoOptions(config.Mongo{Conn: "mongodb://127.0.0.1", TLSCAFile: "/does/not/exist.pem"})
+	assert.NotNil(t, err)
+}
+
+// Test_SlowQueryMonitor
+// Commands slower than the threshold should be logged with the request id.
+func Test_SlowQueryMonitor(t *testing.T) {
+	out := new(bytes.Buffer)
+	logging.Logger.SetOutput(out)
+	defer logging.Logger.SetOutput(os.Stdout)
+
+	monitor := NewCommandMonitor(100 * time.Millisecond)
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{
+		CommandName:   "find",
+		DurationNanos: int64(10 * time.Millisecond),
+	}})
+	assert.Empty(t, out.String())
+
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{
+		CommandName:   "find",
+		DurationNanos: int64(250 * time.Millisecond),
+	}})
+	assert.Contains(t, out.String(), `"command":"find"`)
+	assert.Contains(t, out.String(), `"requestId":"req-1"`)
+	assert.Contains(t, out.String(), `"durationMs":250`)
+}
+
+// Test_CommandMonitorOmitsStatement
+// Spans for Mongo commands should not carry the command document.
+func Test_CommandMonitorOmitsStatement(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	monitor := NewCommandMonitor(0)
+	ctx := context.Background()
+	command, err := bson.Marshal(bson.M{"update": "md", "updateKey": "secret"})
+	assert.Nil(t, err)
+
+	monitor.Started(ctx, &event.CommandStartedEvent{
+		Command:      command,
+		DatabaseName: "mdsnips",
+		CommandName:  "update",
+		RequestID:    1,
+		ConnectionID: "localhost:27017[-1]",
+	})
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{
+		CommandName:  "update",
+		RequestID:    1,
+		ConnectionID: "localhost:27017[-1]",
+	}})
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	for _, attr := range spans[0].Attributes() {
+		assert.NotEqual(t, "db.statement", string(attr.Key))
+		assert.NotContains(t, attr.Value.Emit(), "secret")
+	}
+}
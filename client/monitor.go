@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soulxburn/mdsnips/logging"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+)
+
+// NewCommandMonitor
+// Returns a command monitor tracing every command with OpenTelemetry,
+// and logging commands slower than slowQuery with the request id
+// carried by the operation context. Slow query logging is disabled
+// when slowQuery is zero. Spans leave out the command document, since
+// it carries update keys, webhook secrets and snippet bodies.
+func NewCommandMonitor(slowQuery time.Duration) *event.CommandMonitor {
+	tracing := otelmongo.NewMonitor(otelmongo.WithCommandAttributeDisabled(true))
+
+	logSlow := func(ctx context.Context, evt event.CommandFinishedEvent, err string) {
+		duration := time.Duration(evt.DurationNanos)
+		if slowQuery <= 0 || duration < slowQuery {
+			return
+		}
+		entry := logging.FromContext(ctx).WithFields(logrus.Fields{
+			"command":       evt.CommandName,
+			"wireRequestId": evt.RequestID,
+			"connection":    evt.ConnectionID,
+			"durationMs":    duration.Milliseconds(),
+		})
+		if err != "" {
+			entry = entry.WithField("error", err)
+		}
+		entry.Warn("Slow Mongo command")
+	}
+
+	return &event.CommandMonitor{
+		Started: tracing.Started,
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			tracing.Succeeded(ctx, evt)
+			logSlow(ctx, evt.CommandFinishedEvent, "")
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			tracing.Failed(ctx, evt)
+			logSlow(ctx, evt.CommandFinishedEvent, evt.Failure)
+		},
+	}
+}
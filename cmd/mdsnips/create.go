@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/soulxburn/mdsnips/md"
+)
+
+func init() {
+	register(&command{
+		name:    "create",
+		summary: "Create a snippet from a file or stdin",
+		run:     runCreate,
+	})
+}
+
+// runCreate
+// Creates a snippet and stores its update key in the keyring.
+// The title defaults to the file name without its extension.
+// Usage: mdsnips create [-title title] [-json] [file|-]
+func runCreate(c *cli, args []string) error {
+	flags := c.newFlagSet("create")
+	title := flags.String("title", "", "Snippet title, required when reading stdin")
+	asJSON := flags.Bool("json", false, "Print the created snippet as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return errors.New("usage: mdsnips create [-title title] [file|-]")
+	}
+
+	var body []byte
+	var err error
+	if path := flags.Arg(0); path == "" || path == "-" {
+		body, err = ioutil.ReadAll(c.stdin)
+	} else {
+		body, err = ioutil.ReadFile(path)
+		if *title == "" {
+			*title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if *title == "" {
+		return errors.New("-title is required when reading stdin")
+	}
+
+	snippet, err := c.api.Create(context.Background(), &md.CreateMDReq{Title: *title, Body: string(body)})
+	if err != nil {
+		return err
+	}
+	if err := c.keys.Put(c.api.BaseURL(), snippet.ID, snippet.UpdateKey); err != nil {
+		fmt.Fprintf(c.stderr, "warning: update key not saved, keep it to edit the snippet: %s (%v)\n", snippet.UpdateKey, err)
+	}
+
+	if *asJSON {
+		return printJSON(c.stdout, snippet)
+	}
+	fmt.Fprintln(c.stdout, snippet.ID)
+	return nil
+}
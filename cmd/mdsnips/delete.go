@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+func init() {
+	register(&command{
+		name:    "delete",
+		summary: "Delete a snippet",
+		run:     runDelete,
+	})
+}
+
+// runDelete
+// Deletes a snippet and forgets its update key.
+// Usage: mdsnips delete [-key updateKey] <id>
+func runDelete(c *cli, args []string) error {
+	flags := c.newFlagSet("delete")
+	key := flags.String("key", "", "Update key, defaults to the keyring")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: mdsnips delete [-key updateKey] <id>")
+	}
+	id := flags.Arg(0)
+	updateKey, err := c.updateKey(id, *key)
+	if err != nil {
+		return err
+	}
+
+	if err := c.api.Delete(context.Background(), id, updateKey); err != nil {
+		return err
+	}
+	if err := c.keys.Delete(c.api.BaseURL(), id); err != nil {
+		fmt.Fprintf(c.stderr, "warning: keyring not updated: %v\n", err)
+	}
+	fmt.Fprintln(c.stderr, "Deleted", id)
+	return nil
+}
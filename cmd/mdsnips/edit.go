@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/soulxburn/mdsnips/md"
+)
+
+func init() {
+	register(&command{
+		name:    "edit",
+		summary: "Edit a snippet in $EDITOR",
+		run:     runEdit,
+	})
+}
+
+// runEdit
+// Opens the snippet body in $VISUAL or $EDITOR and saves the result.
+// The update key is read from the keyring unless -key is given,
+// in which case it is stored once the update succeeds.
+// Usage: mdsnips edit [-key updateKey] [-title title] <id>
+func runEdit(c *cli, args []string) error {
+	flags := c.newFlagSet("edit")
+	key := flags.String("key", "", "Update key, defaults to the keyring")
+	title := flags.String("title", "", "New snippet title")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: mdsnips edit [-key updateKey] [-title title] <id>")
+	}
+	id := flags.Arg(0)
+	updateKey, err := c.updateKey(id, *key)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	snippet, err := c.api.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	body, err := c.editBody(snippet.Body)
+	if err != nil {
+		return err
+	}
+	if *title == "" {
+		*title = snippet.Title
+	}
+	if body == snippet.Body && *title == snippet.Title {
+		fmt.Fprintln(c.stderr, "No changes")
+		return nil
+	}
+
+	req := &md.UpdateMDReq{
+		ID:          id,
+		UpdateKey:   updateKey,
+		CreateMDReq: md.CreateMDReq{Title: *title, Body: body},
+	}
+	if _, err := c.api.Update(ctx, req); err != nil {
+		return err
+	}
+	if *key != "" {
+		if err := c.keys.Put(c.api.BaseURL(), id, updateKey); err != nil {
+			fmt.Fprintf(c.stderr, "warning: update key not saved: %v\n", err)
+		}
+	}
+	fmt.Fprintln(c.stderr, "Saved", id)
+	return nil
+}
+
+// updateKey
+// Returns key when set, otherwise the keyring entry for snippet id.
+func (c *cli) updateKey(id, key string) (string, error) {
+	if key != "" {
+		return key, nil
+	}
+	if key, ok := c.keys.Get(c.api.BaseURL(), id); ok {
+		return key, nil
+	}
+	return "", fmt.Errorf("no update key for %s in %s, pass -key", id, c.keys.path)
+}
+
+// editBody
+// Writes body to a temporary markdown file, opens it
+// in the editor and returns the saved content.
+func (c *cli) editBody(body string) (string, error) {
+	file, err := ioutil.TempFile("", "mdsnips-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(body); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	if err := c.editor(file.Name()); err != nil {
+		return "", fmt.Errorf("editor: %w", err)
+	}
+	edited, err := ioutil.ReadFile(file.Name())
+	return string(edited), err
+}
+
+// runEditor
+// Runs $VISUAL or $EDITOR, falling back to vi, on path
+// attached to the terminal.
+func (c *cli) runEditor(path string) error {
+	editor := c.env("VISUAL", c.env("EDITOR", "vi"))
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
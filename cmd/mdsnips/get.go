@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/yuin/goldmark"
+)
+
+func init() {
+	register(&command{
+		name:    "get",
+		summary: "Print a snippet as raw markdown, HTML or JSON",
+		run:     runGet,
+	})
+}
+
+// runGet
+// Prints a snippet in the requested format.
+// Usage: mdsnips get [-format raw|html|json] <id>
+func runGet(c *cli, args []string) error {
+	flags := c.newFlagSet("get")
+	format := flags.String("format", "raw", "Output format, one of raw, html or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: mdsnips get [-format raw|html|json] <id>")
+	}
+	switch *format {
+	case "raw", "html", "json":
+	default:
+		return fmt.Errorf("`%s` is not a valid format", *format)
+	}
+
+	snippet, err := c.api.Get(context.Background(), flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "html":
+		return goldmark.Convert([]byte(snippet.Body), c.stdout)
+	case "json":
+		return printJSON(c.stdout, snippet)
+	}
+	_, err = io.WriteString(c.stdout, snippet.Body)
+	return err
+}
+
+// printJSON
+// Writes v to w as indented JSON.
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// keyring
+// Update keys of the snippets created from this machine,
+// stored by API base URL then snippet id.
+type keyring struct {
+	path string
+	keys map[string]map[string]string
+}
+
+// openKeyring
+// Loads the keyring at path, defaulting to mdsnips/keyring.json
+// in the user config directory. A missing file is an empty keyring.
+func openKeyring(path string) (*keyring, error) {
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, "mdsnips", "keyring.json")
+	}
+
+	k := &keyring{path: path, keys: map[string]map[string]string{}}
+	content, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return k, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &k.keys); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Get
+// Returns the update key of snippet id on baseURL.
+func (k *keyring) Get(baseURL, id string) (string, bool) {
+	key, ok := k.keys[baseURL][id]
+	return key, ok
+}
+
+// Put
+// Stores the update key of snippet id on baseURL and saves the keyring.
+func (k *keyring) Put(baseURL, id, updateKey string) error {
+	if k.keys[baseURL] == nil {
+		k.keys[baseURL] = map[string]string{}
+	}
+	k.keys[baseURL][id] = updateKey
+	return k.save()
+}
+
+// Delete
+// Forgets the update key of snippet id on baseURL and saves the keyring.
+func (k *keyring) Delete(baseURL, id string) error {
+	if _, ok := k.keys[baseURL][id]; !ok {
+		return nil
+	}
+	delete(k.keys[baseURL], id)
+	if len(k.keys[baseURL]) == 0 {
+		delete(k.keys, baseURL)
+	}
+	return k.save()
+}
+
+// save
+// Writes the keyring readable only by the current user,
+// replacing the file atomically.
+func (k *keyring) save() error {
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(k.keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(k.path), ".keyring-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), k.path)
+}
@@ -0,0 +1,153 @@
+// Command mdsnips is a command-line client for the MDSnips API.
+//
+// Usage: mdsnips [-url URL] <command> [flags]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/soulxburn/mdsnips/md/client"
+)
+
+// command
+// A client subcommand, run with the arguments following its name.
+type command struct {
+	name    string
+	summary string
+	run     func(c *cli, args []string) error
+}
+
+// commands Registered subcommands by name.
+var commands = map[string]*command{}
+
+// register
+// Adds cmd to the set of subcommands.
+func register(cmd *command) {
+	commands[cmd.name] = cmd
+}
+
+// cli
+// State shared by every client command.
+type cli struct {
+	api    *client.Client
+	keys   *keyring
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+	// editor opens path in the user's editor and waits for it to exit.
+	editor func(path string) error
+}
+
+func main() {
+	c := &cli{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		getenv: os.Getenv,
+	}
+	c.editor = c.runEditor
+	os.Exit(c.run(os.Args[1:]))
+}
+
+// run
+// Parses global flags and runs the subcommand named by args[0].
+// Returns the process exit code.
+func (c *cli) run(args []string) int {
+	flags := flag.NewFlagSet("mdsnips", flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() { c.usage(c.stderr) }
+	baseURL := flags.String("url", c.env("MDSNIPS_URL", "http://localhost:3000"), "API base URL")
+	user := flags.String("user", c.getenv("MDSNIPS_USER"), "Basic auth user name")
+	keyringPath := flags.String("keyring", c.getenv("MDSNIPS_KEYRING"), "Update key file, defaults to the user config directory")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() == 0 {
+		c.usage(c.stderr)
+		return 2
+	}
+	name, args := flags.Arg(0), flags.Args()[1:]
+	if name == "help" {
+		c.usage(c.stdout)
+		return 0
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(c.stderr, "mdsnips: unknown command %q\n\n", name)
+		c.usage(c.stderr)
+		return 2
+	}
+
+	opts := []client.Option{client.WithTenantToken(c.getenv("MDSNIPS_TENANT_TOKEN"))}
+	if *user != "" {
+		opts = append(opts, client.WithBasicAuth(*user, c.getenv("MDSNIPS_PASS")))
+	}
+	api, err := client.New(*baseURL, opts...)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "mdsnips: %v\n", err)
+		return 2
+	}
+	c.api = api
+	keys, err := openKeyring(*keyringPath)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "mdsnips: %v\n", err)
+		return 1
+	}
+	c.keys = keys
+
+	if err := cmd.run(c, args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		fmt.Fprintf(c.stderr, "mdsnips %s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// env
+// Returns the environment variable key, or fallback when unset.
+func (c *cli) env(key, fallback string) string {
+	if value := c.getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// usage
+// Writes the global flags and list of subcommands to w.
+func (c *cli) usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: mdsnips [-url URL] [-user USER] [-keyring FILE] <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "MDSNIPS_URL, MDSNIPS_USER, MDSNIPS_PASS, MDSNIPS_TENANT_TOKEN and MDSNIPS_KEYRING")
+	fmt.Fprintln(w, "provide defaults. Run `mdsnips <command> -h` for command flags.")
+}
+
+// newFlagSet
+// Returns a FlagSet for name that reports errors
+// instead of exiting, writing usage to c.stderr.
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("mdsnips "+name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	return flags
+}
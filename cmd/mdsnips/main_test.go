@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/soulxburn/mdsnips/md"
+	"github.com/soulxburn/mdsnips/md/client/clienttest"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCLI
+// Returns a cli pointed at a clienttest.Server, with a temporary keyring.
+func testCLI(t *testing.T) (*cli, *clienttest.Server, *bytes.Buffer, *bytes.Buffer) {
+	server := clienttest.NewServer()
+	t.Cleanup(server.Close)
+
+	env := map[string]string{
+		"MDSNIPS_URL":     server.URL,
+		"MDSNIPS_USER":    clienttest.User,
+		"MDSNIPS_PASS":    clienttest.Pass,
+		"MDSNIPS_KEYRING": filepath.Join(t.TempDir(), "keyring.json"),
+	}
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	c := &cli{
+		stdin:  strings.NewReader(""),
+		stdout: stdout,
+		stderr: stderr,
+		getenv: func(key string) string { return env[key] },
+		editor: func(path string) error { return nil },
+	}
+	return c, server, stdout, stderr
+}
+
+// Test_CreateStoresUpdateKey
+// Creating from stdin prints the id and stores the update key.
+func Test_CreateStoresUpdateKey(t *testing.T) {
+	c, server, stdout, _ := testCLI(t)
+	c.stdin = strings.NewReader("# Hello")
+
+	assert.Equal(t, 0, c.run([]string{"create", "-title", "Hello"}))
+	assert.Equal(t, "snippet-1\n", stdout.String())
+	assert.Equal(t, "# Hello", server.Snippet("snippet-1").Body)
+
+	key, ok := c.keys.Get(c.api.BaseURL(), "snippet-1")
+	assert.True(t, ok)
+	assert.Equal(t, "key-1", key)
+}
+
+// Test_CreateFromFile
+// The title defaults to the file name.
+func Test_CreateFromFile(t *testing.T) {
+	c, server, _, _ := testCLI(t)
+	path := filepath.Join(t.TempDir(), "notes.md")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("# Notes"), 0600))
+
+	assert.Equal(t, 0, c.run([]string{"create", path}))
+	assert.Equal(t, "notes", server.Snippet("snippet-1").Title)
+}
+
+// Test_CreateStdinRequiresTitle
+func Test_CreateStdinRequiresTitle(t *testing.T) {
+	c, _, _, stderr := testCLI(t)
+
+	assert.Equal(t, 1, c.run([]string{"create"}))
+	assert.Contains(t, stderr.String(), "-title is required")
+}
+
+// Test_GetFormats
+// Snippets print as raw markdown, HTML or JSON.
+func Test_GetFormats(t *testing.T) {
+	c, server, stdout, _ := testCLI(t)
+	server.Put(&md.MarkdownSnippet{ID: "abc", Title: "T", Body: "# Heading"})
+
+	assert.Equal(t, 0, c.run([]string{"get", "abc"}))
+	assert.Equal(t, "# Heading", stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, 0, c.run([]string{"get", "-format", "html", "abc"}))
+	assert.Equal(t, "<h1>Heading</h1>\n", stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, 0, c.run([]string{"get", "-format", "json", "abc"}))
+	snippet := new(md.MarkdownSnippet)
+	assert.Nil(t, json.Unmarshal(stdout.Bytes(), snippet))
+	assert.Equal(t, "abc", snippet.ID)
+}
+
+// Test_GetNotFound
+// API errors are decoded from the problem response.
+func Test_GetNotFound(t *testing.T) {
+	c, _, _, stderr := testCLI(t)
+
+	assert.Equal(t, 1, c.run([]string{"get", "missing"}))
+	assert.Contains(t, stderr.String(), "404 Not Found: Markdown Snippet Not Found")
+}
+
+// Test_SearchPagination
+// Pages are translated into limit and skip.
+func Test_SearchPagination(t *testing.T) {
+	c, server, stdout, _ := testCLI(t)
+	server.Put(&md.MarkdownSnippet{ID: "abc", Title: "Go tips"})
+
+	assert.Equal(t, 0, c.run([]string{"search", "-limit", "5", "-page", "3", "-sort", "createDate_ASC", "go", "tips"}))
+	assert.Equal(t, "limit=5&skip=10&sort=createDate_ASC&text=go+tips", server.Requests()[0].URL.RawQuery)
+	assert.NotContains(t, stdout.String(), "Go tips")
+
+	stdout.Reset()
+	assert.Equal(t, 0, c.run([]string{"search", "go"}))
+	assert.Contains(t, stdout.String(), "Go tips")
+}
+
+// Test_EditWithKeyring
+// Edits are saved with the update key from the keyring.
+func Test_EditWithKeyring(t *testing.T) {
+	c, server, _, _ := testCLI(t)
+	c.stdin = strings.NewReader("# Draft")
+	assert.Equal(t, 0, c.run([]string{"create", "-title", "Draft"}))
+
+	c.editor = func(path string) error {
+		content, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "# Draft", string(content))
+		return ioutil.WriteFile(path, []byte("# Final"), 0600)
+	}
+	assert.Equal(t, 0, c.run([]string{"edit", "snippet-1"}))
+	assert.Equal(t, "# Final", server.Snippet("snippet-1").Body)
+}
+
+// Test_EditWithoutKey
+// Editing a snippet not in the keyring requires -key.
+func Test_EditWithoutKey(t *testing.T) {
+	c, server, _, stderr := testCLI(t)
+	server.Put(&md.MarkdownSnippet{ID: "abc", Title: "T", Body: "# Body", UpdateKey: "secret"})
+
+	assert.Equal(t, 1, c.run([]string{"edit", "abc"}))
+	assert.Contains(t, stderr.String(), "pass -key")
+
+	c.editor = func(path string) error { return ioutil.WriteFile(path, []byte("# Edited"), 0600) }
+	assert.Equal(t, 0, c.run([]string{"edit", "-key", "secret", "abc"}))
+	assert.Equal(t, "# Edited", server.Snippet("abc").Body)
+
+	key, _ := c.keys.Get(c.api.BaseURL(), "abc")
+	assert.Equal(t, "secret", key)
+}
+
+// Test_DeleteForgetsKey
+func Test_DeleteForgetsKey(t *testing.T) {
+	c, server, _, _ := testCLI(t)
+	c.stdin = strings.NewReader("# Gone")
+	assert.Equal(t, 0, c.run([]string{"create", "-title", "Gone"}))
+
+	assert.Equal(t, 0, c.run([]string{"delete", "snippet-1"}))
+	assert.Equal(t, 0, server.Len())
+
+	reopened, err := openKeyring(c.keys.path)
+	assert.Nil(t, err)
+	_, ok := reopened.Get(c.api.BaseURL(), "snippet-1")
+	assert.False(t, ok)
+}
+
+// Test_UnknownCommand
+func Test_UnknownCommand(t *testing.T) {
+	c, _, _, stderr := testCLI(t)
+
+	assert.Equal(t, 2, c.run([]string{"frobnicate"}))
+	assert.Contains(t, stderr.String(), `unknown command "frobnicate"`)
+}
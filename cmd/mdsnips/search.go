@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/soulxburn/mdsnips/md"
+)
+
+func init() {
+	register(&command{
+		name:    "search",
+		summary: "Search snippets with sorting and pagination",
+		run:     runSearch,
+	})
+}
+
+// runSearch
+// Lists snippets matching the search text, one page at a time.
+// Usage: mdsnips search [-sort createDate_DESC] [-limit 10] [-page 1] [-json] [text...]
+func runSearch(c *cli, args []string) error {
+	flags := c.newFlagSet("search")
+	sort := flags.String("sort", md.CreateDate_DESC, "Sort order, createDate_ASC or createDate_DESC")
+	limit := flags.Int("limit", 10, "Snippets per page")
+	page := flags.Int("page", 1, "Page number, starting at 1")
+	asJSON := flags.Bool("json", false, "Print results as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *limit < 1 || *page < 1 {
+		return errors.New("-limit and -page must be at least 1")
+	}
+
+	snippets, err := c.api.Search(context.Background(), md.MDSearchParams{
+		Text:   strings.Join(flags.Args(), " "),
+		Limit:  int64(*limit),
+		Skip:   int64((*page - 1) * *limit),
+		SortBy: md.SortBy(*sort),
+	})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(c.stdout, snippets)
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCREATED\tTITLE")
+	for _, snippet := range snippets {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", snippet.ID, snippet.CreateDate.Local().Format(time.RFC822), snippet.Title)
+	}
+	if len(snippets) == *limit {
+		fmt.Fprintf(tw, "\n-- more results: -page %d --\n", *page+1)
+	}
+	return tw.Flush()
+}
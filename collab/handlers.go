@@ -0,0 +1,190 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/logging"
+	"github.com/soulxburn/mdsnips/md"
+)
+
+// maxMessageSize Largest message accepted from an editor, room
+// for an operation inserting a whole document.
+const maxMessageSize = 512 * 1024
+
+// Subprotocol WebSocket subprotocol of collaborative editing. Browsers,
+// which cannot set headers, offer the update key as a second subprotocol.
+const Subprotocol = "mdsnips-collab"
+
+type Handlers struct {
+	mdService *md.MDService
+	hub       *Hub
+}
+
+// InitHandlers Creates an instance of Handlers
+// Requires a reference to a md.MDService and the session Hub
+func InitHandlers(mdService *md.MDService, hub *Hub) *Handlers {
+	return &Handlers{mdService: mdService, hub: hub}
+}
+
+// ConfigureRoutes
+// Attaches the collaborative editing route.
+func (h *Handlers) ConfigureRoutes(app *fiber.App) {
+	app.Get("/md/:id/collab", h.CollabHandler)
+}
+
+// CollabHandler GET - Joins a collaborative editing session
+// @Summary Edit a markdown snippet collaboratively over WebSocket
+// @Description Editors exchange JSON messages: `op` carries an ot.js text operation on a `version`,
+// @Description `cursor` an `{anchor, head}` selection. The session replies `init` on joining, `ack` to
+// @Description each op and relays ops, cursors, `join` and `leave` of other editors. Edits are saved
+// @Description as a snippet revision every snapshot interval, announced by `saved`. Changes saved outside
+// @Description the session are merged into it and relayed as ops without an editor, overlapping changes end it.
+// @Description The update key is sent as `X-Update-Key`, or by browsers as the subprotocols `mdsnips-collab, {updateKey}`.
+// @Tags md
+// @Success 101
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 426 {object} api.ErrorResponse
+// @Failure 429 {object} api.ErrorResponse
+// @Failure 503 {object} api.ErrorResponse
+// @Router /md/{id}/collab [get]
+// @Param id path string true "Snippet ID"
+// @Param X-Update-Key header string false "Update Key, required unless offered as a subprotocol"
+// @Param Sec-WebSocket-Protocol header string false "mdsnips-collab, {updateKey}"
+// @Param name query string false "Editor display name"
+func (h *Handlers) CollabHandler(ctx *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(ctx) {
+		return fiber.NewError(http.StatusUpgradeRequired, "WebSocket upgrade required")
+	}
+	id, name := ctx.Params("id"), ctx.Query("name")
+	if len(name) > 64 {
+		return fiber.NewError(http.StatusBadRequest, "name: must be at most 64 characters")
+	}
+
+	key := updateKey(ctx)
+	if key == "" {
+		return fiber.NewError(http.StatusBadRequest, "X-Update-Key header or update key subprotocol is required")
+	}
+
+	tenant := api.Tenant(ctx)
+	service := h.mdService.ForTenant(tenant)
+	if err := service.ValidateIdAndKey(ctx.UserContext(), id, key); err != nil {
+		return httpError(err)
+	}
+	member, err := h.hub.Join(ctx.UserContext(), service, tenant, id, name)
+	if err != nil {
+		return httpError(err)
+	}
+
+	// The connection outlives the handler chain.
+	log := logging.FromContext(api.Detach(ctx.UserContext())).WithField("snippetId", id)
+	heartbeat := h.mdService.Live().Heartbeat()
+	err = websocket.New(func(conn *websocket.Conn) {
+		defer member.Leave()
+		if err := serveEditor(conn, member, heartbeat); err != nil {
+			log.WithError(err).Debug("Collaborative editor disconnected")
+		}
+	}, websocket.Config{Subprotocols: []string{Subprotocol}})(ctx)
+	if err != nil {
+		member.Leave()
+	}
+	return err
+}
+
+// updateKey
+// Returns the update key of the X-Update-Key header,
+// or the subprotocol offered besides Subprotocol.
+// The key is never read from the URL, which is logged.
+func updateKey(ctx *fiber.Ctx) string {
+	if key := ctx.Get("X-Update-Key"); key != "" {
+		return key
+	}
+	for _, protocol := range strings.Split(ctx.Get(fiber.HeaderSecWebSocketProtocol), ",") {
+		if protocol = strings.TrimSpace(protocol); protocol != "" && protocol != Subprotocol {
+			return protocol
+		}
+	}
+	return ""
+}
+
+// serveEditor
+// Relays messages between conn and member, pinging every heartbeat.
+// Editors missing two pongs are disconnected. Returns once
+// the editor disconnects or is removed from the session.
+func serveEditor(conn *websocket.Conn, member *Member, heartbeat time.Duration) error {
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	gone := make(chan struct{})
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	})
+	go func() {
+		defer close(gone)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			msg := new(Message)
+			if err := json.Unmarshal(data, msg); err != nil {
+				member.reject(err)
+				continue
+			}
+			member.Handle(msg)
+		}
+	}()
+
+	write := func(msg *Message) error {
+		conn.SetWriteDeadline(time.Now().Add(heartbeat))
+		return conn.WriteJSON(msg)
+	}
+	for {
+		select {
+		case msg := <-member.Messages():
+			if err := write(msg); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(heartbeat)); err != nil {
+				return err
+			}
+		case <-member.Gone():
+			// Flush what was queued before removal, such as the reason.
+			for len(member.Messages()) > 0 {
+				if err := write(<-member.Messages()); err != nil {
+					return err
+				}
+			}
+			return conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(heartbeat))
+		case <-gone:
+			return nil
+		}
+	}
+}
+
+// httpError
+// Maps collab and md domain errors onto fiber errors,
+// any other error is passed through as an internal error.
+func httpError(err error) error {
+	switch {
+	case errors.Is(err, md.ErrNotFound):
+		return fiber.NewError(http.StatusNotFound, "Markdown Snippet Not Found")
+	case errors.Is(err, md.ErrInvalidKey):
+		return fiber.NewError(http.StatusUnauthorized, "Invalid Update Key")
+	case errors.Is(err, ErrSessionFull):
+		return fiber.NewError(http.StatusTooManyRequests, "Collaborative Session Full")
+	case errors.Is(err, ErrClosed):
+		return fiber.NewError(http.StatusServiceUnavailable, "Collaborative Editing Unavailable")
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+package collab
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_UpdateKey
+// The update key is read from the header or the subprotocols, never the URL.
+func Test_UpdateKey(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		return ctx.SendString(updateKey(ctx))
+	})
+
+	headers := map[string]map[string]string{
+		"header":      {"X-Update-Key": "key"},
+		"subprotocol": {fiber.HeaderSecWebSocketProtocol: Subprotocol + ", key"},
+		"query":       {},
+	}
+	expected := map[string]string{"header": "key", "subprotocol": "key", "query": ""}
+	for name, header := range headers {
+		req := httptest.NewRequest(http.MethodGet, "/?key=key", nil)
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, expected[name], string(body), name)
+	}
+}
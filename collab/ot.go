@@ -0,0 +1,307 @@
+package collab
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ErrInvalidOp is returned for operations that do not fit the document.
+var ErrInvalidOp = errors.New("invalid operation")
+
+// Component
+// One step of an Op, exactly one field is set.
+type Component struct {
+	// Keep the next Retain characters.
+	Retain int
+	// Insert text at the current position.
+	Insert string
+	// Remove the next Delete characters.
+	Delete int
+}
+
+// Op
+// A text operation in the ot.js format, a sequence of retains
+// (positive numbers), inserts (strings) and deletes (negative numbers)
+// spanning the whole document. Lengths count Unicode code points.
+//
+//	[5, "hello", -3, 10]
+type Op []Component
+
+// retain
+// Appends a retain of n characters, merging with a preceding retain.
+func (o Op) retain(n int) Op {
+	if n <= 0 {
+		return o
+	}
+	if last := len(o) - 1; last >= 0 && o[last].Retain > 0 {
+		o[last].Retain += n
+		return o
+	}
+	return append(o, Component{Retain: n})
+}
+
+// insert
+// Appends an insert of s, merging with a preceding insert. Inserts are
+// kept before an adjacent delete, so equivalent operations compare equal.
+func (o Op) insert(s string) Op {
+	if s == "" {
+		return o
+	}
+	last := len(o) - 1
+	switch {
+	case last >= 0 && o[last].Insert != "":
+		o[last].Insert += s
+		return o
+	case last >= 0 && o[last].Delete > 0:
+		if last > 0 && o[last-1].Insert != "" {
+			o[last-1].Insert += s
+			return o
+		}
+		o = append(o, o[last])
+		o[last] = Component{Insert: s}
+		return o
+	}
+	return append(o, Component{Insert: s})
+}
+
+// delete
+// Appends a delete of n characters, merging with a preceding delete.
+func (o Op) delete(n int) Op {
+	if n <= 0 {
+		return o
+	}
+	if last := len(o) - 1; last >= 0 && o[last].Delete > 0 {
+		o[last].Delete += n
+		return o
+	}
+	return append(o, Component{Delete: n})
+}
+
+// BaseLen
+// Returns the length of the document the operation applies to.
+func (o Op) BaseLen() int {
+	n := 0
+	for _, c := range o {
+		n += c.Retain + c.Delete
+	}
+	return n
+}
+
+// TargetLen
+// Returns the length of the document the operation produces.
+func (o Op) TargetLen() int {
+	n := 0
+	for _, c := range o {
+		n += c.Retain + utf8.RuneCountInString(c.Insert)
+	}
+	return n
+}
+
+// IsNoop
+// Reports whether the operation leaves the document unchanged.
+func (o Op) IsNoop() bool {
+	return len(o) == 0 || (len(o) == 1 && o[0].Retain > 0)
+}
+
+// Apply
+// Returns doc with the operation applied.
+// Returns ErrInvalidOp when the operation does not span doc.
+func (o Op) Apply(doc string) (string, error) {
+	runes := []rune(doc)
+	if o.BaseLen() != len(runes) {
+		return "", fmt.Errorf("%w: base length %d does not match document length %d", ErrInvalidOp, o.BaseLen(), len(runes))
+	}
+	result := make([]rune, 0, o.TargetLen())
+	pos := 0
+	for _, c := range o {
+		switch {
+		case c.Retain > 0:
+			result = append(result, runes[pos:pos+c.Retain]...)
+			pos += c.Retain
+		case c.Insert != "":
+			result = append(result, []rune(c.Insert)...)
+		case c.Delete > 0:
+			pos += c.Delete
+		}
+	}
+	return string(result), nil
+}
+
+// Transform
+// Returns a' and b' such that applying a then b' gives the same document
+// as applying b then a', for operations a and b on the same document.
+// Inserts of a at the same position as inserts of b are placed first.
+func Transform(a Op, b Op) (Op, Op, error) {
+	if a.BaseLen() != b.BaseLen() {
+		return nil, nil, fmt.Errorf("%w: concurrent operations have different base lengths", ErrInvalidOp)
+	}
+
+	var aPrime, bPrime Op
+	ai, bi := 0, 0
+	var ac, bc *Component
+	next := func(op Op, i *int) *Component {
+		if *i >= len(op) {
+			return nil
+		}
+		c := op[*i]
+		*i++
+		return &c
+	}
+	ac, bc = next(a, &ai), next(b, &bi)
+	for ac != nil || bc != nil {
+		if ac != nil && ac.Insert != "" {
+			aPrime = aPrime.insert(ac.Insert)
+			bPrime = bPrime.retain(utf8.RuneCountInString(ac.Insert))
+			ac = next(a, &ai)
+			continue
+		}
+		if bc != nil && bc.Insert != "" {
+			aPrime = aPrime.retain(utf8.RuneCountInString(bc.Insert))
+			bPrime = bPrime.insert(bc.Insert)
+			bc = next(b, &bi)
+			continue
+		}
+		if ac == nil || bc == nil {
+			return nil, nil, fmt.Errorf("%w: operation is too short", ErrInvalidOp)
+		}
+
+		n := min(ac.Retain+ac.Delete, bc.Retain+bc.Delete)
+		switch {
+		case ac.Retain > 0 && bc.Retain > 0:
+			aPrime = aPrime.retain(n)
+			bPrime = bPrime.retain(n)
+		case ac.Delete > 0 && bc.Retain > 0:
+			aPrime = aPrime.delete(n)
+		case ac.Retain > 0 && bc.Delete > 0:
+			bPrime = bPrime.delete(n)
+		}
+		// Both deleting the same text needs no output.
+		if ac = consume(ac, n); ac == nil {
+			ac = next(a, &ai)
+		}
+		if bc = consume(bc, n); bc == nil {
+			bc = next(b, &bi)
+		}
+	}
+	return aPrime, bPrime, nil
+}
+
+// consume
+// Shortens a retain or delete by n, returning nil once it is used up.
+func consume(c *Component, n int) *Component {
+	if c.Retain > 0 {
+		c.Retain -= n
+	} else {
+		c.Delete -= n
+	}
+	if c.Retain == 0 && c.Delete == 0 {
+		return nil
+	}
+	return c
+}
+
+// TransformIndex
+// Returns where position index moves to once op is applied.
+func TransformIndex(index int, op Op) int {
+	newIndex := index
+	for _, c := range op {
+		switch {
+		case c.Retain > 0:
+			index -= c.Retain
+		case c.Insert != "":
+			newIndex += utf8.RuneCountInString(c.Insert)
+		case c.Delete > 0:
+			newIndex -= min(index, c.Delete)
+			index -= c.Delete
+		}
+		if index < 0 {
+			break
+		}
+	}
+	return newIndex
+}
+
+// replaceOp
+// Returns an operation turning from into to, retaining
+// their common prefix and suffix and replacing the rest.
+func replaceOp(from string, to string) Op {
+	a, b := []rune(from), []rune(to)
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var op Op
+	op = op.retain(prefix)
+	op = op.insert(string(b[prefix : len(b)-suffix]))
+	op = op.delete(len(a) - prefix - suffix)
+	return op.retain(suffix)
+}
+
+// MarshalJSON
+// Encodes the operation in the ot.js format.
+func (o Op) MarshalJSON() ([]byte, error) {
+	parts := make([]interface{}, len(o))
+	for i, c := range o {
+		switch {
+		case c.Retain > 0:
+			parts[i] = c.Retain
+		case c.Insert != "":
+			parts[i] = c.Insert
+		default:
+			parts[i] = -c.Delete
+		}
+	}
+	return json.Marshal(parts)
+}
+
+// UnmarshalJSON
+// Decodes an operation in the ot.js format,
+// rejecting zero lengths, fractions and empty inserts.
+func (o *Op) UnmarshalJSON(data []byte) error {
+	var parts []json.RawMessage
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+	op := make(Op, 0, len(parts))
+	for _, part := range parts {
+		if bytes.HasPrefix(part, []byte(`"`)) {
+			var s string
+			if err := json.Unmarshal(part, &s); err != nil {
+				return err
+			}
+			if s == "" {
+				return fmt.Errorf("%w: empty insert", ErrInvalidOp)
+			}
+			op = op.insert(s)
+			continue
+		}
+		var n int
+		if err := json.Unmarshal(part, &n); err != nil {
+			return fmt.Errorf("%w: %s is not a retain, insert or delete", ErrInvalidOp, part)
+		}
+		switch {
+		case n > 0:
+			op = op.retain(n)
+		case n < 0:
+			op = op.delete(-n)
+		default:
+			return fmt.Errorf("%w: zero length component", ErrInvalidOp)
+		}
+	}
+	*o = op
+	return nil
+}
+
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
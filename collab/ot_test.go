@@ -0,0 +1,136 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_OpJSON
+// Operations round trip through the ot.js format.
+func Test_OpJSON(t *testing.T) {
+	op := new(Op)
+	assert.Nil(t, json.Unmarshal([]byte(`[2, "héllo", -3, 1]`), op))
+	assert.Equal(t, Op{{Retain: 2}, {Insert: "héllo"}, {Delete: 3}, {Retain: 1}}, *op)
+	assert.Equal(t, 6, op.BaseLen())
+	assert.Equal(t, 8, op.TargetLen())
+
+	data, err := json.Marshal(op)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `[2, "héllo", -3, 1]`, string(data))
+
+	for _, invalid := range []string{`[0]`, `[""]`, `[1.5]`, `[true]`, `{}`} {
+		assert.NotNil(t, json.Unmarshal([]byte(invalid), op), invalid)
+	}
+}
+
+// Test_OpApply
+// Apply counts code points and requires the base length to match.
+func Test_OpApply(t *testing.T) {
+	op := Op{{Retain: 2}, {Insert: "ü"}, {Delete: 1}, {Retain: 2}}
+	doc, err := op.Apply("añbcd")
+	assert.Nil(t, err)
+	assert.Equal(t, "añücd", doc)
+
+	_, err = op.Apply("abc")
+	assert.True(t, errors.Is(err, ErrInvalidOp))
+}
+
+// Test_TransformTies
+// Concurrent inserts at the same position place the first operation's first.
+func Test_TransformTies(t *testing.T) {
+	a := Op{{Retain: 1}, {Insert: "A"}, {Retain: 1}}
+	b := Op{{Retain: 1}, {Insert: "B"}, {Retain: 1}}
+	aPrime, bPrime, err := Transform(a, b)
+	assert.Nil(t, err)
+
+	viaA, _ := a.Apply("xy")
+	viaA, _ = bPrime.Apply(viaA)
+	viaB, _ := b.Apply("xy")
+	viaB, _ = aPrime.Apply(viaB)
+	assert.Equal(t, "xABy", viaA)
+	assert.Equal(t, viaA, viaB)
+
+	_, _, err = Transform(a, Op{{Retain: 5}})
+	assert.True(t, errors.Is(err, ErrInvalidOp))
+}
+
+// Test_TransformConverges
+// Random concurrent operations converge whichever order they apply in.
+func Test_TransformConverges(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		doc := randomText(rnd, rnd.Intn(20))
+		a, b := randomOp(rnd, doc), randomOp(rnd, doc)
+		aPrime, bPrime, err := Transform(a, b)
+		assert.Nil(t, err)
+
+		viaA, err := a.Apply(doc)
+		assert.Nil(t, err)
+		viaA, err = bPrime.Apply(viaA)
+		assert.Nil(t, err)
+		viaB, err := b.Apply(doc)
+		assert.Nil(t, err)
+		viaB, err = aPrime.Apply(viaB)
+		assert.Nil(t, err)
+		if !assert.Equal(t, viaA, viaB, "doc %q a %v b %v", doc, a, b) {
+			return
+		}
+	}
+}
+
+// Test_TransformIndex
+// Positions shift with inserts and deletes before them.
+func Test_TransformIndex(t *testing.T) {
+	op := Op{{Retain: 2}, {Insert: "xyz"}, {Delete: 2}, {Retain: 4}}
+	assert.Equal(t, 1, TransformIndex(1, op))
+	assert.Equal(t, 5, TransformIndex(2, op))
+	assert.Equal(t, 5, TransformIndex(3, op))
+	assert.Equal(t, 5, TransformIndex(4, op))
+	assert.Equal(t, 7, TransformIndex(6, op))
+}
+
+func randomText(rnd *rand.Rand, n int) string {
+	runes := make([]rune, n)
+	for i := range runes {
+		runes[i] = []rune("abcdé漢 ")[rnd.Intn(7)]
+	}
+	return string(runes)
+}
+
+func randomOp(rnd *rand.Rand, doc string) Op {
+	var op Op
+	remaining := utf8.RuneCountInString(doc)
+	for remaining > 0 {
+		n := 1 + rnd.Intn(remaining)
+		switch rnd.Intn(3) {
+		case 0:
+			op = op.retain(n)
+		case 1:
+			op = op.delete(n)
+		default:
+			op = op.insert(randomText(rnd, 1+rnd.Intn(3)))
+			continue
+		}
+		remaining -= n
+	}
+	if rnd.Intn(2) == 0 {
+		op = op.insert(randomText(rnd, 1+rnd.Intn(3)))
+	}
+	return op
+}
+
+// Test_ReplaceOp
+func Test_ReplaceOp(t *testing.T) {
+	for _, c := range [][2]string{{"hello world", "hello there world"}, {"abc", ""}, {"", "abc"}, {"añb", "aéb"}, {"same", "same"}} {
+		op := replaceOp(c[0], c[1])
+		doc, err := op.Apply(c[0])
+		assert.Nil(t, err)
+		assert.Equal(t, c[1], doc)
+	}
+	assert.Equal(t, Op{{Retain: 6}, {Insert: "there "}, {Retain: 5}}, replaceOp("hello world", "hello there world"))
+}
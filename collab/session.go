@@ -0,0 +1,601 @@
+package collab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/logging"
+	"github.com/soulxburn/mdsnips/md"
+)
+
+var (
+	// ErrSessionFull is returned when a session has the maximum number of editors.
+	ErrSessionFull = errors.New("collaborative session is full")
+	// ErrClosed is returned when joining after the Hub was closed.
+	ErrClosed = errors.New("collaborative editing is shutting down")
+
+	// errSessionEnding is returned when joining a session
+	// that is saving its final snapshot.
+	errSessionEnding = errors.New("collaborative session is ending")
+)
+
+const (
+	// sendBuffer Messages queued per editor before it is dropped as too slow.
+	sendBuffer = 64
+	// maxBodyLength Longest document accepted, matching CreateMDReq.
+	maxBodyLength = 64000
+	// maxHistory Operations kept at least to transform ops on older
+	// versions. Ops on versions dropped since are rejected.
+	maxHistory = 1000
+)
+
+// Message types exchanged with editors.
+const (
+	// MessageInit Sent on joining, with the document, version and editors.
+	MessageInit = "init"
+	// MessageOp An operation on a version, sent by editors and relayed to the others.
+	MessageOp = "op"
+	// MessageAck Confirms an editor's operation, with the version it produced.
+	MessageAck = "ack"
+	// MessageCursor An editor's selection on a version.
+	MessageCursor = "cursor"
+	// MessageJoin An editor joined the session.
+	MessageJoin = "join"
+	// MessageLeave An editor left the session.
+	MessageLeave = "leave"
+	// MessageSaved A snapshot was saved as a snippet revision.
+	MessageSaved = "saved"
+	// MessageError A message was rejected, or the session ended.
+	MessageError = "error"
+)
+
+// Cursor
+// An editor's selection, as code point offsets into the document.
+type Cursor struct {
+	Anchor int `json:"anchor"`
+	Head   int `json:"head"`
+}
+
+// Editor
+// Presence of an editor in a session.
+type Editor struct {
+	// Editor guid, assigned on joining.
+	ID string `json:"id"`
+	// Display name given on joining.
+	Name string `json:"name,omitempty"`
+	// Latest selection, unset until the editor sends one.
+	Cursor *Cursor `json:"cursor,omitempty"`
+}
+
+// Message
+// A WebSocket message between an editor and the session.
+type Message struct {
+	// One of the Message* types.
+	Type string `json:"type"`
+	// Document version the op or cursor applies to. For init
+	// and ack, the version of the session after the change.
+	Version int `json:"version"`
+	// Operation of an op message.
+	Op Op `json:"op,omitempty"`
+	// Selection of a cursor message.
+	Cursor *Cursor `json:"cursor,omitempty"`
+	// Editor an op, cursor, join or leave message is about,
+	// or the receiving editor for init. Unset for ops merging
+	// changes saved outside the session.
+	Editor *Editor `json:"editor,omitempty"`
+	// Document of an init message.
+	Body *string `json:"body,omitempty"`
+	// Editors already in the session, for init.
+	Editors []*Editor `json:"editors,omitempty"`
+	// Latest saved snippet revision, for init and saved.
+	Revision int64 `json:"revision,omitempty"`
+	// Reason an error message was sent.
+	Error string `json:"error,omitempty"`
+}
+
+// Hub
+// Tracks the open collaborative editing session of each snippet.
+type Hub struct {
+	cfg config.Collab
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+	closed   bool
+}
+
+// NewHub
+// Returns a Hub with no sessions, filling unset settings with their defaults.
+func NewHub(cfg config.Collab) *Hub {
+	if cfg.SnapshotInterval <= 0 {
+		cfg.SnapshotInterval = 10 * time.Second
+	}
+	if cfg.MaxEditors <= 0 {
+		cfg.MaxEditors = 20
+	}
+	return &Hub{cfg: cfg, sessions: make(map[string]*Session)}
+}
+
+// Join
+// Adds an editor to the session of snippet id, opening the session
+// from the saved snippet when none is open. service must be scoped to tenant.
+func (h *Hub) Join(ctx context.Context, service *md.MDService, tenant string, id string, name string) (*Member, error) {
+	key := tenant + "/" + id
+	for {
+		h.mu.Lock()
+		if h.closed {
+			h.mu.Unlock()
+			return nil, ErrClosed
+		}
+		s, open := h.sessions[key]
+		if !open {
+			s = &Session{
+				hub:     h,
+				key:     key,
+				id:      id,
+				service: service,
+				members: make(map[string]*Member),
+				ready:   make(chan struct{}),
+				stop:    make(chan struct{}),
+				closed:  make(chan struct{}),
+			}
+			h.sessions[key] = s
+		}
+		h.mu.Unlock()
+		if !open {
+			s.open(ctx)
+		}
+
+		select {
+		case <-s.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if s.err != nil {
+			return nil, s.err
+		}
+		member, err := s.join(name)
+		if !errors.Is(err, errSessionEnding) {
+			return member, err
+		}
+		select {
+		case <-s.closed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Close
+// Disconnects every editor and saves open sessions,
+// waiting until they are saved or ctx ends.
+func (h *Hub) Close(ctx context.Context) {
+	h.mu.Lock()
+	h.closed = true
+	sessions := make([]*Session, 0, len(h.sessions))
+	for _, s := range h.sessions {
+		sessions = append(sessions, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range sessions {
+		s.end("server shutting down")
+	}
+	for _, s := range sessions {
+		select {
+		case <-s.closed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// remove
+// Forgets s once it has closed.
+func (h *Hub) remove(s *Session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.sessions[s.key] == s {
+		delete(h.sessions, s.key)
+	}
+}
+
+// Session
+// The shared document of one snippet. Operations are transformed
+// against those applied since their base version, so every editor
+// converges on the same document, which is saved periodically.
+type Session struct {
+	hub     *Hub
+	key     string
+	id      string
+	service *md.MDService
+
+	// Closed once the snippet is loaded, err is then set on failure.
+	ready chan struct{}
+	err   error
+	// Closed when the last editor leaves, stopping the snapshot loop.
+	stop chan struct{}
+	// Closed once the final snapshot is saved.
+	closed chan struct{}
+
+	mu      sync.Mutex
+	doc     string
+	version int
+	// history[v-first] transforms version v into v+1.
+	history []Op
+	first   int
+	// Version of the latest saved snapshot.
+	saved int
+	// Snippet revision the document was loaded from or last saved as,
+	// snapshots are merged with revisions saved since.
+	revision int64
+	members  map[string]*Member
+	ending   bool
+}
+
+// open
+// Loads the snippet and starts the snapshot loop.
+func (s *Session) open(ctx context.Context) {
+	defer close(s.ready)
+	snippet, err := s.service.GetMarkdownSnippet(ctx, s.id)
+	if err != nil {
+		s.err = err
+		s.hub.remove(s)
+		close(s.closed)
+		return
+	}
+	s.doc, s.revision = snippet.Body, snippet.Revision
+	go s.run()
+}
+
+// run
+// Saves a snapshot every interval while the document changed,
+// and a final one once the session ends.
+func (s *Session) run() {
+	ticker := time.NewTicker(s.hub.cfg.SnapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.persist()
+		case <-s.stop:
+			s.persist()
+			s.hub.remove(s)
+			close(s.closed)
+			return
+		}
+	}
+}
+
+// persist
+// Saves the document as a snippet revision when it changed since
+// the last snapshot. Changes saved outside the session since are merged
+// into the document. Failures are retried on the next snapshot, a deleted
+// snippet or changes overlapping the session's end the session.
+func (s *Session) persist() {
+	s.mu.Lock()
+	body, version, base := s.doc, s.version, s.revision
+	changed := version != s.saved
+	s.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	log := logging.Logger.WithField("snippetId", s.id)
+	snippet, err := s.service.SaveSnapshot(context.Background(), s.id, body, base)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case errors.Is(err, md.ErrNotFound):
+		s.saved = version
+		s.endLocked("snippet was deleted")
+		return
+	case errors.Is(err, md.ErrConflict), errors.Is(err, md.ErrInvalidRevision):
+		log.WithError(err).Warn("Collaborative snapshot conflicts with saved changes")
+		s.saved = version
+		s.endLocked("snippet was changed outside the session")
+		return
+	case err != nil:
+		log.WithError(err).Error("Failed to save collaborative snapshot")
+		return
+	}
+	s.saved, s.revision = version, snippet.Revision
+	log.WithField("revision", snippet.Revision).Debug("Collaborative snapshot saved")
+	if snippet.Body != body {
+		// Relay the merged changes as an op on the snapshot version.
+		op, err := s.commit(version, replaceOp(body, snippet.Body))
+		if err != nil {
+			log.WithError(err).Error("Failed to merge saved changes into collaborative session")
+			s.endLocked("snippet was changed outside the session")
+			return
+		}
+		if s.version == version+1 {
+			s.saved = s.version
+		}
+		s.broadcast(&Message{Type: MessageOp, Version: s.version - 1, Op: op}, nil)
+	}
+	s.broadcast(&Message{Type: MessageSaved, Version: version, Revision: snippet.Revision}, nil)
+}
+
+// join
+// Registers a new editor, queueing its init message
+// and announcing it to the others.
+func (s *Session) join(name string) (*Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ending {
+		return nil, errSessionEnding
+	}
+	if len(s.members) >= s.hub.cfg.MaxEditors {
+		return nil, ErrSessionFull
+	}
+
+	member := &Member{
+		session: s,
+		editor:  Editor{ID: uuid.NewString(), Name: name},
+		send:    make(chan *Message, sendBuffer),
+		gone:    make(chan struct{}),
+	}
+	editors := make([]*Editor, 0, len(s.members))
+	for _, other := range s.members {
+		editors = append(editors, other.presence())
+	}
+	body := s.doc
+	member.send <- &Message{
+		Type:     MessageInit,
+		Version:  s.version,
+		Editor:   member.presence(),
+		Body:     &body,
+		Editors:  editors,
+		Revision: s.revision,
+	}
+	s.broadcast(&Message{Type: MessageJoin, Version: s.version, Editor: member.presence()}, nil)
+	s.members[member.editor.ID] = member
+	return member, nil
+}
+
+// handle
+// Applies a message received from member.
+func (s *Session) handle(member *Member, msg *Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.members[member.editor.ID]; !ok {
+		return
+	}
+
+	var err error
+	switch msg.Type {
+	case MessageOp:
+		err = s.apply(member, msg.Version, msg.Op)
+	case MessageCursor:
+		err = s.moveCursor(member, msg.Version, msg.Cursor)
+	default:
+		err = fmt.Errorf("unknown message type `%s`", msg.Type)
+	}
+	if err != nil {
+		s.deliver(member, &Message{Type: MessageError, Version: s.version, Error: err.Error()})
+	}
+}
+
+// apply
+// Transforms op from its base version to the current one,
+// applies it and relays it to the other editors. Requires s.mu.
+func (s *Session) apply(member *Member, base int, op Op) error {
+	op, err := s.commit(base, op)
+	if err != nil {
+		return err
+	}
+	s.deliver(member, &Message{Type: MessageAck, Version: s.version})
+	s.broadcast(&Message{Type: MessageOp, Version: s.version - 1, Op: op, Editor: member.presence()}, member)
+	return nil
+}
+
+// commit
+// Transforms op from its base version to the current one and applies it,
+// returning the applied op. Requires s.mu.
+func (s *Session) commit(base int, op Op) (Op, error) {
+	op, err := s.rebase(base, op)
+	if err != nil {
+		return nil, err
+	}
+	if op.TargetLen() > maxBodyLength {
+		return nil, fmt.Errorf("%w: document would exceed %d characters", ErrInvalidOp, maxBodyLength)
+	}
+	doc, err := op.Apply(s.doc)
+	if err != nil {
+		return nil, err
+	}
+
+	s.doc = doc
+	s.history = append(s.history, op)
+	s.version++
+	if len(s.history) > 2*maxHistory {
+		dropped := len(s.history) - maxHistory
+		s.history = append([]Op(nil), s.history[dropped:]...)
+		s.first += dropped
+	}
+	for _, other := range s.members {
+		if cursor := other.editor.Cursor; cursor != nil {
+			cursor.Anchor = TransformIndex(cursor.Anchor, op)
+			cursor.Head = TransformIndex(cursor.Head, op)
+		}
+	}
+	return op, nil
+}
+
+// since
+// Returns the operations applied since version base. Requires s.mu.
+func (s *Session) since(base int) ([]Op, error) {
+	switch {
+	case base < 0 || base > s.version:
+		return nil, fmt.Errorf("%w: unknown version %d", ErrInvalidOp, base)
+	case base < s.first:
+		return nil, fmt.Errorf("%w: version %d is too old, rejoin the session", ErrInvalidOp, base)
+	}
+	return s.history[base-s.first:], nil
+}
+
+// rebase
+// Transforms op on version base against every later operation. Requires s.mu.
+func (s *Session) rebase(base int, op Op) (Op, error) {
+	applied, err := s.since(base)
+	if err != nil {
+		return nil, err
+	}
+	for _, later := range applied {
+		if op, _, err = Transform(op, later); err != nil {
+			return nil, err
+		}
+	}
+	return op, nil
+}
+
+// moveCursor
+// Records member's selection on version base, transformed to
+// the current version, and relays it to the other editors. Requires s.mu.
+func (s *Session) moveCursor(member *Member, base int, cursor *Cursor) error {
+	if cursor == nil {
+		return errors.New("cursor is required")
+	}
+	history, err := s.since(base)
+	if err != nil {
+		return err
+	}
+	moved := *cursor
+	for _, applied := range history {
+		moved.Anchor = TransformIndex(moved.Anchor, applied)
+		moved.Head = TransformIndex(moved.Head, applied)
+	}
+	member.editor.Cursor = &moved
+	s.broadcast(&Message{Type: MessageCursor, Version: s.version, Cursor: &moved, Editor: member.presence()}, member)
+	return nil
+}
+
+// leave
+// Removes member, ending the session when it was the last editor.
+func (s *Session) leave(member *Member) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(member)
+}
+
+// end
+// Disconnects every editor with reason, ending the session.
+func (s *Session) end(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endLocked(reason)
+}
+
+// endLocked
+// Disconnects every editor with reason. Requires s.mu.
+func (s *Session) endLocked(reason string) {
+	for _, member := range s.members {
+		s.deliver(member, &Message{Type: MessageError, Version: s.version, Error: reason})
+	}
+	for _, member := range s.members {
+		s.removeLocked(member)
+	}
+	if !s.ending {
+		s.ending = true
+		close(s.stop)
+	}
+}
+
+// removeLocked
+// Unregisters member and announces it left, stopping the
+// session once empty. Requires s.mu.
+func (s *Session) removeLocked(member *Member) {
+	if _, ok := s.members[member.editor.ID]; !ok {
+		return
+	}
+	delete(s.members, member.editor.ID)
+	member.once.Do(func() { close(member.gone) })
+	s.broadcast(&Message{Type: MessageLeave, Version: s.version, Editor: member.presence()}, nil)
+	if len(s.members) == 0 && !s.ending {
+		s.ending = true
+		close(s.stop)
+	}
+}
+
+// broadcast
+// Queues msg for every editor except skip. Requires s.mu.
+func (s *Session) broadcast(msg *Message, skip *Member) {
+	for _, member := range s.members {
+		if member != skip {
+			s.deliver(member, msg)
+		}
+	}
+}
+
+// deliver
+// Queues msg for member, dropping it when its queue is full. Requires s.mu.
+func (s *Session) deliver(member *Member, msg *Message) {
+	select {
+	case member.send <- msg:
+	default:
+		s.removeLocked(member)
+	}
+}
+
+// Member
+// An editor connected to a Session.
+type Member struct {
+	session *Session
+	editor  Editor
+	// Messages queued for the editor.
+	send chan *Message
+	// Closed once the editor is removed from the session.
+	gone chan struct{}
+	once sync.Once
+}
+
+// presence
+// Returns a copy of the member's presence. Requires session.mu.
+func (m *Member) presence() *Editor {
+	editor := m.editor
+	if editor.Cursor != nil {
+		cursor := *editor.Cursor
+		editor.Cursor = &cursor
+	}
+	return &editor
+}
+
+// Handle
+// Applies a message sent by the editor.
+func (m *Member) Handle(msg *Message) {
+	m.session.handle(m, msg)
+}
+
+// reject
+// Sends the editor an error for a message that could not be read.
+func (m *Member) reject(err error) {
+	s := m.session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.members[m.editor.ID]; ok {
+		s.deliver(m, &Message{Type: MessageError, Version: s.version, Error: err.Error()})
+	}
+}
+
+// Messages
+// Returns the queue of messages for the editor.
+func (m *Member) Messages() <-chan *Message {
+	return m.send
+}
+
+// Gone
+// Returns a channel closed once the editor has left or was dropped.
+func (m *Member) Gone() <-chan struct{} {
+	return m.gone
+}
+
+// Leave
+// Removes the editor from the session, it is safe to call more than once.
+func (m *Member) Leave() {
+	m.session.leave(m)
+}
@@ -0,0 +1,144 @@
+package collab
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// SetupSession
+// Returns an open session on doc without a backing snippet,
+// snapshots are never taken.
+func SetupSession(doc string, maxEditors int) *Session {
+	hub := NewHub(config.Collab{MaxEditors: maxEditors})
+	s := &Session{
+		hub:     hub,
+		key:     "/test",
+		id:      "test",
+		doc:     doc,
+		members: make(map[string]*Member),
+		ready:   make(chan struct{}),
+		stop:    make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+	close(s.ready)
+	hub.sessions[s.key] = s
+	return s
+}
+
+// next
+// Returns the next queued message for member.
+func next(t *testing.T, member *Member) *Message {
+	select {
+	case msg := <-member.Messages():
+		return msg
+	default:
+		t.Fatal("no message queued")
+		return nil
+	}
+}
+
+// Test_SessionConcurrentOps
+// Operations on an old version are transformed against later ones,
+// acknowledged to their editor and relayed to the others.
+func Test_SessionConcurrentOps(t *testing.T) {
+	s := SetupSession("hello", 0)
+	alice, err := s.join("alice")
+	assert.Nil(t, err)
+	bob, err := s.join("bob")
+	assert.Nil(t, err)
+
+	init := next(t, alice)
+	assert.Equal(t, MessageInit, init.Type)
+	assert.Equal(t, "hello", *init.Body)
+	assert.Equal(t, MessageJoin, next(t, alice).Type)
+	assert.Equal(t, "alice", next(t, bob).Editors[0].Name)
+
+	alice.Handle(&Message{Type: MessageOp, Version: 0, Op: Op{{Insert: "oh "}, {Retain: 5}}})
+	bob.Handle(&Message{Type: MessageOp, Version: 0, Op: Op{{Retain: 5}, {Insert: "!"}}})
+	assert.Equal(t, "oh hello!", s.doc)
+	assert.Equal(t, 2, s.version)
+
+	assert.Equal(t, &Message{Type: MessageAck, Version: 1}, next(t, alice))
+	relayed := next(t, bob)
+	assert.Equal(t, MessageOp, relayed.Type)
+	assert.Equal(t, 0, relayed.Version)
+	assert.Equal(t, "alice", relayed.Editor.Name)
+	assert.Equal(t, &Message{Type: MessageAck, Version: 2}, next(t, bob))
+	relayed = next(t, alice)
+	assert.Equal(t, 1, relayed.Version)
+	assert.Equal(t, Op{{Retain: 8}, {Insert: "!"}}, relayed.Op)
+
+	bob.Handle(&Message{Type: MessageOp, Version: 5, Op: Op{{Retain: 9}}})
+	assert.Equal(t, MessageError, next(t, bob).Type)
+}
+
+// Test_SessionCursors
+// Cursors are transformed to the current version and
+// follow later edits.
+func Test_SessionCursors(t *testing.T) {
+	s := SetupSession("abc", 0)
+	alice, _ := s.join("alice")
+	bob, _ := s.join("bob")
+	alice.Handle(&Message{Type: MessageOp, Version: 0, Op: Op{{Insert: "xx"}, {Retain: 3}}})
+
+	bob.Handle(&Message{Type: MessageCursor, Version: 0, Cursor: &Cursor{Anchor: 1, Head: 3}})
+	assert.Equal(t, &Cursor{Anchor: 3, Head: 5}, bob.editor.Cursor)
+
+	alice.Handle(&Message{Type: MessageOp, Version: 1, Op: Op{{Delete: 2}, {Retain: 3}}})
+	assert.Equal(t, &Cursor{Anchor: 1, Head: 3}, bob.editor.Cursor)
+
+	carol, _ := s.join("carol")
+	editors := next(t, carol).Editors
+	assert.Len(t, editors, 2)
+	for _, editor := range editors {
+		if editor.Name == "bob" {
+			assert.Equal(t, &Cursor{Anchor: 1, Head: 3}, editor.Cursor)
+		}
+	}
+}
+
+// Test_SessionMembership
+// Sessions are limited to MaxEditors and end with their last editor.
+func Test_SessionMembership(t *testing.T) {
+	s := SetupSession("abc", 2)
+	alice, _ := s.join("alice")
+	bob, _ := s.join("bob")
+	_, err := s.join("carol")
+	assert.True(t, errors.Is(err, ErrSessionFull))
+
+	bob.Leave()
+	bob.Leave()
+	<-bob.Gone()
+	messages := []*Message{next(t, alice), next(t, alice), next(t, alice)}
+	assert.Equal(t, MessageLeave, messages[2].Type)
+
+	alice.Leave()
+	<-s.stop
+	_, err = s.join("dave")
+	assert.True(t, errors.Is(err, errSessionEnding))
+}
+
+// Test_SessionHistoryLimit
+// History is trimmed, ops on versions dropped from it are rejected.
+func Test_SessionHistoryLimit(t *testing.T) {
+	s := SetupSession("", 0)
+	alice, _ := s.join("alice")
+	next(t, alice)
+	for i := 0; i <= 2*maxHistory; i++ {
+		_, err := s.commit(i, Op{{Retain: i}, {Insert: "x"}})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 2*maxHistory+1, s.version)
+	assert.Len(t, s.history, maxHistory)
+
+	alice.Handle(&Message{Type: MessageOp, Version: 0, Op: Op{{Insert: "y"}}})
+	rejected := next(t, alice)
+	assert.Equal(t, MessageError, rejected.Type)
+	assert.Contains(t, rejected.Error, "version 0 is too old")
+
+	alice.Handle(&Message{Type: MessageOp, Version: s.first, Op: Op{{Insert: "y"}, {Retain: s.first}}})
+	assert.Equal(t, MessageAck, next(t, alice).Type)
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+func init() {
+	register(&Command{
+		Name:    "backup",
+		Summary: "Write every snippet and its revisions, not attachments, to a gzip compressed JSONL file",
+		Run:     runBackup,
+	})
+}
+
+// runBackup
+// Streams every snippet and its revisions to a gzip compressed JSON lines file.
+// Attachments are not included.
+// Usage: mdsnips backup [-out file] [-tenant id]
+func runBackup(env *Env, args []string) error {
+	flags := newFlagSet(env, "backup")
+	out := flags.String("out", "mdsnips-"+time.Now().UTC().Format("20060102-150405")+".jsonl.gz", "Backup file, `-` for stdout")
+	tenant := flags.String("tenant", "", "Tenant to back up, required when tenancy is enabled")
+	timeout := flags.Duration("timeout", time.Hour, "Maximum time to write the backup")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	mClient, err := env.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer mClient.Disconnect(context.Background())
+
+	mdService, err := env.Service(ctx, mClient, *tenant)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = env.Stdout
+	if *out != "-" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	gz := gzip.NewWriter(w)
+	count, err := mdService.BackupSnippets(ctx, gz)
+	if err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if *out != "-" {
+		fmt.Fprintf(env.Stderr, "%d snippet(s) written to %s\n", count, *out)
+	}
+	return nil
+}
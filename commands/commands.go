@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/joho/godotenv"
+	"github.com/soulxburn/mdsnips/blob"
+	"github.com/soulxburn/mdsnips/client"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/logging"
+	"github.com/soulxburn/mdsnips/md"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Command
+// A mdsnips subcommand, run with the arguments following its name.
+type Command struct {
+	Name    string
+	Summary string
+	Run     func(env *Env, args []string) error
+}
+
+// commands Registered subcommands by name.
+var commands = map[string]*Command{}
+
+// register
+// Adds cmd to the set of subcommands.
+func register(cmd *Command) {
+	commands[cmd.Name] = cmd
+}
+
+// Env
+// Configuration and Mongo client setup shared by every command.
+type Env struct {
+	Config *config.Config
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Connect
+// Returns a mongo.Client once the server responds to a ping.
+// The caller is responsible for disconnecting it.
+func (e *Env) Connect(ctx context.Context) (*mongo.Client, error) {
+	mClient, err := client.NewMongoClient(e.Config.Mongo)
+	if err != nil {
+		return nil, err
+	}
+	if err := mClient.Ping(ctx, nil); err != nil {
+		mClient.Disconnect(context.Background())
+		return nil, fmt.Errorf("mongo unreachable: %w", err)
+	}
+	return mClient, nil
+}
+
+// Service
+// Returns a MDService scoped to tenant over mClient, with the configured blob store.
+// tenant is required when tenancy is enabled, and must be configured.
+func (e *Env) Service(ctx context.Context, mClient *mongo.Client, tenant string) (*md.MDService, error) {
+	if e.Config.Tenant.Enabled() && tenant == "" {
+		return nil, errors.New("-tenant is required when tenancy is enabled")
+	}
+	if e.Config.Tenant.Enabled() && !e.Config.Tenant.Allowed(tenant) {
+		return nil, fmt.Errorf("-tenant `%s` is not a configured tenant", tenant)
+	}
+	store, err := blob.New(ctx, e.Config.Blob)
+	if err != nil {
+		return nil, err
+	}
+	return md.InitMDService(mClient, e.Config).WithBlobStore(store).ForTenant(tenant), nil
+}
+
+// Run
+// Loads configuration and runs the subcommand named by args[0],
+// `serve` when no subcommand is given. Returns the process exit code.
+func Run(args []string) int {
+	if err := godotenv.Load(); err != nil {
+		logging.Logger.Info("No .env file found")
+	}
+	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		logging.Logger.Error(err)
+		return 1
+	}
+	if err := logging.Configure(cfg.LogLevel); err != nil {
+		logging.Logger.Error(err)
+		return 1
+	}
+
+	return dispatch(&Env{Config: cfg, Stdout: os.Stdout, Stderr: os.Stderr}, args)
+}
+
+// dispatch
+// Runs the subcommand named by args[0] against env.
+func dispatch(env *Env, args []string) int {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		usage(env.Stdout)
+		return 0
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(env.Stderr, "mdsnips: unknown command %q\n\n", name)
+		usage(env.Stderr)
+		return 2
+	}
+	if err := cmd.Run(env, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(env.Stderr, "mdsnips %s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// usage
+// Writes the list of subcommands to w.
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: mdsnips <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].Summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run `mdsnips <command> -h` for command flags.")
+}
+
+// newFlagSet
+// Returns a FlagSet for name that reports errors
+// instead of exiting, writing usage to env.Stderr.
+func newFlagSet(env *Env, name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("mdsnips "+name, flag.ContinueOnError)
+	flags.SetOutput(env.Stderr)
+	return flags
+}
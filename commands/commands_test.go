@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// testEnv
+// Returns an Env writing to buffers.
+func testEnv() (*Env, *bytes.Buffer, *bytes.Buffer) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	return &Env{Config: &config.Config{}, Stdout: stdout, Stderr: stderr}, stdout, stderr
+}
+
+// Test_DispatchUnknownCommand
+// Unknown commands exit 2 and print usage.
+func Test_DispatchUnknownCommand(t *testing.T) {
+	env, _, stderr := testEnv()
+
+	assert.Equal(t, 2, dispatch(env, []string{"frobnicate"}))
+	assert.Contains(t, stderr.String(), `unknown command "frobnicate"`)
+	assert.Contains(t, stderr.String(), "restore")
+}
+
+// Test_DispatchHelp
+// Help lists every registered command.
+func Test_DispatchHelp(t *testing.T) {
+	env, stdout, _ := testEnv()
+
+	assert.Equal(t, 0, dispatch(env, []string{"help"}))
+	for _, name := range []string{"serve", "migrate", "reindex", "backup", "restore", "stats", "trash"} {
+		assert.Contains(t, stdout.String(), name)
+	}
+}
+
+// Test_DispatchFlagError
+// Invalid flags fail before connecting to Mongo.
+func Test_DispatchFlagError(t *testing.T) {
+	env, _, stderr := testEnv()
+
+	assert.Equal(t, 1, dispatch(env, []string{"reindex", "-weights", "author=3"}))
+	assert.Contains(t, stderr.String(), "`author` is not a text index field")
+}
+
+// Test_ServiceTenantRequired
+// Tenant scoped commands require -tenant when tenancy is enabled.
+func Test_ServiceTenantRequired(t *testing.T) {
+	env, _, _ := testEnv()
+	env.Config.Tenant = config.Tenant{Mode: config.TenancyFilter}
+
+	_, err := env.Service(context.Background(), nil, "")
+	assert.EqualError(t, err, "-tenant is required when tenancy is enabled")
+}
+
+// Test_ParseWeights
+func Test_ParseWeights(t *testing.T) {
+	weights, err := parseWeights("title=10, body=1")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int32{"title": 10, "body": 1}, weights)
+
+	weights, err = parseWeights("")
+	assert.Nil(t, err)
+	assert.Nil(t, weights)
+
+	for _, invalid := range []string{"title", "title=0", "title=abc", "body=100000", "tags=2"} {
+		_, err := parseWeights(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+// Test_Decompress
+// Backups are read whether or not they are compressed.
+func Test_Decompress(t *testing.T) {
+	line := `{"id":"abc"}` + "\n"
+
+	compressed := new(bytes.Buffer)
+	gz := gzip.NewWriter(compressed)
+	gz.Write([]byte(line))
+	gz.Close()
+
+	for _, input := range []io.Reader{compressed, strings.NewReader(line)} {
+		r, err := decompress(input)
+		assert.Nil(t, err)
+		content, err := io.ReadAll(r)
+		assert.Nil(t, err)
+		assert.Equal(t, line, string(content))
+	}
+}
+
+// Test_IsUpload
+// Only attachment uploads are allowed the larger body limit.
+func Test_IsUpload(t *testing.T) {
+	app := fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		if isUpload(ctx) {
+			return ctx.SendStatus(http.StatusNoContent)
+		}
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		method, path string
+		upload       bool
+	}{
+		{http.MethodPost, "/md/abc/attachments", true},
+		{http.MethodPost, "/md/abc/attachments?source=editor", true},
+		{http.MethodPost, "/t/acme/md/abc/attachments", true},
+		{http.MethodPost, "/t/acme/md/abc/fork", false},
+		{http.MethodGet, "/md/abc/attachments", false},
+		{http.MethodPost, "/md/abc/fork", false},
+		{http.MethodPost, "/md/import", false},
+	} {
+		resp, err := app.Test(httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, tc.upload, resp.StatusCode == http.StatusNoContent, tc.method+" "+tc.path)
+	}
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/soulxburn/mdsnips/migrations"
+)
+
+func init() {
+	register(&Command{
+		Name:    "migrate",
+		Summary: "Apply pending schema migrations",
+		Run:     runMigrate,
+	})
+}
+
+// runMigrate
+// Applies pending schema migrations and exits.
+// Usage: mdsnips migrate [-dry-run] [-timeout 10m]
+func runMigrate(env *Env, args []string) error {
+	flags := newFlagSet(env, "migrate")
+	dryRun := flags.Bool("dry-run", false, "List pending migrations without applying them")
+	timeout := flags.Duration("timeout", 10*time.Minute, "Maximum time to wait for the lock and apply migrations")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	mClient, err := env.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer mClient.Disconnect(context.Background())
+
+	// Tenant databases follow the configured database, their
+	// migrations are listed with the database name.
+	migrators := migrations.NewMigrators(mClient, env.Config)
+	prefix := func(migrator *migrations.Migrator) string {
+		if migrator == migrators[0] {
+			return ""
+		}
+		return migrator.Database() + " "
+	}
+
+	if *dryRun {
+		count := 0
+		for _, migrator := range migrators {
+			pending, err := migrator.Pending(ctx)
+			if err != nil {
+				return err
+			}
+			for _, migration := range pending {
+				fmt.Fprintf(env.Stdout, "%spending %d: %s\n", prefix(migrator), migration.Version, migration.Description)
+			}
+			count += len(pending)
+		}
+		fmt.Fprintf(env.Stdout, "%d pending migration(s)\n", count)
+		return nil
+	}
+
+	count := 0
+	for _, migrator := range migrators {
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Fprintf(env.Stdout, "%sapplied %d: %s\n", prefix(migrator), migration.Version, migration.Description)
+		}
+		count += len(applied)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(env.Stdout, "%d migration(s) applied\n", count)
+	return nil
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	register(&Command{
+		Name:    "reindex",
+		Summary: "Rebuild the markdown indexes with custom text weights",
+		Run:     runReindex,
+	})
+}
+
+// textIndexFields Fields covered by the markdown text index.
+var textIndexFields = map[string]bool{"title": true, "body": true, "files.content": true}
+
+// runReindex
+// Drops and recreates the markdown indexes.
+// Usage: mdsnips reindex [-weights title=10,body=1] [-language english] [-tenant id]
+func runReindex(env *Env, args []string) error {
+	flags := newFlagSet(env, "reindex")
+	weightsFlag := flags.String("weights", "", "Text index weights, e.g. title=10,body=1")
+	language := flags.String("language", "", "Text index default language")
+	tenant := flags.String("tenant", "", "Tenant to reindex, required when tenancy is enabled")
+	timeout := flags.Duration("timeout", 30*time.Minute, "Maximum time to rebuild the indexes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	weights, err := parseWeights(*weightsFlag)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	mClient, err := env.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer mClient.Disconnect(context.Background())
+
+	mdService, err := env.Service(ctx, mClient, *tenant)
+	if err != nil {
+		return err
+	}
+	if err := mdService.RebuildIndexes(ctx, weights, *language); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "indexes rebuilt on %s\n", mdService.Namespace())
+	return nil
+}
+
+// parseWeights
+// Parses a comma separated list of field=weight pairs
+// for the text index fields. An empty value returns nil.
+func parseWeights(value string) (map[string]int32, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	weights := make(map[string]int32)
+	for _, pair := range strings.Split(value, ",") {
+		field, raw := pair, ""
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			field, raw = pair[:i], pair[i+1:]
+		}
+		field = strings.TrimSpace(field)
+		if !textIndexFields[field] {
+			return nil, fmt.Errorf("weights: `%s` is not a text index field", field)
+		}
+		weight, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 32)
+		if err != nil || weight < 1 || weight > 99999 {
+			return nil, fmt.Errorf("weights: `%s` must be a whole number between 1 and 99999", field)
+		}
+		weights[field] = int32(weight)
+	}
+	return weights, nil
+}
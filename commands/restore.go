@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+func init() {
+	register(&Command{
+		Name:    "restore",
+		Summary: "Upsert the snippets and revisions of a backup file",
+		Run:     runRestore,
+	})
+}
+
+// runRestore
+// Upserts every snippet of a backup file by id,
+// restoring the same file twice is safe.
+// Usage: mdsnips restore [-tenant id] <file>
+func runRestore(env *Env, args []string) error {
+	flags := newFlagSet(env, "restore")
+	tenant := flags.String("tenant", "", "Tenant to restore into, required when tenancy is enabled")
+	timeout := flags.Duration("timeout", time.Hour, "Maximum time to restore the backup")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: mdsnips restore [-tenant id] <file>")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	r, err := decompress(file)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	mClient, err := env.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer mClient.Disconnect(context.Background())
+
+	mdService, err := env.Service(ctx, mClient, *tenant)
+	if err != nil {
+		return err
+	}
+	count, err := mdService.RestoreSnippets(ctx, r)
+	fmt.Fprintf(env.Stdout, "%d snippet(s) restored\n", count)
+	return err
+}
+
+// decompress
+// Returns a reader over r, decompressing it when
+// it starts with the gzip magic number.
+func decompress(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buffered)
+	}
+	return buffered, nil
+}
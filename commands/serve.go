@@ -0,0 +1,232 @@
+package commands
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
+	"time"
+
+	swagger "github.com/arsmn/fiber-swagger/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/blob"
+	"github.com/soulxburn/mdsnips/client"
+	"github.com/soulxburn/mdsnips/collab"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/docs"
+	"github.com/soulxburn/mdsnips/logging"
+	"github.com/soulxburn/mdsnips/md"
+	"github.com/soulxburn/mdsnips/metrics"
+	"github.com/soulxburn/mdsnips/migrations"
+	"github.com/soulxburn/mdsnips/tracing"
+	"github.com/soulxburn/mdsnips/webhooks"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	register(&Command{
+		Name:    "serve",
+		Summary: "Start the HTTP server (default)",
+		Run:     runServe,
+	})
+}
+
+// runServe
+// Starts the API server and blocks until SIGINT/SIGTERM,
+// then drains in-flight requests.
+// Usage: mdsnips serve
+func runServe(env *Env, args []string) error {
+	flags := newFlagSet(env, "serve")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	cfg := env.Config
+	docs.SwaggerInfo.Host = cfg.Host
+
+	// Cancelled on SIGINT/SIGTERM to begin graceful shutdown.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Init(ctx, cfg.Tracing)
+	if err != nil {
+		return err
+	}
+
+	mClient, err := getMongoConnection(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	fiberApp := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+		IdleTimeout:  cfg.Timeouts.Idle,
+		BodyLimit:    bodyLimit(cfg.Attachments),
+	})
+	api.ConfigureRequestContext(fiberApp, cfg.Timeouts.Request)
+	api.ConfigureRequestID(fiberApp)
+	api.ConfigureTracing(fiberApp)
+	api.ConfigureMiddleware(fiberApp)
+	api.ConfigureBodyLimit(fiberApp, fiber.DefaultBodyLimit, isUpload)
+
+	api.ConfigureHealth(fiberApp,
+		api.ReadinessCheck{Name: "mongo", Check: func(ctx context.Context) error {
+			return mClient.Ping(ctx, nil)
+		}},
+		api.ReadinessCheck{Name: "indexes", Check: func(ctx context.Context) error {
+			return md.CheckIndexes(ctx, mClient, cfg)
+		}},
+	)
+	fiberApp.Get("/swagger/*", swagger.Handler)
+	fiberApp.All("/", func(ctx *fiber.Ctx) error {
+		return ctx.Redirect("/swagger/index.html", http.StatusMovedPermanently)
+	})
+
+	store, err := blob.New(ctx, cfg.Blob)
+	if err != nil {
+		return err
+	}
+	mdService := md.InitMDService(mClient, cfg).WithBlobStore(store)
+	go mdService.WatchEvents(ctx)
+	go md.NewPurger(mdService, cfg.Trash).Run(ctx)
+	mdHandlers := md.InitMDHandlers(mdService)
+
+	// Admin routes span every tenant and take their own credentials,
+	// on the admin listener when there is one, else ahead of the API auth.
+	adminApp := fiber.New(fiber.Config{DisableStartupMessage: true, ErrorHandler: api.ErrorHandler})
+	adminHost := fiberApp
+	if cfg.MetricsAddr != "" {
+		adminHost = adminApp
+	}
+	if admin := api.AdminRouter(adminHost, cfg.AdminUser, cfg.AdminPass); admin != nil {
+		mdHandlers.ConfigureAdminRoutes(admin)
+	}
+
+	api.ConfigureTenancy(fiberApp, cfg.Tenant)
+	api.ConfigureBasicAuth(fiberApp)
+	api.ConfigureActor(fiberApp)
+
+	mdHandlers.ConfigureRoutes(fiberApp)
+	collabHub := collab.NewHub(cfg.Collab)
+	collab.InitHandlers(mdService, collabHub).ConfigureRoutes(fiberApp)
+	webhooks.InitHandlers(webhooks.InitService(mClient, cfg)).ConfigureRoutes(fiberApp)
+
+	metrics.Registry.MustRegister(md.NewStatsCollector(mdService))
+	adminApp.Get("/metrics", metrics.Handler())
+	if cfg.MetricsAddr != "" {
+		go serve(adminApp, cfg.MetricsAddr)
+	} else {
+		fiberApp.Get("/metrics", metrics.Handler())
+	}
+
+	go serve(fiberApp, ":"+cfg.Port)
+
+	<-ctx.Done()
+	logging.Logger.Info("Shutting down, draining in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Timeouts.Shutdown)
+	defer cancel()
+
+	// Live streams and editing sessions stay open indefinitely,
+	// end them so the server can drain. Sessions save a final snapshot.
+	mdService.Live().Close()
+	collabHub.Close(shutdownCtx)
+	shutdown(shutdownCtx, fiberApp)
+	if cfg.MetricsAddr != "" {
+		shutdown(shutdownCtx, adminApp)
+	}
+	if err := mClient.Disconnect(shutdownCtx); err != nil {
+		logging.Logger.WithError(err).Error("Failed to disconnect mongo client")
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logging.Logger.WithError(err).Error("Failed to flush traces")
+	}
+	logging.Logger.Info("Shutdown complete")
+	return nil
+}
+
+// bodyLimit
+// Returns the largest request body accepted, fitting
+// an attachment upload and its multipart framing.
+// Other routes are held to fiber.DefaultBodyLimit.
+func bodyLimit(attachments config.Attachments) int {
+	limit := int(attachments.MaxSize) + 64<<10
+	if limit < fiber.DefaultBodyLimit {
+		return fiber.DefaultBodyLimit
+	}
+	return limit
+}
+
+// uploadPath Attachment upload paths, with the optional `/t/{tenant}`
+// prefix, since the body limit runs before the tenant path is rewritten.
+var uploadPath = regexp.MustCompile(`^(/t/[^/]+)?/md/[^/]+/attachments/?$`)
+
+// isUpload
+// Reports whether ctx is an attachment upload, allowed up to bodyLimit.
+func isUpload(ctx *fiber.Ctx) bool {
+	return ctx.Method() == fiber.MethodPost && uploadPath.MatchString(ctx.Path())
+}
+
+// Initialize MongoClient
+// The connection is established in the background, retrying
+// with backoff. Once it succeeds migrations and audit retention are
+// applied and the webhook dispatcher started. Readiness reports unavailable until then.
+func getMongoConnection(ctx context.Context, cfg *config.Config) (*mongo.Client, error) {
+	mClient, err := client.NewMongoClient(cfg.Mongo)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		onRetry := func(attempt int, err error) {
+			logging.Logger.WithError(err).WithField("attempt", attempt).Warn("Mongo unreachable, retrying")
+		}
+		if err := client.WaitForMongo(ctx, mClient, 500*time.Millisecond, cfg.Mongo.RetryMaxInterval, onRetry); err != nil {
+			return
+		}
+		logging.Logger.Info("Mongo connected")
+		if cfg.MigrateOnStart {
+			for _, migrator := range migrations.NewMigrators(mClient, cfg) {
+				if _, err := migrator.Up(ctx); err != nil {
+					logging.Logger.WithError(err).WithField("database", migrator.Database()).Error("Failed to apply migrations")
+				}
+			}
+		}
+		if err := md.ApplyAuditRetention(ctx, mClient.Database(cfg.Mongo.Database), cfg.Audit.Retention); err != nil {
+			logging.Logger.WithError(err).Error("Failed to apply audit retention")
+		}
+		if cfg.Webhooks.Enabled {
+			webhooks.NewDispatcher(mClient, cfg).Run(ctx)
+		}
+	}()
+
+	return mClient, nil
+}
+
+// serve
+// Listens on addr until the app is shut down.
+func serve(app *fiber.App, addr string) {
+	if err := app.Listen(addr); err != nil {
+		logging.Logger.Fatal(err)
+	}
+}
+
+// shutdown
+// Stops app accepting connections and waits for
+// in-flight requests to drain, or ctx to expire.
+func shutdown(ctx context.Context, app *fiber.App) {
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Shutdown()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			logging.Logger.WithError(err).Error("Failed to shut down server")
+		}
+	case <-ctx.Done():
+		logging.Logger.Warn("Shutdown timed out with requests in flight")
+	}
+}
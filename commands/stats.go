@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/md"
+	"github.com/soulxburn/mdsnips/migrations"
+)
+
+func init() {
+	register(&Command{
+		Name:    "stats",
+		Summary: "Print collection statistics",
+		Run:     runStats,
+	})
+}
+
+// statsReport Output of the stats command.
+type statsReport struct {
+	*md.CollectionStats
+	PendingMigrations int `json:"pendingMigrations"`
+}
+
+// runStats
+// Prints storage statistics of the markdown collection.
+// Usage: mdsnips stats [-json] [-tenant id]
+func runStats(env *Env, args []string) error {
+	flags := newFlagSet(env, "stats")
+	asJSON := flags.Bool("json", false, "Print statistics as JSON")
+	tenant := flags.String("tenant", "", "Tenant to report on, required when tenancy is enabled")
+	timeout := flags.Duration("timeout", time.Minute, "Maximum time to collect statistics")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	mClient, err := env.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer mClient.Disconnect(context.Background())
+
+	mdService, err := env.Service(ctx, mClient, *tenant)
+	if err != nil {
+		return err
+	}
+	stats, err := mdService.CollectionStats(ctx)
+	if err != nil {
+		return err
+	}
+	migrator := migrations.NewMigrator(mClient, env.Config)
+	if env.Config.Tenant.Mode == config.TenancyDatabase {
+		migrator = migrations.NewTenantMigrator(mClient, env.Config, *tenant)
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	report := statsReport{CollectionStats: stats, PendingMigrations: len(pending)}
+
+	if *asJSON {
+		encoder := json.NewEncoder(env.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	tw := tabwriter.NewWriter(env.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "namespace\t%s\n", mdService.Namespace())
+	fmt.Fprintf(tw, "snippets\t%d\n", report.Snippets)
+	fmt.Fprintf(tw, "snippet bytes\t%d\n", report.SnippetBytes)
+	fmt.Fprintf(tw, "documents\t%d\n", report.Count)
+	fmt.Fprintf(tw, "data size\t%d\n", report.Size)
+	fmt.Fprintf(tw, "avg document size\t%.0f\n", report.AvgObjSize)
+	fmt.Fprintf(tw, "storage size\t%d\n", report.StorageSize)
+	fmt.Fprintf(tw, "indexes\t%d\n", report.IndexCount)
+	fmt.Fprintf(tw, "index size\t%d\n", report.TotalIndexSize)
+	fmt.Fprintf(tw, "pending migrations\t%d\n", report.PendingMigrations)
+	return tw.Flush()
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+)
+
+func init() {
+	register(&Command{
+		Name:    "trash",
+		Summary: "List deleted snippets awaiting purge",
+		Run:     runTrash,
+	})
+}
+
+// trashItem Output of the trash command, one per deleted snippet.
+type trashItem struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	DeletedAt time.Time  `json:"deletedAt"`
+	PurgeAt   *time.Time `json:"purgeAt,omitempty"`
+}
+
+// runTrash
+// Lists the snippets in the trash, most recently deleted first,
+// with the time each will be purged.
+// Usage: mdsnips trash [-json] [-tenant id]
+func runTrash(env *Env, args []string) error {
+	flags := newFlagSet(env, "trash")
+	asJSON := flags.Bool("json", false, "Print the trash as JSON")
+	tenant := flags.String("tenant", "", "Tenant to list, required when tenancy is enabled")
+	timeout := flags.Duration("timeout", time.Minute, "Maximum time to list the trash")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	mClient, err := env.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer mClient.Disconnect(context.Background())
+
+	mdService, err := env.Service(ctx, mClient, *tenant)
+	if err != nil {
+		return err
+	}
+	snippets, err := mdService.ListTrash(ctx)
+	if err != nil {
+		return err
+	}
+
+	items := make([]trashItem, len(snippets))
+	for i, snippet := range snippets {
+		items[i] = trashItem{ID: snippet.ID, Title: snippet.Title, DeletedAt: *snippet.DeletedAt}
+		if retention := env.Config.Trash.Retention; retention > 0 {
+			purgeAt := snippet.DeletedAt.Add(retention)
+			items[i].PurgeAt = &purgeAt
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(env.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(items)
+	}
+
+	tw := tabwriter.NewWriter(env.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "id\ttitle\tdeleted\tpurge")
+	for _, item := range items {
+		purge := "never"
+		if item.PurgeAt != nil {
+			purge = item.PurgeAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", item.ID, item.Title, item.DeletedAt.Format(time.RFC3339), purge)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,507 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TenancyDatabase routes each tenant to its own database.
+	TenancyDatabase = "database"
+	// TenancyFilter stores every tenant in the shared collection
+	// and scopes queries with a `tenantId` filter.
+	TenancyFilter = "filter"
+)
+
+// Config
+// Application configuration sourced from the environment.
+type Config struct {
+	Host string
+	Port string
+	User string
+	Pass string
+	// Credentials of the `/admin` routes, separate from the API user.
+	// The admin routes are disabled when AdminUser is empty.
+	AdminUser string
+	AdminPass string
+	// Minimum log level, e.g. `debug`, `info`, `warn`, `error`.
+	LogLevel string
+	// Apply pending schema migrations when the server starts.
+	MigrateOnStart bool
+	// Admin listen address serving `/metrics` and `/admin`, e.g. `:9090`.
+	// When empty both are served on the API port.
+	MetricsAddr string
+	Mongo       Mongo
+	Tenant      Tenant
+	Timeouts    Timeouts
+	Tracing     Tracing
+	Webhooks    Webhooks
+	Live        Live
+	Collab      Collab
+	Trash       Trash
+	Audit       Audit
+	Attachments Attachments
+	Blob        Blob
+}
+
+// Blob
+// External storage of large bodies and attachments.
+type Blob struct {
+	// Store backend, `local` or `s3`. When empty bodies stay
+	// in Mongo and attachments are stored in GridFS.
+	Store string
+	// Bodies larger than this many bytes are kept in the store,
+	// where full-text search no longer matches them.
+	OffloadSize int64
+	// Root directory of the `local` store.
+	Dir string
+	// S3-compatible service host, e.g. `s3.amazonaws.com` or `localhost:9000`.
+	S3Endpoint string
+	// Bucket holding the blobs, created when missing.
+	S3Bucket    string
+	S3Region    string
+	S3AccessKey string
+	S3SecretKey string
+	// Connect to the service without TLS.
+	S3Insecure bool
+}
+
+// Attachments
+// Limits of files uploaded to snippets.
+type Attachments struct {
+	// Largest single upload in bytes.
+	MaxSize int64
+	// Total bytes of attachments a single snippet may hold.
+	Quota int64
+	// Reject uploads that are not PNG, JPEG, GIF, WebP, BMP or ICO images.
+	ImagesOnly bool
+	// Time clients may cache a served attachment.
+	CacheMaxAge time.Duration
+}
+
+// Audit
+// Retention of the audit log.
+type Audit struct {
+	// Time audit entries are kept, forever when zero.
+	Retention time.Duration
+}
+
+// Trash
+// Retention of deleted snippets.
+type Trash struct {
+	// Time deleted snippets can be restored before they are purged.
+	// Purging is disabled when zero.
+	Retention time.Duration
+	// Interval between purges of expired snippets.
+	PurgeInterval time.Duration
+}
+
+// Collab
+// Collaborative editing session settings.
+type Collab struct {
+	// Interval between snapshots of edited sessions.
+	SnapshotInterval time.Duration
+	// Concurrent editors of a single snippet.
+	MaxEditors int
+}
+
+// Live
+// Limits of live snippet event streams.
+type Live struct {
+	// Concurrent subscribers across every snippet.
+	MaxSubscribers int
+	// Concurrent subscribers of a single snippet.
+	MaxPerSnippet int
+	// Interval between keep-alive messages on idle streams.
+	Heartbeat time.Duration
+}
+
+// Webhooks
+// Outbox dispatch and webhook delivery settings.
+type Webhooks struct {
+	// Run the outbox dispatcher and deliver webhooks in this process.
+	Enabled bool
+	// Interval between polls of the outbox and pending deliveries.
+	PollInterval time.Duration
+	// Timeout of a single delivery attempt.
+	Timeout time.Duration
+	// Delivery attempts before giving up.
+	MaxAttempts int
+	// Wait before the first retry, doubled for each attempt.
+	RetryInitial time.Duration
+	// Upper bound on the wait between retries.
+	RetryMax time.Duration
+	// CIDR ranges deliveries may reach although they are loopback,
+	// private or link-local, all of which are refused otherwise.
+	AllowedNetworks []string
+}
+
+// Tracing
+// OpenTelemetry trace export settings.
+type Tracing struct {
+	// Span exporter, one of `otlp`, `stdout` or `file`.
+	// Tracing is disabled when empty.
+	Exporter string
+	// Output path for the `file` exporter.
+	File string
+	// Fraction of new traces sampled, between 0 and 1.
+	SampleRatio float64
+	// Reported service.name resource attribute.
+	ServiceName string
+}
+
+// Timeouts
+// Per-operation time budgets applied to MDService calls.
+type Timeouts struct {
+	// Maximum duration of the whole request, including every operation.
+	Request time.Duration
+	// Single document lookups.
+	Read time.Duration
+	// Inserts, updates and deletes.
+	Write time.Duration
+	// Listing and text search queries.
+	Search time.Duration
+	// Streaming a bulk export, which outlives the request.
+	Export time.Duration
+	// Keep-alive connections idle longer than this are closed.
+	Idle time.Duration
+	// Maximum time to drain in-flight requests on shutdown.
+	Shutdown time.Duration
+}
+
+// Mongo
+// MongoDB connection, client and naming settings.
+// Zero values leave the connection string or driver default in place.
+type Mongo struct {
+	// MongoDB Connection String.
+	Conn string
+	// Database holding the markdown collection.
+	Database string
+	// Markdown snippet collection name.
+	Collection string
+	// Upper bound of the backoff between startup connection attempts.
+	RetryMaxInterval time.Duration
+
+	// Name reported to the server in the connection handshake.
+	AppName string
+	// Connection pool bounds.
+	MaxPoolSize     uint64
+	MinPoolSize     uint64
+	MaxConnIdleTime time.Duration
+	// Driver timeouts.
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+	SocketTimeout          time.Duration
+	// Retryable reads and writes, nil keeps the driver default.
+	RetryReads  *bool
+	RetryWrites *bool
+	// Read preference mode, e.g. `primary`, `secondaryPreferred`, `nearest`.
+	ReadPreference string
+	// Read concern level, e.g. `local`, `majority`.
+	ReadConcern string
+	// Write concern `w` value, `majority` or a number of nodes.
+	WriteConcern string
+	// Require write acknowledgement from the journal.
+	WriteConcernJournal *bool
+	// Write concern acknowledgement timeout.
+	WriteConcernTimeout time.Duration
+	// PEM encoded CA bundle used to verify the server.
+	TLSCAFile string
+	// PEM encoded client certificate and key for x509 authentication.
+	TLSCertFile string
+	TLSKeyFile  string
+	// Commands slower than this are logged, disabled when zero.
+	SlowQueryThreshold time.Duration
+}
+
+// Tenant
+// Multi-tenant settings. Tenancy is disabled when Mode is empty.
+type Tenant struct {
+	// Isolation mode, one of TenancyDatabase or TenancyFilter.
+	Mode string
+	// Tenant resolution strategy, one of `host`, `path` or `token`.
+	Resolver string
+	// Tenant database name prefix, used with TenancyDatabase.
+	DatabasePrefix string
+	// Maps API tokens to tenant ids, used with the `token` resolver.
+	Tokens map[string]string
+	// Known tenant ids, others are rejected. Required with
+	// TenancyDatabase, where migrations create each tenant database.
+	Names []string
+}
+
+// tenantName Tenant ids, safe as database name suffixes.
+// Lower case only, database names may not differ by case alone.
+var tenantName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,47}$`)
+
+// Enabled
+// Reports whether multi-tenant mode is configured.
+func (t Tenant) Enabled() bool {
+	return t.Mode != ""
+}
+
+// Allowed
+// Reports whether tenant is a valid tenant id,
+// and one of Names when they are configured.
+func (t Tenant) Allowed(tenant string) bool {
+	if !tenantName.MatchString(tenant) {
+		return false
+	}
+	if len(t.Names) == 0 {
+		return true
+	}
+	for _, name := range t.Names {
+		if name == tenant {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate
+// Returns an error describing the first invalid setting.
+func (c *Config) Validate() error {
+	switch c.Tenant.Mode {
+	case "", TenancyDatabase, TenancyFilter:
+	default:
+		return fmt.Errorf("MDSNIPS_TENANT_MODE: `%s` is not a valid value", c.Tenant.Mode)
+	}
+	switch c.Blob.Store {
+	case "", "local", "s3":
+	default:
+		return fmt.Errorf("MDSNIPS_BLOB_STORE: `%s` is not a valid value", c.Blob.Store)
+	}
+	for _, cidr := range c.Webhooks.AllowedNetworks {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("MDSNIPS_WEBHOOK_ALLOWED_NETWORKS: `%s` is not a valid CIDR", cidr)
+		}
+	}
+	if c.Tenant.Enabled() {
+		switch c.Tenant.Resolver {
+		case "host", "path", "token":
+		default:
+			return fmt.Errorf("MDSNIPS_TENANT_RESOLVER: `%s` is not a valid value", c.Tenant.Resolver)
+		}
+	}
+	if c.Tenant.Mode == TenancyDatabase && len(c.Tenant.Names) == 0 {
+		return errors.New("MDSNIPS_TENANTS: required when MDSNIPS_TENANT_MODE is `database`")
+	}
+	for _, name := range c.Tenant.Names {
+		if !tenantName.MatchString(name) {
+			return fmt.Errorf("MDSNIPS_TENANTS: `%s` is not a valid tenant, use up to 48 lower case letters, digits, `-` and `_`", name)
+		}
+	}
+	for _, tenant := range c.Tenant.Tokens {
+		if !c.Tenant.Allowed(tenant) {
+			return fmt.Errorf("MDSNIPS_TENANT_TOKENS: `%s` is not a valid or known tenant", tenant)
+		}
+	}
+	return nil
+}
+
+// Load
+// Builds a Config from environment variables, applying defaults.
+func Load() *Config {
+	port := getEnv("PORT", "3000")
+	host := os.Getenv("HOST")
+	if host == "" || strings.Contains(host, "localhost") {
+		host = "localhost:" + port
+	}
+
+	return &Config{
+		Host:           host,
+		Port:           port,
+		User:           os.Getenv("MDSNIPS_USER"),
+		Pass:           os.Getenv("MDSNIPS_PASS"),
+		AdminUser:      os.Getenv("MDSNIPS_ADMIN_USER"),
+		AdminPass:      os.Getenv("MDSNIPS_ADMIN_PASS"),
+		LogLevel:       getEnv("MDSNIPS_LOG_LEVEL", "info"),
+		MetricsAddr:    os.Getenv("MDSNIPS_METRICS_ADDR"),
+		MigrateOnStart: os.Getenv("MDSNIPS_MIGRATE_ON_START") != "false",
+		Mongo: Mongo{
+			Conn:             os.Getenv("MDSNIPS_MONGO_CONN"),
+			Database:         getEnv("MDSNIPS_MONGO_DB", "mdsnips"),
+			Collection:       getEnv("MDSNIPS_MONGO_COLLECTION", "markdown"),
+			RetryMaxInterval: getEnvDuration("MDSNIPS_MONGO_RETRY_MAX_INTERVAL", 30*time.Second),
+
+			AppName:                getEnv("MDSNIPS_MONGO_APP_NAME", "mdsnips"),
+			MaxPoolSize:            getEnvUint("MDSNIPS_MONGO_MAX_POOL_SIZE"),
+			MinPoolSize:            getEnvUint("MDSNIPS_MONGO_MIN_POOL_SIZE"),
+			MaxConnIdleTime:        getEnvDuration("MDSNIPS_MONGO_MAX_CONN_IDLE_TIME", 0),
+			ConnectTimeout:         getEnvDuration("MDSNIPS_MONGO_CONNECT_TIMEOUT", 0),
+			ServerSelectionTimeout: getEnvDuration("MDSNIPS_MONGO_SERVER_SELECTION_TIMEOUT", 0),
+			SocketTimeout:          getEnvDuration("MDSNIPS_MONGO_SOCKET_TIMEOUT", 0),
+			RetryReads:             getEnvBool("MDSNIPS_MONGO_RETRY_READS"),
+			RetryWrites:            getEnvBool("MDSNIPS_MONGO_RETRY_WRITES"),
+			ReadPreference:         os.Getenv("MDSNIPS_MONGO_READ_PREFERENCE"),
+			ReadConcern:            os.Getenv("MDSNIPS_MONGO_READ_CONCERN"),
+			WriteConcern:           os.Getenv("MDSNIPS_MONGO_WRITE_CONCERN"),
+			WriteConcernJournal:    getEnvBool("MDSNIPS_MONGO_WRITE_CONCERN_JOURNAL"),
+			WriteConcernTimeout:    getEnvDuration("MDSNIPS_MONGO_WRITE_CONCERN_TIMEOUT", 0),
+			TLSCAFile:              os.Getenv("MDSNIPS_MONGO_TLS_CA_FILE"),
+			TLSCertFile:            os.Getenv("MDSNIPS_MONGO_TLS_CERT_FILE"),
+			TLSKeyFile:             os.Getenv("MDSNIPS_MONGO_TLS_KEY_FILE"),
+			SlowQueryThreshold:     getEnvDuration("MDSNIPS_MONGO_SLOW_QUERY", 100*time.Millisecond),
+		},
+		Tenant: Tenant{
+			Mode:           os.Getenv("MDSNIPS_TENANT_MODE"),
+			Resolver:       getEnv("MDSNIPS_TENANT_RESOLVER", "host"),
+			DatabasePrefix: getEnv("MDSNIPS_TENANT_DB_PREFIX", "mdsnips_"),
+			Tokens:         getEnvMap("MDSNIPS_TENANT_TOKENS"),
+			Names:          getEnvList("MDSNIPS_TENANTS"),
+		},
+		Tracing: Tracing{
+			Exporter:    os.Getenv("MDSNIPS_TRACE_EXPORTER"),
+			File:        getEnv("MDSNIPS_TRACE_FILE", "traces.jsonl"),
+			SampleRatio: getEnvFloat("MDSNIPS_TRACE_SAMPLE_RATIO", 1),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "mdsnips"),
+		},
+		Webhooks: Webhooks{
+			Enabled:         os.Getenv("MDSNIPS_WEBHOOKS_ENABLED") != "false",
+			PollInterval:    getEnvDuration("MDSNIPS_WEBHOOK_POLL_INTERVAL", time.Second),
+			Timeout:         getEnvDuration("MDSNIPS_WEBHOOK_TIMEOUT", 10*time.Second),
+			MaxAttempts:     int(getEnvUint("MDSNIPS_WEBHOOK_MAX_ATTEMPTS")),
+			RetryInitial:    getEnvDuration("MDSNIPS_WEBHOOK_RETRY_INITIAL", 30*time.Second),
+			RetryMax:        getEnvDuration("MDSNIPS_WEBHOOK_RETRY_MAX", time.Hour),
+			AllowedNetworks: getEnvList("MDSNIPS_WEBHOOK_ALLOWED_NETWORKS"),
+		},
+		Live: Live{
+			MaxSubscribers: int(getEnvUint("MDSNIPS_LIVE_MAX_SUBSCRIBERS")),
+			MaxPerSnippet:  int(getEnvUint("MDSNIPS_LIVE_MAX_PER_SNIPPET")),
+			Heartbeat:      getEnvDuration("MDSNIPS_LIVE_HEARTBEAT", 15*time.Second),
+		},
+		Collab: Collab{
+			SnapshotInterval: getEnvDuration("MDSNIPS_COLLAB_SNAPSHOT_INTERVAL", 10*time.Second),
+			MaxEditors:       int(getEnvUint("MDSNIPS_COLLAB_MAX_EDITORS")),
+		},
+		Trash: Trash{
+			Retention:     getEnvDuration("MDSNIPS_TRASH_RETENTION", 30*24*time.Hour),
+			PurgeInterval: getEnvDuration("MDSNIPS_TRASH_PURGE_INTERVAL", time.Hour),
+		},
+		Audit: Audit{
+			Retention: getEnvDuration("MDSNIPS_AUDIT_RETENTION", 365*24*time.Hour),
+		},
+		Attachments: Attachments{
+			MaxSize:     getEnvInt64("MDSNIPS_ATTACHMENT_MAX_SIZE", 5<<20),
+			Quota:       getEnvInt64("MDSNIPS_ATTACHMENT_QUOTA", 25<<20),
+			ImagesOnly:  os.Getenv("MDSNIPS_ATTACHMENT_IMAGES_ONLY") == "true",
+			CacheMaxAge: getEnvDuration("MDSNIPS_ATTACHMENT_CACHE_MAX_AGE", 24*time.Hour),
+		},
+		Blob: Blob{
+			Store:       os.Getenv("MDSNIPS_BLOB_STORE"),
+			OffloadSize: getEnvInt64("MDSNIPS_BLOB_OFFLOAD_SIZE", 16<<10),
+			Dir:         getEnv("MDSNIPS_BLOB_DIR", "blobs"),
+			S3Endpoint:  os.Getenv("MDSNIPS_S3_ENDPOINT"),
+			S3Bucket:    getEnv("MDSNIPS_S3_BUCKET", "mdsnips"),
+			S3Region:    os.Getenv("MDSNIPS_S3_REGION"),
+			S3AccessKey: os.Getenv("MDSNIPS_S3_ACCESS_KEY"),
+			S3SecretKey: os.Getenv("MDSNIPS_S3_SECRET_KEY"),
+			S3Insecure:  os.Getenv("MDSNIPS_S3_INSECURE") == "true",
+		},
+		Timeouts: Timeouts{
+			Request:  getEnvDuration("MDSNIPS_TIMEOUT_REQUEST", 15*time.Second),
+			Read:     getEnvDuration("MDSNIPS_TIMEOUT_READ", 5*time.Second),
+			Write:    getEnvDuration("MDSNIPS_TIMEOUT_WRITE", 5*time.Second),
+			Search:   getEnvDuration("MDSNIPS_TIMEOUT_SEARCH", 5*time.Second),
+			Export:   getEnvDuration("MDSNIPS_TIMEOUT_EXPORT", 5*time.Minute),
+			Idle:     getEnvDuration("MDSNIPS_TIMEOUT_IDLE", 30*time.Second),
+			Shutdown: getEnvDuration("MDSNIPS_TIMEOUT_SHUTDOWN", 20*time.Second),
+		},
+	}
+}
+
+// getEnv
+// Returns the environment variable for key, or def when unset.
+func getEnv(key string, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// getEnvDuration
+// Parses a duration environment variable, e.g. `5s`,
+// returning def when unset or invalid.
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+// getEnvUint
+// Parses an unsigned integer environment variable,
+// returning 0 when unset or invalid.
+func getEnvUint(key string) uint64 {
+	u, err := strconv.ParseUint(os.Getenv(key), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return u
+}
+
+// getEnvInt64
+// Parses an integer environment variable,
+// returning def when unset or invalid.
+func getEnvInt64(key string, def int64) int64 {
+	i, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil {
+		return def
+	}
+	return i
+}
+
+// getEnvBool
+// Parses a boolean environment variable,
+// returning nil when unset or invalid.
+func getEnvBool(key string) *bool {
+	b, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return nil
+	}
+	return &b
+}
+
+// getEnvFloat
+// Parses a float environment variable,
+// returning def when unset or invalid.
+func getEnvFloat(key string, def float64) float64 {
+	f, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return def
+	}
+	return f
+}
+
+// getEnvList
+// Parses a comma separated environment variable,
+// skipping empty values.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvMap
+// Parses a `key:value,key:value` environment variable.
+func getEnvMap(key string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(kv) == 2 && kv[0] != "" {
+			values[kv[0]] = kv[1]
+		}
+	}
+	return values
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_ValidateTenants
+// Database tenancy requires valid, configured tenants,
+// which also bound the tenants tokens may resolve to.
+func Test_ValidateTenants(t *testing.T) {
+	cfg := &Config{Tenant: Tenant{Mode: TenancyDatabase, Resolver: "token"}}
+	assert.NotNil(t, cfg.Validate())
+
+	cfg.Tenant.Names = []string{"acme", "Globex"}
+	assert.NotNil(t, cfg.Validate())
+
+	cfg.Tenant.Names = []string{"acme", "globex"}
+	assert.Nil(t, cfg.Validate())
+
+	cfg.Tenant.Tokens = map[string]string{"secret": "initech"}
+	assert.NotNil(t, cfg.Validate())
+}
+
+// Test_TenantAllowed
+// Tenants must match the name pattern, and be listed when names are configured.
+func Test_TenantAllowed(t *testing.T) {
+	open := Tenant{Mode: TenancyFilter}
+	assert.True(t, open.Allowed("acme"))
+	assert.True(t, open.Allowed("acme-2_eu"))
+	for _, tenant := range []string{"", "Acme", "-acme", "acme.x", "a/b", strings.Repeat("a", 49)} {
+		assert.False(t, open.Allowed(tenant), tenant)
+	}
+
+	listed := Tenant{Mode: TenancyDatabase, Names: []string{"acme"}}
+	assert.True(t, listed.Allowed("acme"))
+	assert.False(t, listed.Allowed("globex"))
+}
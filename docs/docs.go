@@ -24,19 +24,245 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/md": {
+        "/admin/audit": {
             "get": {
-                "consumes": [
+                "description": "Lists changes to snippets of every tenant, most recent first, matching the given filters.",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Query the audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Basic auth user",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client IP address",
+                        "name": "ip",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Request ID",
+                        "name": "requestId",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "create",
+                            "import",
+                            "fork",
+                            "update",
+                            "patch",
+                            "merge",
+                            "collab",
+                            "delete",
+                            "restore"
+                        ],
+                        "type": "string",
+                        "description": "Operation",
+                        "name": "operation",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "snippetId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant",
+                        "name": "tenant",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Entries at or after, RFC 3339",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Entries before, RFC 3339",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Number of Entries",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Skip Number of Entries",
+                        "name": "skip",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/md.AuditEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/audit/export": {
+            "get": {
+                "description": "Streams every audit entry matching the filters of ` + "`" + `GET /admin/audit` + "`" + ` as JSON lines.",
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Export the audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Basic auth user",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client IP address",
+                        "name": "ip",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Request ID",
+                        "name": "requestId",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "create",
+                            "import",
+                            "fork",
+                            "update",
+                            "patch",
+                            "merge",
+                            "collab",
+                            "delete",
+                            "restore"
+                        ],
+                        "type": "string",
+                        "description": "Operation",
+                        "name": "operation",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "snippetId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant",
+                        "name": "tenant",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Entries at or after, RFC 3339",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Entries before, RFC 3339",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of Entries, 0 for all",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Skip Number of Entries",
+                        "name": "skip",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/md.AuditEntry"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/trash": {
+            "get": {
+                "description": "Lists the snippets of a tenant awaiting purge, most recently deleted first.\nA configured tenant is required when multi-tenant mode is enabled.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "md"
+                    "admin"
+                ],
+                "summary": "List deleted markdown snippets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant",
+                        "name": "tenant",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Retrieve All Markdown Snippets",
-                "deprecated": true,
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -60,6 +286,38 @@ var doc = `{
                         }
                     }
                 }
+            }
+        },
+        "/md": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Retrieve All Markdown Snippets",
+                "deprecated": true,
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/md.MDListItem"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
             },
             "post": {
                 "consumes": [
@@ -96,6 +354,12 @@ var doc = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -105,6 +369,7 @@ var doc = `{
                 }
             },
             "patch": {
+                "description": "With a ` + "`" + `baseRevision` + "`" + `, changes saved since that revision are merged line by line\nwith the update. Overlapping changes are returned with conflict markers to resolve\nand send again based on the returned ` + "`" + `revision` + "`" + `.",
                 "consumes": [
                     "application/json"
                 ],
@@ -139,12 +404,24 @@ var doc = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/md.MergeConflictResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -154,18 +431,17 @@ var doc = `{
                 }
             }
         },
-        "/md/search": {
+        "/md/export": {
             "get": {
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Streams every snippet matching the search filters, without update keys.\n` + "`" + `zip` + "`" + ` archives hold one ` + "`" + `.md` + "`" + ` file per snippet, titled in front matter, and can be imported.",
                 "produces": [
-                    "application/json"
+                    "application/x-ndjson",
+                    "application/zip"
                 ],
                 "tags": [
                     "md"
                 ],
-                "summary": "Search, Sort, and paginate through MarkdownSnippets.",
+                "summary": "Export markdown snippets in bulk",
                 "parameters": [
                     {
                         "type": "string",
@@ -175,8 +451,8 @@ var doc = `{
                     },
                     {
                         "type": "integer",
-                        "default": 10,
-                        "description": "Number of Snippets",
+                        "default": 0,
+                        "description": "Number of Snippets, 0 for all",
                         "name": "limit",
                         "in": "query"
                     },
@@ -196,16 +472,24 @@ var doc = `{
                         "description": "Sort By",
                         "name": "sort",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "jsonl",
+                            "zip"
+                        ],
+                        "type": "string",
+                        "default": "jsonl",
+                        "description": "Export Format",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/md.MDListItem"
-                            }
+                            "$ref": "#/definitions/md.MarkdownSnippet"
                         }
                     },
                     "400": {
@@ -223,10 +507,14 @@ var doc = `{
                 }
             }
         },
-        "/md/{id}": {
-            "get": {
+        "/md/import": {
+            "post": {
+                "description": "Accepts JSON lines of ` + "`" + `CreateMDReq` + "`" + `, or a zip or tarball (optionally gzipped) of ` + "`" + `.md` + "`" + ` files.\nFiles are titled by their front matter ` + "`" + `title` + "`" + `, or else their file name.\nEach snippet is reported individually, with the update key of those created.",
                 "consumes": [
-                    "application/json"
+                    "application/x-ndjson",
+                    "application/zip",
+                    "application/x-tar",
+                    "application/gzip"
                 ],
                 "produces": [
                     "application/json"
@@ -234,21 +522,23 @@ var doc = `{
                 "tags": [
                     "md"
                 ],
-                "summary": "Retrieve Markdown Snippet",
+                "summary": "Import markdown snippets in bulk",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Snippet ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "JSON lines or archive",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/md.MarkdownSnippet"
+                            "$ref": "#/definitions/md.ImportResponse"
                         }
                     },
                     "400": {
@@ -257,6 +547,12 @@ var doc = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -264,8 +560,10 @@ var doc = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/md/search": {
+            "get": {
                 "consumes": [
                     "application/json"
                 ],
@@ -275,14 +573,125 @@ var doc = `{
                 "tags": [
                     "md"
                 ],
-                "summary": "Removes MarkdownSnippet permanantly",
+                "summary": "Search, Sort, and paginate through MarkdownSnippets.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Snippet ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Search Term",
+                        "name": "text",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of Snippets",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Skip Number of Snippets",
+                        "name": "skip",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "createDate_ASC",
+                            "createDate_DESC"
+                        ],
+                        "type": "string",
+                        "description": "Sort By",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/md.MDListItem"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/md/{id}": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Retrieve Markdown Snippet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/md.MarkdownSnippet"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deleted snippets are hidden and can be restored until the trash retention period expires.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Moves a markdown snippet to the trash",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "description": "Delete Body",
@@ -293,91 +702,1764 @@ var doc = `{
                             "$ref": "#/definitions/md.DeleteMDReq"
                         }
                     }
-                ],
-                "responses": {
-                    "204": {
-                        "description": ""
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
-                        }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "The patch format is chosen by Content-Type:\n` + "`" + `application/merge-patch+json` + "`" + ` replaces the title or body given (RFC 7396),\n` + "`" + `text/x-diff` + "`" + ` or ` + "`" + `text/x-patch` + "`" + ` applies a unified diff to the body, and\n` + "`" + `application/vnd.mdsnips.line-edits+json` + "`" + ` replaces line ranges of the body.\nDiff hunks and line edits with ` + "`" + `expect` + "`" + ` must match the current body exactly.",
+                "consumes": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Partially updates a markdown snippet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Update Key",
+                        "name": "X-Update-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Merge patch, unified diff or LineEdits",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/md.MergePatch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/md.MarkdownSnippet"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/md/{id}/attachments": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "List snippet attachments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/md.Attachment"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Stores an uploaded file, such as an image the snippet embeds, under its name or the given ` + "`" + `name` + "`" + `.\nThe content type is sniffed from the file, the response ` + "`" + `Location` + "`" + ` is where it is served.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Upload a snippet attachment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Attachment",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Update Key",
+                        "name": "updateKey",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Attachment Name",
+                        "name": "name",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/md.Attachment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/md/{id}/attachments/{name}": {
+            "get": {
+                "description": "Serves the attachment with its sniffed content type, cacheable and revalidated by its ` + "`" + `ETag` + "`" + `.\nOnly images are served inline, anything else is served as a download.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Get a snippet attachment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Attachment Name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Delete a snippet attachment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Attachment Name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Delete Body",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/md.DeleteMDReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/md/{id}/collab": {
+            "get": {
+                "description": "Editors exchange JSON messages: ` + "`" + `op` + "`" + ` carries an ot.js text operation on a ` + "`" + `version` + "`" + `,\n` + "`" + `cursor` + "`" + ` an ` + "`" + `{anchor, head}` + "`" + ` selection. The session replies ` + "`" + `init` + "`" + ` on joining, ` + "`" + `ack` + "`" + ` to\neach op and relays ops, cursors, ` + "`" + `join` + "`" + ` and ` + "`" + `leave` + "`" + ` of other editors. Edits are saved\nas a snippet revision every snapshot interval, announced by ` + "`" + `saved` + "`" + `. Changes saved outside\nthe session are merged into it and relayed as ops without an editor, overlapping changes end it.\nThe update key is sent as ` + "`" + `X-Update-Key` + "`" + `, or by browsers as the subprotocols ` + "`" + `mdsnips-collab, {updateKey}` + "`" + `.",
+                "tags": [
+                    "md"
+                ],
+                "summary": "Edit a markdown snippet collaboratively over WebSocket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Update Key, required unless offered as a subprotocol",
+                        "name": "X-Update-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "mdsnips-collab, {updateKey}",
+                        "name": "Sec-WebSocket-Protocol",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Editor display name",
+                        "name": "name",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "426": {
+                        "description": "Upgrade Required",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/md/{id}/diff": {
+            "get": {
+                "description": "Returns the body changes between revisions ` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + ` as JSON hunks with a unified diff,\nor with ` + "`" + `format` + "`" + ` as the unified diff alone or an HTML table. Either revision may be ` + "`" + `current` + "`" + `,\n` + "`" + `to` + "`" + ` defaults to the current snippet and ` + "`" + `from` + "`" + ` to the revision before ` + "`" + `to` + "`" + `.\nWith ` + "`" + `words` + "`" + `, changed lines record the words inserted and deleted.",
+                "produces": [
+                    "application/json",
+                    "text/plain",
+                    "text/html"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Compare two revisions of a markdown snippet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Revision number or current",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Revision number or current",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 3,
+                        "description": "Unchanged lines around each change",
+                        "name": "context",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include word changes",
+                        "name": "words",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "unified",
+                            "html"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/md.SnippetDiff"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/md/{id}/events": {
+            "get": {
+                "description": "Server-Sent Events of ` + "`" + `snippet.updated` + "`" + ` and ` + "`" + `snippet.deleted` + "`" + `, ending after a delete.\nIdle streams receive a ` + "`" + `: heartbeat` + "`" + ` comment. Send ` + "`" + `Upgrade: websocket` + "`" + ` to receive\nthe same events as JSON WebSocket messages instead, kept alive with pings.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Stream live changes to a markdown snippet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/md.Event"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/md/{id}/files/{name}": {
+            "get": {
+                "description": "Serves the named file as plain text. The body is served as ` + "`" + `README.md` + "`" + `.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Get the raw content of a snippet file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File Name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/md/{id}/fork": {
+            "post": {
+                "description": "Copies the current revision into a new snippet with its own id and update key,\nrecording ` + "`" + `forkedFrom` + "`" + ` and the ` + "`" + `lineage` + "`" + ` of snippets it descends from.\nThe body is optional, the fork keeps the forked snippet's title unless one is given.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Fork a markdown snippet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fork Body",
+                        "name": "message",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/md.ForkMDReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/md.MarkdownSnippet"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/md/{id}/forks": {
+            "get": {
+                "description": "Lists the snippets forked directly from the snippet, oldest first, without their body.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "List the forks of a markdown snippet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/md.MDListItem"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/md/{id}/restore": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Restores a deleted markdown snippet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Restore Body",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/md.RestoreMDReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/md.MarkdownSnippet"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/md/{id}/revisions": {
+            "get": {
+                "description": "Revisions are listed newest first, without their bodies.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "List the revisions of a markdown snippet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/md.Revision"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/md/{id}/revisions/{revision}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Retrieve a revision of a markdown snippet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision Number",
+                        "name": "revision",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/md.Revision"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/md/{id}/zip": {
+            "get": {
+                "description": "Archives the body as ` + "`" + `README.md` + "`" + ` beside each of the snippet's files.",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "md"
+                ],
+                "summary": "Download a snippet and its files",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Snippet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/webhooks.Webhook"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Deliveries are POSTed as JSON events, signed in the ` + "`" + `X-MDSnips-Signature` + "`" + ` header\nas ` + "`" + `t=\u003cunix\u003e,v1=\u003chex HMAC-SHA256 of \"\u003ct\u003e.\u003cbody\u003e\"\u003e` + "`" + ` using the returned secret.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Create a webhook",
+                "parameters": [
+                    {
+                        "description": "Post Body",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/webhooks.CreateWebhookReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/webhooks.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/deliveries/{id}/redeliver": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Queue a new delivery of a past event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/webhooks.Delivery"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Retrieve a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhooks.Webhook"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List recent deliveries of a webhook, newest first",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Number of Deliveries",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/webhooks.Delivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "description": "Explanation specific to this occurrence of the problem.",
+                    "type": "string",
+                    "example": "Request failed validation"
+                },
+                "errors": {
+                    "description": "Field level validation failures.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ValidationError"
+                    }
+                },
+                "instance": {
+                    "description": "Request path the problem occurred on.",
+                    "type": "string",
+                    "example": "/md"
+                },
+                "status": {
+                    "description": "HTTP status code.",
+                    "type": "integer",
+                    "format": "int",
+                    "example": 400
+                },
+                "title": {
+                    "description": "Short summary of the problem type.",
+                    "type": "string",
+                    "example": "Bad Request"
+                },
+                "type": {
+                    "description": "URI reference identifying the problem type.",
+                    "type": "string",
+                    "format": "uri",
+                    "example": "about:blank"
+                }
+            }
+        },
+        "api.ValidationError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "CreateMDReq.Title"
+                },
+                "tag": {
+                    "type": "string",
+                    "example": "max"
+                },
+                "value": {
+                    "type": "string",
+                    "example": "64"
+                }
+            }
+        },
+        "md.Attachment": {
+            "type": "object",
+            "properties": {
+                "contentType": {
+                    "description": "Content type sniffed from the uploaded bytes.",
+                    "type": "string",
+                    "example": "image/png"
+                },
+                "name": {
+                    "description": "Attachment name, unique within the snippet.",
+                    "type": "string",
+                    "example": "diagram.png"
+                },
+                "sha256": {
+                    "description": "Hex SHA-256 of the content, served as its ETag.",
+                    "type": "string"
+                },
+                "size": {
+                    "description": "Size in bytes.",
+                    "type": "integer",
+                    "example": 20480
+                },
+                "uploadDate": {
+                    "description": "Date the attachment was uploaded.",
+                    "type": "string",
+                    "format": "date-time"
+                }
+            }
+        },
+        "md.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Basic auth user, empty for changes made by background work.",
+                    "type": "string",
+                    "example": "admin"
+                },
+                "afterHash": {
+                    "description": "Content hash of the snippet after the change, unset for deletes.",
+                    "type": "string"
+                },
+                "beforeHash": {
+                    "description": "Content hash of the snippet before the change, unset for creates and restores.",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Audit entry guid.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "ip": {
+                    "description": "Client IP address.",
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "operation": {
+                    "description": "One of create, import, fork, update, patch, merge, collab, delete or restore.",
+                    "type": "string",
+                    "example": "update"
+                },
+                "requestId": {
+                    "description": "Request correlation id.",
+                    "type": "string"
+                },
+                "revision": {
+                    "description": "Revision saved by the change, unset for deletes.",
+                    "type": "integer",
+                    "example": 2
+                },
+                "snippetId": {
+                    "description": "Affected markdown snippet guid.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "tenant": {
+                    "description": "Tenant the snippet belongs to, empty when tenancy is disabled.",
+                    "type": "string"
+                },
+                "time": {
+                    "description": "Date the change was made.",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "userAgent": {
+                    "description": "Client User-Agent header.",
+                    "type": "string",
+                    "example": "curl/7.79.1"
+                }
+            }
+        },
+        "md.CreateMDReq": {
+            "type": "object",
+            "required": [
+                "body",
+                "title"
+            ],
+            "properties": {
+                "body": {
+                    "description": "Markdown body to save.",
+                    "type": "string",
+                    "maxLength": 64000,
+                    "minLength": 1,
+                    "example": "# Markdown Snippet\nSome Text"
+                },
+                "files": {
+                    "description": "Named files beside the body. Omitted files are left unchanged by updates.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/md.SnippetFile"
+                    }
+                },
+                "title": {
+                    "description": "Markdown snippet title.",
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 1,
+                    "example": "SouLxBurN Is Awesome!"
+                }
+            }
+        },
+        "md.DeleteMDReq": {
+            "type": "object",
+            "required": [
+                "updateKey"
+            ],
+            "properties": {
+                "updateKey": {
+                    "description": "UpdateKey required for deleting snippet.",
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "md.DiffHunk": {
+            "type": "object",
+            "properties": {
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/md.DiffLine"
+                    }
+                },
+                "newLines": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "newStart": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "oldLines": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "oldStart": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "md.DiffLine": {
+            "type": "object",
+            "properties": {
+                "newLine": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "noNewline": {
+                    "description": "Whether the line ends the body without a newline.",
+                    "type": "boolean"
+                },
+                "oldLine": {
+                    "description": "Line number in the old and new body, 0 when absent from it.",
+                    "type": "integer",
+                    "example": 0
+                },
+                "op": {
+                    "type": "string",
+                    "example": "add"
+                },
+                "text": {
+                    "type": "string",
+                    "example": "# New"
+                },
+                "words": {
+                    "description": "Word changes from the paired deleted or added line, with word diffs.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/md.WordChange"
+                    }
+                }
+            }
+        },
+        "md.Event": {
+            "type": "object",
+            "properties": {
+                "createDate": {
+                    "description": "Date the change was committed.",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "id": {
+                    "description": "Event guid.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "snippet": {
+                    "description": "Snippet after the change, without its update key. Unset for deletes.",
+                    "$ref": "#/definitions/md.MarkdownSnippet"
+                },
+                "snippetId": {
+                    "description": "Affected markdown snippet guid.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "type": {
+                    "description": "One of snippet.created, snippet.updated, snippet.deleted or snippet.restored.",
+                    "type": "string",
+                    "example": "snippet.created"
+                }
+            }
+        },
+        "md.ForkMDReq": {
+            "type": "object",
+            "properties": {
+                "title": {
+                    "description": "Title of the fork, defaults to the title of the forked snippet.",
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "My Runbook"
+                }
+            }
+        },
+        "md.ForkRef": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "Markdown snippet guid.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "revision": {
+                    "description": "Revision of the snippet when it was forked.",
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "md.ImportResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "description": "Number of snippets created.",
+                    "type": "integer",
+                    "example": 2
+                },
+                "failed": {
+                    "description": "Number of snippets rejected.",
+                    "type": "integer",
+                    "example": 0
+                },
+                "results": {
+                    "description": "Per snippet outcome, in upload order.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/md.ImportResult"
+                    }
+                }
+            }
+        },
+        "md.ImportResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Reason the snippet was not created.",
+                    "type": "string",
+                    "example": "CreateMDReq.Title failed required validation"
+                },
+                "errors": {
+                    "description": "Field level validation failures.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ValidationError"
+                    }
+                },
+                "id": {
+                    "description": "Markdown snippet guid, set when created.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "item": {
+                    "description": "Position of the snippet in the upload, starting at 1.",
+                    "type": "integer",
+                    "example": 1
+                },
+                "name": {
+                    "description": "Source file name, for archive uploads.",
+                    "type": "string",
+                    "example": "notes/go.md"
+                },
+                "updateKey": {
+                    "description": "Update hash key, set when created.",
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "md.MDListItem": {
+            "type": "object",
+            "properties": {
+                "createDate": {
+                    "description": "Date markdown snippet was created",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "deletedAt": {
+                    "description": "Date the snippet was deleted, only listed for the trash.",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "forkedFrom": {
+                    "description": "Guid of the snippet this was forked from, unset for originals.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "id": {
+                    "description": "Markdown snippet guid.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "title": {
+                    "description": "Markdown snippet title.",
+                    "type": "string",
+                    "example": "SouLxBurN Is Awesome!"
+                }
+            }
+        },
+        "md.MarkdownSnippet": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "description": "Markdown body to save.",
+                    "type": "string",
+                    "example": "# Markdown Snippet\nSome Text"
+                },
+                "createDate": {
+                    "description": "Date markdown snippet was created.",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "deletedAt": {
+                    "description": "Date the snippet was deleted, unset unless it is in the trash.",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "files": {
+                    "description": "Named files beside the markdown body.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/md.SnippetFile"
+                    }
+                },
+                "forkedFrom": {
+                    "description": "Guid of the snippet this was forked from, unset for originals.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "id": {
+                    "description": "Markdown snippet guid.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "lineage": {
+                    "description": "Snippets this descends from, its parent first, each at the revision forked.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/md.ForkRef"
                     }
+                },
+                "revision": {
+                    "description": "Latest saved revision number, starting at 1.",
+                    "type": "integer",
+                    "example": 1
+                },
+                "title": {
+                    "description": "Markdown snippet title.",
+                    "type": "string",
+                    "example": "SouLxBurN Is Awesome!"
+                },
+                "updateKey": {
+                    "description": "Update hash key allowing the snippet to be updated.",
+                    "type": "string",
+                    "format": "uuid"
                 }
             }
-        }
-    },
-    "definitions": {
-        "api.ErrorResponse": {
+        },
+        "md.MergeConflictResponse": {
             "type": "object",
             "properties": {
-                "code": {
+                "body": {
+                    "description": "Merged body with conflict markers.",
+                    "type": "string",
+                    "example": "\u003c\u003c\u003c\u003c\u003c\u003c\u003c revision 5\nTheirs\n=======\nMine\n\u003e\u003e\u003e\u003e\u003e\u003e\u003e update"
+                },
+                "conflicts": {
+                    "description": "Number of conflicting regions.",
+                    "type": "integer",
+                    "example": 1
+                },
+                "detail": {
+                    "description": "Explanation specific to this occurrence of the problem.",
+                    "type": "string",
+                    "example": "Request failed validation"
+                },
+                "errors": {
+                    "description": "Field level validation failures.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ValidationError"
+                    }
+                },
+                "fileConflicts": {
+                    "description": "Names of the files changed differently.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "deploy.sh"
+                    ]
+                },
+                "files": {
+                    "description": "Merged files with conflict markers, omitted when the update leaves them unchanged.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/md.SnippetFile"
+                    }
+                },
+                "instance": {
+                    "description": "Request path the problem occurred on.",
+                    "type": "string",
+                    "example": "/md"
+                },
+                "revision": {
+                    "description": "Revision to send as baseRevision with the resolved update.",
+                    "type": "integer",
+                    "example": 5
+                },
+                "status": {
+                    "description": "HTTP status code.",
                     "type": "integer",
                     "format": "int",
                     "example": 400
                 },
-                "message": {
+                "title": {
+                    "description": "Merged title, the update's title when both changed it.",
+                    "type": "string",
+                    "example": "SouLxBurN Is Awesome!"
+                },
+                "titleConflict": {
+                    "description": "Whether the title was changed differently.",
+                    "type": "boolean"
+                },
+                "type": {
+                    "description": "URI reference identifying the problem type.",
                     "type": "string",
-                    "format": "string"
+                    "format": "uri",
+                    "example": "about:blank"
                 }
             }
         },
-        "md.CreateMDReq": {
+        "md.MergePatch": {
             "type": "object",
-            "required": [
-                "body",
-                "title"
-            ],
             "properties": {
                 "body": {
-                    "description": "Markdown body to save.",
+                    "description": "Replacement body, unchanged when nil.",
                     "type": "string",
-                    "maxLength": 64000,
-                    "minLength": 1,
                     "example": "# Markdown Snippet\nSome Text"
                 },
                 "title": {
-                    "description": "Markdown snippet title.",
+                    "description": "Replacement title, unchanged when nil.",
                     "type": "string",
-                    "maxLength": 64,
-                    "minLength": 1,
                     "example": "SouLxBurN Is Awesome!"
                 }
             }
         },
-        "md.DeleteMDReq": {
+        "md.RestoreMDReq": {
             "type": "object",
             "required": [
                 "updateKey"
             ],
             "properties": {
                 "updateKey": {
-                    "description": "UpdateKey required for deleting snippet.",
+                    "description": "UpdateKey required for restoring a deleted snippet.",
                     "type": "string",
                     "format": "uuid"
                 }
             }
         },
-        "md.MDListItem": {
+        "md.Revision": {
             "type": "object",
             "properties": {
+                "body": {
+                    "description": "Markdown body, omitted when listing revisions.",
+                    "type": "string",
+                    "example": "# Markdown Snippet\nSome Text"
+                },
                 "createDate": {
-                    "description": "Date markdown snippet was created",
+                    "description": "Date the revision was saved.",
                     "type": "string",
                     "format": "date-time"
                 },
-                "id": {
+                "files": {
+                    "description": "Named files beside the body, omitted when listing revisions.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/md.SnippetFile"
+                    }
+                },
+                "revision": {
+                    "description": "Revision number, starting at 1 and incremented by every save.",
+                    "type": "integer",
+                    "example": 3
+                },
+                "snippetId": {
                     "description": "Markdown snippet guid.",
                     "type": "string",
                     "format": "uuid"
                 },
+                "source": {
+                    "description": "Write that saved the revision, one of create, fork, update, patch, merge or collab.",
+                    "type": "string",
+                    "example": "update"
+                },
                 "title": {
                     "description": "Markdown snippet title.",
                     "type": "string",
@@ -385,33 +2467,86 @@ var doc = `{
                 }
             }
         },
-        "md.MarkdownSnippet": {
+        "md.SnippetDiff": {
             "type": "object",
             "properties": {
-                "body": {
-                    "description": "Markdown body to save.",
-                    "type": "string",
-                    "example": "# Markdown Snippet\nSome Text"
+                "additions": {
+                    "description": "Lines added and deleted in the body.",
+                    "type": "integer",
+                    "example": 1
                 },
-                "createDate": {
-                    "description": "Date markdown snippet was created.",
+                "deletions": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "from": {
+                    "description": "Revision compared from, 0 for an empty snippet.",
+                    "type": "integer",
+                    "example": 2
+                },
+                "fromTitle": {
+                    "description": "Title at each revision.",
                     "type": "string",
-                    "format": "date-time"
+                    "example": "SouLxBurN Is Awesome!"
                 },
-                "id": {
+                "hunks": {
+                    "description": "Body changes with surrounding context.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/md.DiffHunk"
+                    }
+                },
+                "snippetId": {
                     "description": "Markdown snippet guid.",
                     "type": "string",
                     "format": "uuid"
                 },
-                "title": {
-                    "description": "Markdown snippet title.",
+                "to": {
+                    "description": "Revision compared to.",
+                    "type": "integer",
+                    "example": 3
+                },
+                "toTitle": {
                     "type": "string",
-                    "example": "SouLxBurN Is Awesome!"
+                    "example": "SouLxBurN Is Very Awesome!"
                 },
-                "updateKey": {
-                    "description": "Update hash key allowing the snippet to be updated.",
+                "unified": {
+                    "description": "Body changes as a unified diff.",
                     "type": "string",
-                    "format": "uuid"
+                    "example": "--- revision 2\n+++ revision 3\n@@ -1 +1 @@\n-# Old\n+# New\n"
+                }
+            }
+        },
+        "md.SnippetFile": {
+            "type": "object",
+            "required": [
+                "content",
+                "name"
+            ],
+            "properties": {
+                "content": {
+                    "description": "File content.",
+                    "type": "string",
+                    "maxLength": 128000,
+                    "minLength": 1,
+                    "example": "#!/bin/sh\necho deploy"
+                },
+                "language": {
+                    "description": "Syntax highlighting language, detected from the name when empty.",
+                    "type": "string",
+                    "maxLength": 32,
+                    "example": "shell"
+                },
+                "name": {
+                    "description": "File name, unique within the snippet. ` + "`" + `README.md` + "`" + ` is the snippet body.",
+                    "type": "string",
+                    "maxLength": 128,
+                    "example": "deploy.sh"
+                },
+                "size": {
+                    "description": "Content size in bytes, set by the server.",
+                    "type": "integer",
+                    "example": 24
                 }
             }
         },
@@ -424,6 +2559,11 @@ var doc = `{
                 "updateKey"
             ],
             "properties": {
+                "baseRevision": {
+                    "description": "Revision the update was edited from. When newer revisions were\nsaved since, their changes are merged with the update.",
+                    "type": "integer",
+                    "example": 3
+                },
                 "body": {
                     "description": "Markdown body to save.",
                     "type": "string",
@@ -431,6 +2571,13 @@ var doc = `{
                     "minLength": 1,
                     "example": "# Markdown Snippet\nSome Text"
                 },
+                "files": {
+                    "description": "Named files beside the body. Omitted files are left unchanged by updates.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/md.SnippetFile"
+                    }
+                },
                 "id": {
                     "description": "Markdown snippet guid.",
                     "type": "string",
@@ -449,6 +2596,158 @@ var doc = `{
                     "format": "uuid"
                 }
             }
+        },
+        "md.WordChange": {
+            "type": "object",
+            "properties": {
+                "op": {
+                    "type": "string",
+                    "example": "insert"
+                },
+                "text": {
+                    "type": "string",
+                    "example": "Very "
+                }
+            }
+        },
+        "webhooks.Attempt": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "description": "Date the attempt was made.",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "durationMs": {
+                    "description": "Round trip duration in milliseconds.",
+                    "type": "integer",
+                    "example": 42
+                },
+                "error": {
+                    "description": "Transport error or non 2xx status.",
+                    "type": "string"
+                },
+                "statusCode": {
+                    "description": "Response status code, 0 when no response was received.",
+                    "type": "integer",
+                    "example": 200
+                }
+            }
+        },
+        "webhooks.CreateWebhookReq": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "description": "Event types to subscribe to, every type when empty.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "snippet.created"
+                    ]
+                },
+                "url": {
+                    "description": "Endpoint receiving event deliveries.",
+                    "type": "string",
+                    "example": "https://bot.example.com/mdsnips"
+                }
+            }
+        },
+        "webhooks.Delivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Every attempt made, oldest first.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/webhooks.Attempt"
+                    }
+                },
+                "createDate": {
+                    "description": "Date the delivery was created.",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "eventId": {
+                    "description": "Delivered event guid.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "eventType": {
+                    "description": "Delivered event type.",
+                    "type": "string",
+                    "example": "snippet.created"
+                },
+                "id": {
+                    "description": "Delivery guid, sent as X-MDSnips-Delivery.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "nextAttemptAt": {
+                    "description": "Date of the next attempt while pending.",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "payload": {
+                    "description": "JSON request body.",
+                    "type": "string"
+                },
+                "redeliveryOf": {
+                    "description": "Delivery guid this resends, for redeliveries.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "status": {
+                    "description": "One of pending, succeeded or failed.",
+                    "type": "string",
+                    "example": "succeeded"
+                },
+                "webhookId": {
+                    "description": "Receiving webhook guid.",
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "webhooks.Webhook": {
+            "type": "object",
+            "properties": {
+                "createDate": {
+                    "description": "Date the webhook was created.",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "events": {
+                    "description": "Subscribed event types, every type when empty.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "snippet.created",
+                        "snippet.updated"
+                    ]
+                },
+                "id": {
+                    "description": "Webhook guid.",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "secret": {
+                    "description": "HMAC-SHA256 signing secret, only returned when created.",
+                    "type": "string",
+                    "example": "4f1c0a..."
+                },
+                "url": {
+                    "description": "Endpoint receiving event deliveries.",
+                    "type": "string",
+                    "example": "https://bot.example.com/mdsnips"
+                }
+            }
         }
     },
     "tags": [
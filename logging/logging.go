@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey string
+
+const requestIDKey contextKey = "requestId"
+
+// Logger
+// Application wide structured JSON logger.
+var Logger = newLogger(os.Stdout)
+
+// newLogger
+// Creates a JSON logger writing to out at info level.
+func newLogger(out io.Writer) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(out)
+	logger.SetLevel(logrus.InfoLevel)
+	logger.SetFormatter(&logrus.JSONFormatter{
+		TimestampFormat: "2006-01-02T15:04:05.000Z0700",
+		FieldMap: logrus.FieldMap{
+			logrus.FieldKeyTime: "time",
+			logrus.FieldKeyMsg:  "msg",
+		},
+	})
+	return logger
+}
+
+// Configure
+// Sets the Logger level, e.g. `debug`, `info`, `warn`, `error`.
+func Configure(level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	Logger.SetLevel(lvl)
+	return nil
+}
+
+// WithRequestID
+// Returns a copy of ctx carrying the request id.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID
+// Returns the request id carried by ctx, empty when absent.
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// FromContext
+// Returns a log entry tagged with the request id
+// and trace id carried by ctx.
+func FromContext(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(Logger)
+	if requestID := RequestID(ctx); requestID != "" {
+		entry = entry.WithField("requestId", requestID)
+	}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.HasTraceID() {
+		entry = entry.WithField("traceId", spanCtx.TraceID().String())
+	}
+	return entry
+}
@@ -0,0 +1,536 @@
+// Package client is a typed Go client for the MDSnips API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soulxburn/mdsnips/md"
+)
+
+// Client
+// Calls the MDSnips API. Safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	// authorize sets credentials on every request.
+	authorize   func(req *http.Request)
+	tenantToken string
+	retry       Retry
+}
+
+// Retry
+// Backoff applied to requests answered with 429 or 5xx.
+// POST requests are only retried on 429, when the
+// server is known not to have processed them.
+type Retry struct {
+	// Maximum number of retries, 0 disables retrying.
+	Max int
+	// Wait before the first retry, doubled for each attempt.
+	Initial time.Duration
+	// Upper bound on the wait between retries.
+	MaxWait time.Duration
+}
+
+// Option configures a Client.
+type Option func(c *Client)
+
+// WithBasicAuth
+// Authenticates requests with HTTP basic auth.
+func WithBasicAuth(user, pass string) Option {
+	return func(c *Client) {
+		c.authorize = func(req *http.Request) { req.SetBasicAuth(user, pass) }
+	}
+}
+
+// WithBearerToken
+// Authenticates requests with an `Authorization: Bearer` token.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.authorize = func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+	}
+}
+
+// WithTenantToken
+// Sends token as X-Tenant-Token, for servers using the token tenant resolver.
+func WithTenantToken(token string) Option {
+	return func(c *Client) { c.tenantToken = token }
+}
+
+// WithHTTPClient
+// Sends requests with httpClient instead of a client with a 30s timeout.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRetry
+// Overrides the default of 3 retries from 200ms up to 5s.
+func WithRetry(retry Retry) Option {
+	return func(c *Client) { c.retry = retry }
+}
+
+// New
+// Returns a Client for the API at baseURL, e.g. `https://mdsnips.example.com`.
+// Path tenancy is addressed by including the prefix, e.g. `https://host/t/acme`.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("base url: `%s` must be http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		authorize:  func(*http.Request) {},
+		retry:      Retry{Max: 3, Initial: 200 * time.Millisecond, MaxWait: 5 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// BaseURL
+// Returns the API base URL without a trailing slash.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Create
+// Creates a snippet. The returned snippet holds
+// the update key needed to update or delete it.
+func (c *Client) Create(ctx context.Context, req *md.CreateMDReq) (*md.MarkdownSnippet, error) {
+	snippet := new(md.MarkdownSnippet)
+	if err := c.do(ctx, http.MethodPost, "/md", nil, req, snippet); err != nil {
+		return nil, err
+	}
+	return snippet, nil
+}
+
+// Get
+// Returns snippet id.
+func (c *Client) Get(ctx context.Context, id string) (*md.MarkdownSnippet, error) {
+	snippet := new(md.MarkdownSnippet)
+	if err := c.do(ctx, http.MethodGet, "/md/"+url.PathEscape(id), nil, nil, snippet); err != nil {
+		return nil, err
+	}
+	return snippet, nil
+}
+
+// List
+// Returns every snippet.
+// Deprecated: use Search, GET /md is deprecated.
+func (c *Client) List(ctx context.Context) ([]md.MDListItem, error) {
+	var snippets []md.MDListItem
+	if err := c.do(ctx, http.MethodGet, "/md", nil, nil, &snippets); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// Search
+// Returns a page of snippets matching params.
+// Zero values keep the server defaults.
+func (c *Client) Search(ctx context.Context, params md.MDSearchParams) ([]md.MDListItem, error) {
+	var snippets []md.MDListItem
+	if err := c.do(ctx, http.MethodGet, "/md/search", searchQuery(params), nil, &snippets); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// Import
+// Creates snippets in bulk from JSON lines of md.CreateMDReq, or a zip
+// or tarball of markdown files. body is read fully so it can be retried.
+func (c *Client) Import(ctx context.Context, body io.Reader) (*md.ImportResponse, error) {
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.roundTrip(ctx, http.MethodPost, c.baseURL+"/md/import", "application/octet-stream", payload, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := new(md.ImportResponse)
+	return result, json.NewDecoder(resp.Body).Decode(result)
+}
+
+// Export
+// Streams every snippet matching params as format, md.ExportJSONL
+// or md.ExportZip. A zero Limit exports everything.
+// The caller must close the returned reader.
+func (c *Client) Export(ctx context.Context, params md.MDSearchParams, format string) (io.ReadCloser, error) {
+	query := searchQuery(params)
+	query.Set("format", format)
+	resp, err := c.roundTrip(ctx, http.MethodGet, c.baseURL+"/md/export?"+query.Encode(), "", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Update
+// Replaces the title and body of snippet req.ID.
+func (c *Client) Update(ctx context.Context, req *md.UpdateMDReq) (*md.MarkdownSnippet, error) {
+	snippet := new(md.MarkdownSnippet)
+	if err := c.do(ctx, http.MethodPatch, "/md", nil, req, snippet); err != nil {
+		return nil, err
+	}
+	return snippet, nil
+}
+
+// PatchMerge
+// Replaces the title or body set on patch, as a JSON merge patch.
+func (c *Client) PatchMerge(ctx context.Context, id string, updateKey string, patch *md.MergePatch) (*md.MarkdownSnippet, error) {
+	payload, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	return c.patch(ctx, id, updateKey, md.MergePatchType, payload)
+}
+
+// PatchDiff
+// Applies a unified diff to the body.
+// Hunks not matching the current body fail with md.ErrConflict.
+func (c *Client) PatchDiff(ctx context.Context, id string, updateKey string, diff string) (*md.MarkdownSnippet, error) {
+	return c.patch(ctx, id, updateKey, md.DiffType, []byte(diff))
+}
+
+// PatchLines
+// Replaces line ranges of the body.
+// Edits expecting other lines fail with md.ErrConflict.
+func (c *Client) PatchLines(ctx context.Context, id string, updateKey string, edits *md.LineEdits) (*md.MarkdownSnippet, error) {
+	payload, err := json.Marshal(edits)
+	if err != nil {
+		return nil, err
+	}
+	return c.patch(ctx, id, updateKey, md.LineEditsType, payload)
+}
+
+// patch
+// Sends payload as a patch of contentType to snippet id.
+func (c *Client) patch(ctx context.Context, id string, updateKey string, contentType string, payload []byte) (*md.MarkdownSnippet, error) {
+	header := http.Header{"X-Update-Key": {updateKey}}
+	resp, err := c.roundTrip(ctx, http.MethodPatch, c.baseURL+"/md/"+url.PathEscape(id), contentType, payload, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	snippet := new(md.MarkdownSnippet)
+	return snippet, json.NewDecoder(resp.Body).Decode(snippet)
+}
+
+// Revisions
+// Lists the revisions of snippet id newest first, without their bodies.
+func (c *Client) Revisions(ctx context.Context, id string) ([]md.Revision, error) {
+	var revisions []md.Revision
+	if err := c.do(ctx, http.MethodGet, "/md/"+url.PathEscape(id)+"/revisions", nil, nil, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// Revision
+// Returns revision rev of snippet id.
+func (c *Client) Revision(ctx context.Context, id string, rev int64) (*md.Revision, error) {
+	revision := new(md.Revision)
+	path := "/md/" + url.PathEscape(id) + "/revisions/" + strconv.FormatInt(rev, 10)
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, revision); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// Diff
+// Compares two revisions of snippet id, either of which may be
+// md.CurrentRevision. params.Context is always sent, so set it to
+// md.DefaultDiffContext for the server default.
+func (c *Client) Diff(ctx context.Context, id string, params md.DiffParams) (*md.SnippetDiff, error) {
+	query := url.Values{"context": {strconv.Itoa(params.Context)}}
+	if params.From != 0 {
+		query.Set("from", revisionQuery(params.From))
+	}
+	if params.To != 0 {
+		query.Set("to", revisionQuery(params.To))
+	}
+	if params.Words {
+		query.Set("words", "true")
+	}
+
+	diff := new(md.SnippetDiff)
+	if err := c.do(ctx, http.MethodGet, "/md/"+url.PathEscape(id)+"/diff", query, nil, diff); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// revisionQuery
+// Formats rev as the from and to query values of GET /md/{id}/diff.
+func revisionQuery(rev int64) string {
+	if rev == md.CurrentRevision {
+		return "current"
+	}
+	return strconv.FormatInt(rev, 10)
+}
+
+// Fork
+// Copies snippet id into a new snippet with its own update key.
+// An empty title keeps the title of the forked snippet.
+func (c *Client) Fork(ctx context.Context, id string, title string) (*md.MarkdownSnippet, error) {
+	fork := new(md.MarkdownSnippet)
+	if err := c.do(ctx, http.MethodPost, "/md/"+url.PathEscape(id)+"/fork", nil, &md.ForkMDReq{Title: title}, fork); err != nil {
+		return nil, err
+	}
+	return fork, nil
+}
+
+// Forks
+// Lists the snippets forked directly from snippet id, oldest first.
+func (c *Client) Forks(ctx context.Context, id string) ([]md.MDListItem, error) {
+	var forks []md.MDListItem
+	if err := c.do(ctx, http.MethodGet, "/md/"+url.PathEscape(id)+"/forks", nil, nil, &forks); err != nil {
+		return nil, err
+	}
+	return forks, nil
+}
+
+// File
+// Returns the content of the named file of snippet id,
+// the body for md.BodyFileName.
+func (c *Client) File(ctx context.Context, id string, name string) (string, error) {
+	endpoint := c.baseURL + "/md/" + url.PathEscape(id) + "/files/" + url.PathEscape(name)
+	resp, err := c.roundTrip(ctx, http.MethodGet, endpoint, "", nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	return string(content), err
+}
+
+// Zip
+// Streams snippet id as a zip archive of its body and files.
+// The caller must close the returned reader.
+func (c *Client) Zip(ctx context.Context, id string) (io.ReadCloser, error) {
+	resp, err := c.roundTrip(ctx, http.MethodGet, c.baseURL+"/md/"+url.PathEscape(id)+"/zip", "", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// UploadAttachment
+// Attaches content to snippet id under name. The content is read
+// into memory so the upload can be retried.
+func (c *Client) UploadAttachment(ctx context.Context, id string, updateKey string, name string, content io.Reader) (*md.Attachment, error) {
+	var payload bytes.Buffer
+	form := multipart.NewWriter(&payload)
+	form.WriteField("updateKey", updateKey)
+	form.WriteField("name", name)
+	file, err := form.CreateFormFile("file", name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	endpoint := c.baseURL + "/md/" + url.PathEscape(id) + "/attachments"
+	resp, err := c.roundTrip(ctx, http.MethodPost, endpoint, form.FormDataContentType(), payload.Bytes(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	attachment := new(md.Attachment)
+	return attachment, json.NewDecoder(resp.Body).Decode(attachment)
+}
+
+// Attachments
+// Lists the attachments of snippet id by name.
+func (c *Client) Attachments(ctx context.Context, id string) ([]md.Attachment, error) {
+	var attachments []md.Attachment
+	if err := c.do(ctx, http.MethodGet, "/md/"+url.PathEscape(id)+"/attachments", nil, nil, &attachments); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// Attachment
+// Streams the named attachment of snippet id.
+// The caller must close the returned reader.
+func (c *Client) Attachment(ctx context.Context, id string, name string) (io.ReadCloser, error) {
+	endpoint := c.baseURL + "/md/" + url.PathEscape(id) + "/attachments/" + url.PathEscape(name)
+	resp, err := c.roundTrip(ctx, http.MethodGet, endpoint, "", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// DeleteAttachment
+// Removes the named attachment of snippet id.
+func (c *Client) DeleteAttachment(ctx context.Context, id string, updateKey string, name string) error {
+	path := "/md/" + url.PathEscape(id) + "/attachments/" + url.PathEscape(name)
+	return c.do(ctx, http.MethodDelete, path, nil, &md.DeleteMDReq{UpdateKey: updateKey}, nil)
+}
+
+// Delete
+// Moves snippet id to the trash.
+func (c *Client) Delete(ctx context.Context, id string, updateKey string) error {
+	return c.do(ctx, http.MethodDelete, "/md/"+url.PathEscape(id), nil, &md.DeleteMDReq{UpdateKey: updateKey}, nil)
+}
+
+// Restore
+// Restores snippet id from the trash.
+func (c *Client) Restore(ctx context.Context, id string, updateKey string) (*md.MarkdownSnippet, error) {
+	snippet := new(md.MarkdownSnippet)
+	if err := c.do(ctx, http.MethodPost, "/md/"+url.PathEscape(id)+"/restore", nil, &md.RestoreMDReq{UpdateKey: updateKey}, snippet); err != nil {
+		return nil, err
+	}
+	return snippet, nil
+}
+
+// do
+// Sends body as JSON to path and decodes
+// the response into out. out may be nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var payload []byte
+	contentType := ""
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+
+	resp, err := c.roundTrip(ctx, method, endpoint, contentType, payload, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// roundTrip
+// Sends payload to endpoint with header, retrying per c.retry, and returns
+// the first 2xx response. Other responses are returned as *Error.
+func (c *Client) roundTrip(ctx context.Context, method, endpoint, contentType string, payload []byte, header http.Header) (*http.Response, error) {
+	wait := c.retry.Initial
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, endpoint, contentType, payload, header)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			return resp, nil
+		}
+
+		apiErr := decodeError(resp)
+		if attempt >= c.retry.Max || !retryable(method, resp.StatusCode) {
+			return nil, apiErr
+		}
+
+		delay := retryAfter(resp.Header.Get("Retry-After"), wait)
+		if c.retry.MaxWait > 0 && delay > c.retry.MaxWait {
+			delay = c.retry.MaxWait
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		wait *= 2
+	}
+}
+
+// send
+// Sends a single request attempt.
+func (c *Client) send(ctx context.Context, method, endpoint, contentType string, payload []byte, header http.Header) (*http.Response, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.tenantToken != "" {
+		req.Header.Set("X-Tenant-Token", c.tenantToken)
+	}
+	c.authorize(req)
+	return c.httpClient.Do(req)
+}
+
+// searchQuery
+// Encodes the non-zero search params, zero values keep the server defaults.
+func searchQuery(params md.MDSearchParams) url.Values {
+	query := url.Values{}
+	if params.Text != "" {
+		query.Set("text", params.Text)
+	}
+	if params.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(params.Limit, 10))
+	}
+	if params.Skip > 0 {
+		query.Set("skip", strconv.FormatInt(params.Skip, 10))
+	}
+	if params.SortBy != "" {
+		query.Set("sort", string(params.SortBy))
+	}
+	return query
+}
+
+// retryable
+// Reports whether a method answered with status may be retried.
+func retryable(method string, status int) bool {
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+	return status >= 500 && method != http.MethodPost
+}
+
+// retryAfter
+// Returns the delay requested by a Retry-After header
+// in seconds, or fallback when absent or invalid.
+func retryAfter(header string, fallback time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return fallback
+}
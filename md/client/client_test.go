@@ -0,0 +1,350 @@
+package client
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulxburn/mdsnips/md"
+	"github.com/soulxburn/mdsnips/md/client/clienttest"
+	"github.com/stretchr/testify/assert"
+)
+
+// SetupClient
+// Starts a clienttest.Server and returns a Client
+// authenticated against it with fast retries.
+func SetupClient(t *testing.T, opts ...Option) (*Client, *clienttest.Server) {
+	server := clienttest.NewServer()
+	t.Cleanup(server.Close)
+
+	opts = append([]Option{
+		WithBasicAuth(clienttest.User, clienttest.Pass),
+		WithRetry(Retry{Max: 2, Initial: time.Millisecond, MaxWait: 10 * time.Millisecond}),
+	}, opts...)
+	c, err := New(server.URL+"/", opts...)
+	assert.Nil(t, err)
+	return c, server
+}
+
+// Test_SnippetLifecycle
+// Every route round trips through the typed client.
+func Test_SnippetLifecycle(t *testing.T) {
+	c, server := SetupClient(t)
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, &md.CreateMDReq{Title: "Go", Body: "# Go"})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.NotEmpty(t, created.UpdateKey)
+
+	fetched, err := c.Get(ctx, created.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "# Go", fetched.Body)
+
+	updated, err := c.Update(ctx, &md.UpdateMDReq{
+		ID:          created.ID,
+		UpdateKey:   created.UpdateKey,
+		CreateMDReq: md.CreateMDReq{Title: "Go", Body: "# Go 2"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "# Go 2", updated.Body)
+
+	found, err := c.Search(ctx, md.MDSearchParams{Text: "go", Limit: 5, Skip: 0, SortBy: md.CreateDate_ASC})
+	assert.Nil(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, "limit=5&sort=createDate_ASC&text=go", server.Requests()[3].URL.RawQuery)
+
+	all, err := c.List(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, all, 1)
+
+	assert.Nil(t, c.Delete(ctx, created.ID, created.UpdateKey))
+	assert.Equal(t, 0, server.Len())
+
+	restored, err := c.Restore(ctx, created.ID, created.UpdateKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "# Go 2", restored.Body)
+	assert.Equal(t, 1, server.Len())
+}
+
+// Test_ErrorDecoding
+// Problem responses decode into *Error and match md domain errors.
+func Test_ErrorDecoding(t *testing.T) {
+	c, server := SetupClient(t)
+	ctx := context.Background()
+	server.Put(&md.MarkdownSnippet{ID: "abc", Title: "T", Body: "B", UpdateKey: "secret"})
+
+	_, err := c.Get(ctx, "missing")
+	assert.True(t, errors.Is(err, md.ErrNotFound))
+	var apiErr *Error
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.Status)
+	assert.Equal(t, "Markdown Snippet Not Found", apiErr.Detail)
+	assert.Equal(t, "/md/missing", apiErr.Instance)
+
+	err = c.Delete(ctx, "abc", "wrong")
+	assert.True(t, errors.Is(err, md.ErrInvalidKey))
+
+	_, err = c.Create(ctx, &md.CreateMDReq{Body: "# No title"})
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadRequest, apiErr.Status)
+	assert.Equal(t, "CreateMDReq.Title", apiErr.Errors[0].FailedField)
+}
+
+// Test_Auth
+// Basic and bearer credentials are sent, and missing ones rejected.
+func Test_Auth(t *testing.T) {
+	bearer, _ := SetupClient(t, WithBearerToken(clienttest.BearerToken))
+	_, err := bearer.List(context.Background())
+	assert.Nil(t, err)
+
+	wrong, _ := SetupClient(t, WithBasicAuth("user", "nope"))
+	_, err = wrong.List(context.Background())
+	assert.EqualError(t, err, "401 Unauthorized")
+}
+
+// Test_RetryBackoff
+// 429 and 5xx responses are retried up to Retry.Max.
+func Test_RetryBackoff(t *testing.T) {
+	c, server := SetupClient(t)
+	ctx := context.Background()
+
+	server.FailNext(http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	_, err := c.List(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, server.Requests(), 3)
+
+	server.FailNext(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	_, err = c.List(ctx)
+	var apiErr *Error
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadGateway, apiErr.Status)
+	assert.Len(t, server.Requests(), 6)
+}
+
+// Test_RetryPost
+// POST is retried on 429 but not on 5xx, the server may have created the snippet.
+func Test_RetryPost(t *testing.T) {
+	c, server := SetupClient(t)
+	ctx := context.Background()
+
+	server.FailNext(http.StatusTooManyRequests)
+	_, err := c.Create(ctx, &md.CreateMDReq{Title: "T", Body: "B"})
+	assert.Nil(t, err)
+
+	server.FailNext(http.StatusInternalServerError)
+	_, err = c.Create(ctx, &md.CreateMDReq{Title: "T", Body: "B"})
+	assert.NotNil(t, err)
+	assert.Len(t, server.Requests(), 3)
+}
+
+// Test_ContextCancelsRetry
+// Cancelling the context stops waiting between retries.
+func Test_ContextCancelsRetry(t *testing.T) {
+	c, server := SetupClient(t, WithRetry(Retry{Max: 5, Initial: time.Hour}))
+	server.FailNext(http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.List(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+// Test_NewInvalidURL
+func Test_NewInvalidURL(t *testing.T) {
+	_, err := New("localhost:3000")
+	assert.NotNil(t, err)
+}
+
+// Test_ImportExport
+// Bulk imports report per item results and exports stream back.
+func Test_ImportExport(t *testing.T) {
+	c, server := SetupClient(t)
+	ctx := context.Background()
+
+	server.FailNext(http.StatusTooManyRequests)
+	body := `{"title":"One","body":"# One"}` + "\n" + `{"title":"","body":"# Untitled"}` + "\n"
+	result, err := c.Import(ctx, strings.NewReader(body))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.Failed)
+	assert.NotEmpty(t, result.Results[0].UpdateKey)
+	assert.Equal(t, "CreateMDReq.Title", result.Results[1].Errors[0].FailedField)
+
+	export, err := c.Export(ctx, md.MDSearchParams{Text: "one"}, md.ExportJSONL)
+	assert.Nil(t, err)
+	defer export.Close()
+	snippet := new(md.MarkdownSnippet)
+	assert.Nil(t, json.NewDecoder(export).Decode(snippet))
+	assert.Equal(t, "One", snippet.Title)
+	assert.Empty(t, snippet.UpdateKey)
+	assert.Equal(t, "format=jsonl&text=one", server.Requests()[2].URL.RawQuery)
+}
+
+// Test_Patch
+// Each patch format is sent with its Content-Type and the update key header.
+func Test_Patch(t *testing.T) {
+	c, server := SetupClient(t)
+	ctx := context.Background()
+	server.Put(&md.MarkdownSnippet{ID: "abc", Title: "T", Body: "one\ntwo\n", UpdateKey: "secret", Revision: 1})
+
+	title := "Title"
+	patched, err := c.PatchMerge(ctx, "abc", "secret", &md.MergePatch{Title: &title})
+	assert.Nil(t, err)
+	assert.Equal(t, "Title", patched.Title)
+	assert.Equal(t, int64(2), patched.Revision)
+	assert.Empty(t, patched.UpdateKey)
+	assert.Equal(t, md.MergePatchType, server.Requests()[0].Header.Get("Content-Type"))
+	assert.Equal(t, "secret", server.Requests()[0].Header.Get("X-Update-Key"))
+
+	patched, err = c.PatchDiff(ctx, "abc", "secret", "@@ -2 +2 @@\n-two\n+2\n")
+	assert.Nil(t, err)
+	assert.Equal(t, "one\n2\n", patched.Body)
+
+	patched, err = c.PatchLines(ctx, "abc", "secret", &md.LineEdits{Edits: []md.LineEdit{{Start: 1, End: 1, Lines: []string{"1"}}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "1\n2\n", patched.Body)
+
+	_, err = c.PatchLines(ctx, "abc", "secret", &md.LineEdits{Edits: []md.LineEdit{{Start: 1, End: 1, Expect: []string{"one"}, Lines: []string{"x"}}}})
+	assert.True(t, errors.Is(err, md.ErrConflict))
+	_, err = c.PatchDiff(ctx, "abc", "wrong", "@@ -1 +1 @@\n-1\n+x\n")
+	assert.True(t, errors.Is(err, md.ErrInvalidKey))
+}
+
+// Test_RevisionsDiff
+// Saved revisions are listed, fetched and compared.
+func Test_RevisionsDiff(t *testing.T) {
+	c, server := SetupClient(t)
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, &md.CreateMDReq{Title: "Go", Body: "one\ntwo\n"})
+	assert.Nil(t, err)
+	_, err = c.Update(ctx, &md.UpdateMDReq{
+		ID:          created.ID,
+		UpdateKey:   created.UpdateKey,
+		CreateMDReq: md.CreateMDReq{Title: "Go", Body: "one\n2\n"},
+	})
+	assert.Nil(t, err)
+
+	revisions, err := c.Revisions(ctx, created.ID)
+	assert.Nil(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, md.SourceUpdate, revisions[0].Source)
+	assert.Empty(t, revisions[0].Body)
+
+	revision, err := c.Revision(ctx, created.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "one\ntwo\n", revision.Body)
+	_, err = c.Revision(ctx, created.ID, 9)
+	assert.True(t, errors.Is(err, md.ErrNotFound))
+
+	diff, err := c.Diff(ctx, created.ID, md.DiffParams{From: 1, To: md.CurrentRevision, Context: md.DefaultDiffContext})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), diff.To)
+	assert.Equal(t, 1, diff.Additions)
+	assert.Equal(t, 1, diff.Deletions)
+	assert.Contains(t, diff.Unified, "-two\n+2\n")
+	assert.Equal(t, "context=3&from=1&to=current", server.Requests()[5].URL.RawQuery)
+}
+
+// Test_Fork
+// Forks copy the snippet under a new key and are listed on their parent.
+func Test_Fork(t *testing.T) {
+	c, _ := SetupClient(t)
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, &md.CreateMDReq{Title: "Go", Body: "# Go"})
+	assert.Nil(t, err)
+
+	fork, err := c.Fork(ctx, created.ID, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "Go", fork.Title)
+	assert.Equal(t, "# Go", fork.Body)
+	assert.Equal(t, created.ID, fork.ForkedFrom)
+	assert.Equal(t, []md.ForkRef{{ID: created.ID, Revision: 1}}, fork.Lineage)
+	assert.NotEqual(t, created.UpdateKey, fork.UpdateKey)
+
+	named, err := c.Fork(ctx, created.ID, "Mine")
+	assert.Nil(t, err)
+	assert.Equal(t, "Mine", named.Title)
+
+	forks, err := c.Forks(ctx, created.ID)
+	assert.Nil(t, err)
+	assert.Len(t, forks, 2)
+	assert.Equal(t, fork.ID, forks[0].ID)
+
+	_, err = c.Fork(ctx, "missing", "")
+	assert.True(t, errors.Is(err, md.ErrNotFound))
+}
+
+// Test_FilesZip
+// Files are served raw, and the snippet downloads as a zip archive.
+func Test_FilesZip(t *testing.T) {
+	c, _ := SetupClient(t)
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, &md.CreateMDReq{Title: "Go", Body: "# Go", Files: []md.SnippetFile{{Name: "main.go", Content: "package main"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, 12, created.Files[0].Size)
+
+	content, err := c.File(ctx, created.ID, "main.go")
+	assert.Nil(t, err)
+	assert.Equal(t, "package main", content)
+	content, err = c.File(ctx, created.ID, md.BodyFileName)
+	assert.Nil(t, err)
+	assert.Equal(t, "# Go", content)
+	_, err = c.File(ctx, created.ID, "missing.go")
+	assert.True(t, errors.Is(err, md.ErrFileNotFound))
+
+	archive, err := c.Zip(ctx, created.ID)
+	assert.Nil(t, err)
+	defer archive.Close()
+	data, err := io.ReadAll(archive)
+	assert.Nil(t, err)
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+	assert.Equal(t, md.BodyFileName, reader.File[0].Name)
+	assert.Equal(t, "main.go", reader.File[1].Name)
+}
+
+// Test_Attachments
+// Attachments upload as multipart forms and are listed, served and deleted.
+func Test_Attachments(t *testing.T) {
+	c, server := SetupClient(t)
+	ctx := context.Background()
+	server.Put(&md.MarkdownSnippet{ID: "abc", Title: "T", Body: "B", UpdateKey: "secret"})
+
+	server.FailNext(http.StatusTooManyRequests)
+	uploaded, err := c.UploadAttachment(ctx, "abc", "secret", "notes.txt", strings.NewReader("hello"))
+	assert.Nil(t, err)
+	assert.Equal(t, "notes.txt", uploaded.Name)
+	assert.Equal(t, "text/plain; charset=utf-8", uploaded.ContentType)
+	assert.Equal(t, int64(5), uploaded.Size)
+
+	_, err = c.UploadAttachment(ctx, "abc", "secret", "notes.txt", strings.NewReader("again"))
+	assert.True(t, errors.Is(err, md.ErrConflict))
+	_, err = c.UploadAttachment(ctx, "abc", "wrong", "other.txt", strings.NewReader("x"))
+	assert.True(t, errors.Is(err, md.ErrInvalidKey))
+
+	attachments, err := c.Attachments(ctx, "abc")
+	assert.Nil(t, err)
+	assert.Equal(t, []md.Attachment{*uploaded}, attachments)
+
+	content, err := c.Attachment(ctx, "abc", "notes.txt")
+	assert.Nil(t, err)
+	data, _ := io.ReadAll(content)
+	content.Close()
+	assert.Equal(t, "hello", string(data))
+
+	assert.Nil(t, c.DeleteAttachment(ctx, "abc", "secret", "notes.txt"))
+	_, err = c.Attachment(ctx, "abc", "notes.txt")
+	assert.True(t, errors.Is(err, md.ErrAttachmentNotFound))
+	assert.False(t, errors.Is(err, md.ErrFileNotFound))
+}
@@ -0,0 +1,771 @@
+// Package clienttest provides an in-memory MDSnips API for testing clients.
+package clienttest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/md"
+)
+
+// Credentials accepted by the Server.
+const (
+	User        = "user"
+	Pass        = "pass"
+	BearerToken = "token"
+)
+
+// Server
+// httptest.Server implementing the md routes over an in-memory store,
+// answering errors as api.ErrorResponse like the real API.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	snippets    map[string]*md.MarkdownSnippet
+	trash       map[string]*md.MarkdownSnippet
+	revisions   map[string][]md.Revision
+	attachments map[string]map[string]*attachment
+	requests    []*http.Request
+	failures    []int
+	nextID      int
+}
+
+// attachment
+// An uploaded attachment and its content.
+type attachment struct {
+	md.Attachment
+	content []byte
+}
+
+// NewServer
+// Starts a Server accepting basic auth User/Pass or BearerToken.
+// The caller should call Close when finished.
+func NewServer() *Server {
+	s := &Server{
+		snippets:  map[string]*md.MarkdownSnippet{},
+		trash:     map[string]*md.MarkdownSnippet{},
+		revisions: map[string][]md.Revision{},
+		// Attachments of each snippet, by name.
+		attachments: map[string]map[string]*attachment{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Put
+// Stores snippet as if it had been created through the API,
+// saving its revision when snippet.Revision is set.
+func (s *Server) Put(snippet *md.MarkdownSnippet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *snippet
+	s.snippets[snippet.ID] = &copied
+	if copied.Revision > 0 {
+		s.saveRevision(&copied, md.SourceCreate)
+	}
+}
+
+// Snippet
+// Returns a copy of stored snippet id, nil when missing.
+func (s *Server) Snippet(id string) *md.MarkdownSnippet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snippet, ok := s.snippets[id]
+	if !ok {
+		return nil
+	}
+	copied := *snippet
+	return &copied
+}
+
+// Len
+// Returns the number of stored snippets, excluding the trash.
+func (s *Server) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.snippets)
+}
+
+// FailNext
+// Answers the next len(statuses) requests with statuses, in order.
+func (s *Server) FailNext(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statuses...)
+}
+
+// Requests
+// Returns every request received, including failed ones.
+func (s *Server) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+
+	if len(s.failures) > 0 {
+		status := s.failures[0]
+		s.failures = s.failures[1:]
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		problem(w, r, status, "")
+		return
+	}
+	if !authorized(r) {
+		problem(w, r, http.StatusUnauthorized, "")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/md/")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/md":
+		s.create(w, r)
+	case r.Method == http.MethodPatch && r.URL.Path == "/md":
+		s.update(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/md/search":
+		s.search(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/md/import":
+		s.importLines(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/md/export":
+		s.export(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/md":
+		writeJSON(w, http.StatusOK, s.list())
+	case r.Method == http.MethodPost && strings.HasSuffix(id, "/restore"):
+		s.restore(w, r, strings.TrimSuffix(id, "/restore"))
+	case r.Method == http.MethodPost && strings.HasSuffix(id, "/fork"):
+		s.fork(w, r, strings.TrimSuffix(id, "/fork"))
+	case r.Method == http.MethodGet && strings.HasSuffix(id, "/forks"):
+		s.forks(w, r, strings.TrimSuffix(id, "/forks"))
+	case r.Method == http.MethodPost && strings.HasSuffix(id, "/attachments"):
+		s.uploadAttachment(w, r, strings.TrimSuffix(id, "/attachments"))
+	case r.Method == http.MethodGet && strings.HasSuffix(id, "/attachments"):
+		s.listAttachments(w, r, strings.TrimSuffix(id, "/attachments"))
+	case r.Method == http.MethodGet && strings.Contains(id, "/attachments/"):
+		parts := strings.SplitN(id, "/attachments/", 2)
+		s.getAttachment(w, r, parts[0], parts[1])
+	case r.Method == http.MethodDelete && strings.Contains(id, "/attachments/"):
+		parts := strings.SplitN(id, "/attachments/", 2)
+		s.deleteAttachment(w, r, parts[0], parts[1])
+	case r.Method == http.MethodGet && strings.Contains(id, "/files/"):
+		parts := strings.SplitN(id, "/files/", 2)
+		s.file(w, r, parts[0], parts[1])
+	case r.Method == http.MethodGet && strings.HasSuffix(id, "/zip"):
+		s.zip(w, r, strings.TrimSuffix(id, "/zip"))
+	case r.Method == http.MethodGet && strings.HasSuffix(id, "/revisions"):
+		s.listRevisions(w, r, strings.TrimSuffix(id, "/revisions"))
+	case r.Method == http.MethodGet && strings.Contains(id, "/revisions/"):
+		parts := strings.SplitN(id, "/revisions/", 2)
+		s.getRevision(w, r, parts[0], parts[1])
+	case r.Method == http.MethodGet && strings.HasSuffix(id, "/diff"):
+		s.diff(w, r, strings.TrimSuffix(id, "/diff"))
+	case r.Method == http.MethodPatch && id != r.URL.Path:
+		s.patch(w, r, id)
+	case r.Method == http.MethodGet && id != r.URL.Path:
+		snippet, ok := s.snippets[id]
+		if !ok {
+			problem(w, r, http.StatusNotFound, "Markdown Snippet Not Found")
+			return
+		}
+		writeJSON(w, http.StatusOK, snippet)
+	case r.Method == http.MethodDelete && id != r.URL.Path:
+		req := new(md.DeleteMDReq)
+		json.NewDecoder(r.Body).Decode(req)
+		if !s.checkKey(w, r, id, req.UpdateKey) {
+			return
+		}
+		s.trash[id] = s.snippets[id]
+		delete(s.snippets, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		problem(w, r, http.StatusNotFound, "Cannot "+r.Method+" "+r.URL.Path)
+	}
+}
+
+func (s *Server) restore(w http.ResponseWriter, r *http.Request, id string) {
+	req := new(md.RestoreMDReq)
+	json.NewDecoder(r.Body).Decode(req)
+	snippet, ok := s.trash[id]
+	switch {
+	case !ok:
+		problem(w, r, http.StatusNotFound, "Markdown Snippet Not Found")
+	case snippet.UpdateKey != req.UpdateKey:
+		problem(w, r, http.StatusUnauthorized, "Invalid Update Key")
+	default:
+		delete(s.trash, id)
+		s.snippets[id] = snippet
+		copied := *snippet
+		copied.UpdateKey = ""
+		writeJSON(w, http.StatusOK, &copied)
+	}
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	req := new(md.CreateMDReq)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errs := api.ValidateStruct(req); errs != nil {
+		validationProblem(w, r, errs)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, s.insert(req))
+}
+
+// insert
+// Stores a new snippet created from req.
+func (s *Server) insert(req *md.CreateMDReq) *md.MarkdownSnippet {
+	s.nextID++
+	snippet := &md.MarkdownSnippet{
+		ID:         "snippet-" + strconv.Itoa(s.nextID),
+		Title:      req.Title,
+		Body:       req.Body,
+		Files:      sizeFiles(req.Files),
+		UpdateKey:  "key-" + strconv.Itoa(s.nextID),
+		CreateDate: time.Now().UTC(),
+		Revision:   1,
+	}
+	s.snippets[snippet.ID] = snippet
+	s.saveRevision(snippet, md.SourceCreate)
+	return snippet
+}
+
+// importLines
+// Imports JSON lines, archives are not supported.
+func (s *Server) importLines(w http.ResponseWriter, r *http.Request) {
+	response := &md.ImportResponse{Results: []*md.ImportResult{}}
+	decoder := json.NewDecoder(r.Body)
+	for item := 1; decoder.More(); item++ {
+		req := new(md.CreateMDReq)
+		result := &md.ImportResult{Item: item}
+		response.Results = append(response.Results, result)
+		if err := decoder.Decode(req); err != nil {
+			problem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errs := api.ValidateStruct(req); errs != nil {
+			result.Error, result.Errors = errs.Error(), errs
+			response.Failed++
+			continue
+		}
+		snippet := s.insert(req)
+		result.ID, result.UpdateKey = snippet.ID, snippet.UpdateKey
+		response.Created++
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// export
+// Writes every snippet as JSON lines, zip is not supported.
+func (s *Server) export(w http.ResponseWriter, r *http.Request) {
+	if format := r.URL.Query().Get("format"); format != "" && format != md.ExportJSONL {
+		problem(w, r, http.StatusBadRequest, "clienttest only exports jsonl")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	for _, item := range s.list() {
+		snippet := *s.snippets[item.ID]
+		snippet.UpdateKey = ""
+		encoder.Encode(snippet)
+	}
+}
+
+func (s *Server) update(w http.ResponseWriter, r *http.Request) {
+	req := new(md.UpdateMDReq)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errs := api.ValidateStruct(req); errs != nil {
+		validationProblem(w, r, errs)
+		return
+	}
+	if !s.checkKey(w, r, req.ID, req.UpdateKey) {
+		return
+	}
+
+	snippet := s.snippets[req.ID]
+	snippet.Title, snippet.Body = req.Title, req.Body
+	if req.Files != nil {
+		snippet.Files = sizeFiles(req.Files)
+	}
+	snippet.Revision++
+	s.saveRevision(snippet, md.SourceUpdate)
+	writeJSON(w, http.StatusOK, snippet)
+}
+
+// patch
+// Applies the patch of the request Content-Type to snippet id.
+func (s *Server) patch(w http.ResponseWriter, r *http.Request, id string) {
+	if !s.checkKey(w, r, id, r.Header.Get("X-Update-Key")) {
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var patch md.Patch
+	switch strings.TrimSpace(strings.SplitN(r.Header.Get("Content-Type"), ";", 2)[0]) {
+	case md.MergePatchType:
+		patch, err = md.ParseMergePatch(body)
+	case md.DiffType, md.PatchType:
+		patch, err = md.ParseUnifiedDiff(string(body))
+	case md.LineEditsType:
+		edits := new(md.LineEdits)
+		err = json.Unmarshal(body, edits)
+		patch = edits
+	default:
+		problem(w, r, http.StatusUnsupportedMediaType, "")
+		return
+	}
+	if err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	patched := *s.snippets[id]
+	if err := patch.Apply(&patched); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, md.ErrConflict) {
+			status = http.StatusConflict
+		}
+		problem(w, r, status, err.Error())
+		return
+	}
+	patched.Revision++
+	s.snippets[id] = &patched
+	s.saveRevision(&patched, md.SourcePatch)
+	response := patched
+	response.UpdateKey = ""
+	writeJSON(w, http.StatusOK, &response)
+}
+
+func (s *Server) fork(w http.ResponseWriter, r *http.Request, id string) {
+	req := new(md.ForkMDReq)
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			problem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if errs := api.ValidateStruct(req); errs != nil {
+		validationProblem(w, r, errs)
+		return
+	}
+	parent, ok := s.snippets[id]
+	if !ok {
+		problem(w, r, http.StatusNotFound, "Markdown Snippet Not Found")
+		return
+	}
+	if req.Title == "" {
+		req.Title = parent.Title
+	}
+
+	fork := s.insert(&md.CreateMDReq{Title: req.Title, Body: parent.Body})
+	fork.Files = parent.Files
+	fork.ForkedFrom = parent.ID
+	fork.Lineage = append([]md.ForkRef{{ID: parent.ID, Revision: parent.Revision}}, parent.Lineage...)
+	s.revisions[fork.ID][0].Source = md.SourceFork
+	writeJSON(w, http.StatusCreated, fork)
+}
+
+// forks
+// Lists the snippets forked from id, oldest first.
+func (s *Server) forks(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := s.snippets[id]; !ok {
+		problem(w, r, http.StatusNotFound, "Markdown Snippet Not Found")
+		return
+	}
+	forks := []md.MDListItem{}
+	items := s.list()
+	for i := len(items) - 1; i >= 0; i-- {
+		if items[i].ForkedFrom == id {
+			forks = append(forks, items[i])
+		}
+	}
+	writeJSON(w, http.StatusOK, forks)
+}
+
+// sizeFiles
+// Returns a copy of files with their sizes set,
+// languages are not detected. nil stays nil.
+func sizeFiles(files []md.SnippetFile) []md.SnippetFile {
+	if files == nil {
+		return nil
+	}
+	sized := make([]md.SnippetFile, len(files))
+	for i, file := range files {
+		file.Size = len(file.Content)
+		sized[i] = file
+	}
+	return sized
+}
+
+// file
+// Serves the named file of snippet id, the body as md.BodyFileName.
+func (s *Server) file(w http.ResponseWriter, r *http.Request, id string, name string) {
+	snippet, ok := s.snippets[id]
+	if !ok {
+		problem(w, r, http.StatusNotFound, "Markdown Snippet Not Found")
+		return
+	}
+	content, found := snippet.Body, name == md.BodyFileName
+	for _, file := range snippet.Files {
+		if file.Name == name {
+			content, found = file.Content, true
+		}
+	}
+	if !found {
+		problem(w, r, http.StatusNotFound, "Snippet File Not Found")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, content)
+}
+
+func (s *Server) zip(w http.ResponseWriter, r *http.Request, id string) {
+	snippet, ok := s.snippets[id]
+	if !ok {
+		problem(w, r, http.StatusNotFound, "Markdown Snippet Not Found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+snippet.ID+`.zip"`)
+	md.WriteSnippetZip(w, snippet)
+}
+
+// uploadAttachment
+// Stores the multipart file upload, size limits are not enforced.
+func (s *Server) uploadAttachment(w http.ResponseWriter, r *http.Request, id string) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		problem(w, r, http.StatusBadRequest, "file: required")
+		return
+	}
+	defer file.Close()
+	req := &md.UploadAttachmentReq{UpdateKey: r.FormValue("updateKey"), Name: r.FormValue("name")}
+	if req.Name == "" {
+		req.Name = header.Filename
+	}
+	if errs := api.ValidateStruct(req); errs != nil {
+		validationProblem(w, r, errs)
+		return
+	}
+	if !s.checkKey(w, r, id, req.UpdateKey) {
+		return
+	}
+	if _, ok := s.attachments[id][req.Name]; ok {
+		problem(w, r, http.StatusConflict, "Markdown Snippet Conflict")
+		return
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	sum := sha256.Sum256(content)
+	stored := &attachment{
+		Attachment: md.Attachment{
+			Name:        req.Name,
+			ContentType: http.DetectContentType(content),
+			Size:        int64(len(content)),
+			SHA256:      hex.EncodeToString(sum[:]),
+			UploadDate:  time.Now().UTC(),
+		},
+		content: content,
+	}
+	if s.attachments[id] == nil {
+		s.attachments[id] = map[string]*attachment{}
+	}
+	s.attachments[id][req.Name] = stored
+	w.Header().Set("Location", r.URL.EscapedPath()+"/"+url.PathEscape(req.Name))
+	writeJSON(w, http.StatusCreated, &stored.Attachment)
+}
+
+func (s *Server) listAttachments(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := s.snippets[id]; !ok {
+		problem(w, r, http.StatusNotFound, "Markdown Snippet Not Found")
+		return
+	}
+	attachments := []md.Attachment{}
+	for _, stored := range s.attachments[id] {
+		attachments = append(attachments, stored.Attachment)
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].Name < attachments[j].Name })
+	writeJSON(w, http.StatusOK, attachments)
+}
+
+// getAttachment
+// Serves an attachment, as a download unless it is an image.
+func (s *Server) getAttachment(w http.ResponseWriter, r *http.Request, id string, name string) {
+	if _, ok := s.snippets[id]; !ok {
+		problem(w, r, http.StatusNotFound, "Markdown Snippet Not Found")
+		return
+	}
+	stored, ok := s.attachments[id][name]
+	if !ok {
+		problem(w, r, http.StatusNotFound, "Snippet Attachment Not Found")
+		return
+	}
+	w.Header().Set("Content-Type", stored.ContentType)
+	w.Header().Set("ETag", `"`+stored.SHA256+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if !strings.HasPrefix(stored.ContentType, "image/") {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	}
+	w.Write(stored.content)
+}
+
+func (s *Server) deleteAttachment(w http.ResponseWriter, r *http.Request, id string, name string) {
+	req := new(md.DeleteMDReq)
+	json.NewDecoder(r.Body).Decode(req)
+	if !s.checkKey(w, r, id, req.UpdateKey) {
+		return
+	}
+	if _, ok := s.attachments[id][name]; !ok {
+		problem(w, r, http.StatusNotFound, "Snippet Attachment Not Found")
+		return
+	}
+	delete(s.attachments[id], name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// saveRevision
+// Records the current state of snippet as a revision saved by source.
+func (s *Server) saveRevision(snippet *md.MarkdownSnippet, source string) {
+	s.revisions[snippet.ID] = append(s.revisions[snippet.ID], md.Revision{
+		SnippetID:  snippet.ID,
+		Revision:   snippet.Revision,
+		Title:      snippet.Title,
+		Body:       snippet.Body,
+		Files:      snippet.Files,
+		Source:     source,
+		CreateDate: time.Now().UTC(),
+	})
+}
+
+// revision
+// Returns revision rev of snippet id, the snippet itself for
+// md.CurrentRevision, and nil when either is missing.
+func (s *Server) revision(id string, rev int64) *md.Revision {
+	if rev == md.CurrentRevision {
+		snippet, ok := s.snippets[id]
+		if !ok {
+			return nil
+		}
+		return &md.Revision{SnippetID: id, Revision: snippet.Revision, Title: snippet.Title, Body: snippet.Body}
+	}
+	if _, ok := s.snippets[id]; !ok {
+		return nil
+	}
+	for _, revision := range s.revisions[id] {
+		if revision.Revision == rev {
+			return &revision
+		}
+	}
+	return nil
+}
+
+func (s *Server) listRevisions(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := s.snippets[id]; !ok {
+		problem(w, r, http.StatusNotFound, "Markdown Snippet Not Found")
+		return
+	}
+	revisions := []md.Revision{}
+	for i := len(s.revisions[id]) - 1; i >= 0; i-- {
+		revision := s.revisions[id][i]
+		revision.Body = ""
+		revisions = append(revisions, revision)
+	}
+	writeJSON(w, http.StatusOK, revisions)
+}
+
+func (s *Server) getRevision(w http.ResponseWriter, r *http.Request, id string, value string) {
+	rev, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || rev < 1 {
+		problem(w, r, http.StatusBadRequest, "revision: invalid value")
+		return
+	}
+	revision := s.revision(id, rev)
+	if revision == nil {
+		problem(w, r, http.StatusNotFound, "Markdown Snippet Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, revision)
+}
+
+// diff
+// Compares revisions as JSON, other formats are not supported.
+func (s *Server) diff(w http.ResponseWriter, r *http.Request, id string) {
+	query := r.URL.Query()
+	if format := query.Get("format"); format != "" && format != "json" {
+		problem(w, r, http.StatusBadRequest, "clienttest only diffs as json")
+		return
+	}
+	params := md.DiffParams{Context: md.DefaultDiffContext, Words: query.Get("words") == "true"}
+	if value := query.Get("context"); value != "" {
+		var err error
+		if params.Context, err = strconv.Atoi(value); err != nil || params.Context < 0 || params.Context > 100 {
+			problem(w, r, http.StatusBadRequest, "context: must be between 0 and 100")
+			return
+		}
+	}
+	for name, target := range map[string]*int64{"from": &params.From, "to": &params.To} {
+		switch value := query.Get(name); value {
+		case "":
+		case "current":
+			*target = md.CurrentRevision
+		default:
+			rev, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || rev < 1 {
+				problem(w, r, http.StatusBadRequest, name+": invalid value")
+				return
+			}
+			*target = rev
+		}
+	}
+
+	if params.To == 0 {
+		params.To = md.CurrentRevision
+	}
+	to := s.revision(id, params.To)
+	from := &md.Revision{SnippetID: id}
+	if params.From == 0 && to != nil && to.Revision > 1 {
+		params.From = to.Revision - 1
+	}
+	if params.From != 0 {
+		from = s.revision(id, params.From)
+	}
+	if to == nil || from == nil {
+		problem(w, r, http.StatusNotFound, "Markdown Snippet Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, md.CompareRevisions(from, to, params))
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit == 0 {
+		limit = 10
+	}
+	skip, _ := strconv.Atoi(query.Get("skip"))
+	text := strings.ToLower(query.Get("text"))
+
+	items := s.list()
+	if query.Get("sort") == string(md.CreateDate_ASC) {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].CreateDate.Before(items[j].CreateDate) })
+	}
+	matched := []md.MDListItem{}
+	for _, item := range items {
+		if text == "" || strings.Contains(strings.ToLower(item.Title+" "+s.snippets[item.ID].Body), text) {
+			matched = append(matched, item)
+		}
+	}
+	if skip > len(matched) {
+		skip = len(matched)
+	}
+	matched = matched[skip:]
+	if limit < len(matched) {
+		matched = matched[:limit]
+	}
+	writeJSON(w, http.StatusOK, matched)
+}
+
+// list
+// Returns every snippet, newest first.
+func (s *Server) list() []md.MDListItem {
+	items := []md.MDListItem{}
+	for _, snippet := range s.snippets {
+		items = append(items, md.MDListItem{ID: snippet.ID, Title: snippet.Title, CreateDate: snippet.CreateDate, ForkedFrom: snippet.ForkedFrom})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].CreateDate.Equal(items[j].CreateDate) {
+			return items[i].ID > items[j].ID
+		}
+		return items[i].CreateDate.After(items[j].CreateDate)
+	})
+	return items
+}
+
+// checkKey
+// Writes the API error when snippet id is missing
+// or updateKey does not match. Reports whether it matched.
+func (s *Server) checkKey(w http.ResponseWriter, r *http.Request, id, updateKey string) bool {
+	snippet, ok := s.snippets[id]
+	if !ok {
+		problem(w, r, http.StatusNotFound, "Markdown Snippet Not Found")
+		return false
+	}
+	if snippet.UpdateKey != updateKey {
+		problem(w, r, http.StatusUnauthorized, "Invalid Update Key")
+		return false
+	}
+	return true
+}
+
+// authorized
+// Reports whether r carries the basic auth or bearer credentials.
+func authorized(r *http.Request) bool {
+	if user, pass, ok := r.BasicAuth(); ok {
+		return user == User && pass == Pass
+	}
+	return r.Header.Get("Authorization") == "Bearer "+BearerToken
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// problem
+// Writes an api.ErrorResponse the way api.ErrorHandler does.
+func problem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	w.Header().Set("Content-Type", api.ProblemContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(api.ErrorResponse{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.RequestURI(),
+	})
+}
+
+func validationProblem(w http.ResponseWriter, r *http.Request, errs api.ValidationErrors) {
+	w.Header().Set("Content-Type", api.ProblemContentType)
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(api.ErrorResponse{
+		Type:     "about:blank",
+		Title:    http.StatusText(http.StatusBadRequest),
+		Status:   http.StatusBadRequest,
+		Detail:   errs.Error(),
+		Instance: r.URL.RequestURI(),
+		Errors:   errs,
+	})
+}
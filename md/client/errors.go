@@ -0,0 +1,72 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/md"
+)
+
+// Error
+// Problem details returned by the API for a failed request.
+// errors.Is matches md.ErrNotFound, md.ErrFileNotFound, md.ErrInvalidKey,
+// md.ErrConflict and the attachment errors against the corresponding responses.
+type Error struct {
+	api.ErrorResponse
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%d %s", e.Status, e.Title)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	for _, field := range e.Errors {
+		msg += fmt.Sprintf("\n  %s failed `%s` validation", field.FailedField, field.Tag)
+	}
+	return msg
+}
+
+// Is
+// Maps response statuses onto md domain errors.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case md.ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case md.ErrFileNotFound:
+		return e.Status == http.StatusNotFound && e.Detail == "Snippet File Not Found"
+	case md.ErrAttachmentNotFound:
+		return e.Status == http.StatusNotFound && e.Detail == "Snippet Attachment Not Found"
+	case md.ErrAttachmentTooLarge:
+		return e.Status == http.StatusRequestEntityTooLarge && e.Detail == "Attachment Too Large"
+	case md.ErrQuotaExceeded:
+		return e.Status == http.StatusRequestEntityTooLarge && e.Detail == "Attachment Quota Exceeded"
+	case md.ErrUnsupportedType:
+		return e.Status == http.StatusUnsupportedMediaType
+	case md.ErrConflict:
+		return e.Status == http.StatusConflict
+	case md.ErrInvalidKey:
+		return e.Status == http.StatusUnauthorized && e.Detail == "Invalid Update Key"
+	}
+	return false
+}
+
+// decodeError
+// Reads the problem details of resp and closes its body.
+// Responses that are not problem JSON keep their status.
+func decodeError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+	apiErr := &Error{api.ErrorResponse{
+		Status: resp.StatusCode,
+		Title:  http.StatusText(resp.StatusCode),
+	}}
+
+	content, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var problem api.ErrorResponse
+	if json.Unmarshal(content, &problem) == nil && problem.Status != 0 {
+		apiErr.ErrorResponse = problem
+	}
+	return apiErr
+}
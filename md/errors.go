@@ -0,0 +1,55 @@
+package md
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is returned when a snippet does not exist.
+	ErrNotFound = errors.New("markdown snippet not found")
+	// ErrInvalidKey is returned when an update key does not match the snippet.
+	ErrInvalidKey = errors.New("invalid update key")
+	// ErrConflict is returned when a write conflicts with existing state.
+	ErrConflict = errors.New("markdown snippet conflict")
+	// ErrInvalidPatch is returned for patches that cannot be parsed or applied.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrInvalidRevision is returned for base revisions a snippet does not have.
+	ErrInvalidRevision = errors.New("invalid base revision")
+	// ErrFileNotFound is returned when a snippet has no file of the requested name.
+	ErrFileNotFound = errors.New("snippet file not found")
+	// ErrAttachmentNotFound is returned when a snippet has no attachment of the requested name.
+	ErrAttachmentNotFound = errors.New("snippet attachment not found")
+	// ErrAttachmentTooLarge is returned for uploads over the attachment size limit.
+	ErrAttachmentTooLarge = errors.New("attachment too large")
+	// ErrQuotaExceeded is returned for uploads that would exceed a snippet's attachment quota.
+	ErrQuotaExceeded = errors.New("attachment quota exceeded")
+	// ErrUnsupportedType is returned for uploads of a content type that is not allowed.
+	ErrUnsupportedType = errors.New("unsupported attachment type")
+)
+
+// SnippetError
+// Records the operation and snippet id an error occurred on.
+// Use errors.Is to test for the underlying sentinel error.
+type SnippetError struct {
+	Op  string
+	ID  string
+	Err error
+}
+
+func (e *SnippetError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("%s: %s", e.Op, e.Err)
+	}
+	return fmt.Sprintf("%s %s: %s", e.Op, e.ID, e.Err)
+}
+
+func (e *SnippetError) Unwrap() error {
+	return e.Err
+}
+
+// snippetError
+// Wraps err with the operation and snippet id.
+func snippetError(op string, id string, err error) error {
+	return &SnippetError{Op: op, ID: id, Err: err}
+}
@@ -14,6 +14,20 @@ type MarkdownSnippet struct {
 	UpdateKey string `json:"updateKey,omitempty" bson:"updateKey" format:"uuid"`
 	// Date markdown snippet was created.
 	CreateDate time.Time `json:"createDate,omitempty" bson:"createDate" format:"date-time"`
+	// Latest saved revision number, starting at 1.
+	Revision int64 `json:"revision,omitempty" bson:"revision,omitempty" example:"1"`
+	// Named files beside the markdown body.
+	Files []SnippetFile `json:"files,omitempty" bson:"files,omitempty"`
+	// Date the snippet was deleted, unset unless it is in the trash.
+	DeletedAt *time.Time `json:"deletedAt,omitempty" bson:"deletedAt,omitempty" format:"date-time"`
+	// Guid of the snippet this was forked from, unset for originals.
+	ForkedFrom string `json:"forkedFrom,omitempty" bson:"forkedFrom,omitempty" format:"uuid"`
+	// Snippets this descends from, its parent first, each at the revision forked.
+	Lineage []ForkRef `json:"lineage,omitempty" bson:"lineage,omitempty"`
+	// Owning tenant, only set with filter tenancy.
+	TenantID string `json:"-" bson:"tenantId,omitempty"`
+	// Blob store key of a body too large to keep in Mongo.
+	BodyRef string `json:"-" bson:"bodyRef,omitempty"`
 }
 
 // MDListItem
@@ -24,6 +38,30 @@ type MDListItem struct {
 	Title string `json:"title" bson:"title" example:"SouLxBurN Is Awesome!"`
 	// Date markdown snippet was created
 	CreateDate time.Time `json:"createDate,omitempty" bson:"createDate" format:"date-time"`
+	// Date the snippet was deleted, only listed for the trash.
+	DeletedAt *time.Time `json:"deletedAt,omitempty" bson:"deletedAt,omitempty" format:"date-time"`
+	// Guid of the snippet this was forked from, unset for originals.
+	ForkedFrom string `json:"forkedFrom,omitempty" bson:"forkedFrom,omitempty" format:"uuid"`
+}
+
+// SnippetFile
+type SnippetFile struct {
+	// File name, unique within the snippet. `README.md` is the snippet body.
+	Name string `json:"name" bson:"name" validate:"required,max=128,excludesall=/\\,ne=.,ne=..,ne=README.md" maxLength:"128" example:"deploy.sh"`
+	// Syntax highlighting language, detected from the name when empty.
+	Language string `json:"language,omitempty" bson:"language,omitempty" validate:"max=32" maxLength:"32" example:"shell"`
+	// File content.
+	Content string `json:"content" bson:"content" validate:"required,max=128000" minLength:"1" maxLength:"128000" example:"#!/bin/sh\necho deploy"`
+	// Content size in bytes, set by the server.
+	Size int `json:"size" bson:"size" example:"24"`
+}
+
+// ForkRef
+type ForkRef struct {
+	// Markdown snippet guid.
+	ID string `json:"id" bson:"id" format:"uuid"`
+	// Revision of the snippet when it was forked.
+	Revision int64 `json:"revision" bson:"revision" example:"3"`
 }
 
 // CreateMDReq
@@ -32,6 +70,8 @@ type CreateMDReq struct {
 	Title string `json:"title" validate:"required,min=1,max=64" minLength:"1" maxLength:"64" example:"SouLxBurN Is Awesome!"`
 	// Markdown body to save.
 	Body string `json:"body" validate:"required,min=1,max=64000" minLength:"1" maxLength:"64000" example:"# Markdown Snippet\nSome Text"`
+	// Named files beside the body. Omitted files are left unchanged by updates.
+	Files []SnippetFile `json:"files,omitempty" validate:"max=20,unique=Name,dive"`
 }
 
 // UpdateMDReq
@@ -41,6 +81,9 @@ type UpdateMDReq struct {
 	ID string `json:"id,omitempty" format:"uuid" validate:"required"`
 	// UpdateKey required for updating snippet.
 	UpdateKey string `json:"updateKey" format:"uuid" validate:"required"`
+	// Revision the update was edited from. When newer revisions were
+	// saved since, their changes are merged with the update.
+	BaseRevision int64 `json:"baseRevision,omitempty" validate:"min=0" example:"3"`
 }
 
 // DeleteMDReq
@@ -48,3 +91,24 @@ type DeleteMDReq struct {
 	// UpdateKey required for deleting snippet.
 	UpdateKey string `json:"updateKey" format:"uuid" validate:"required"`
 }
+
+// ForkMDReq
+type ForkMDReq struct {
+	// Title of the fork, defaults to the title of the forked snippet.
+	Title string `json:"title,omitempty" validate:"max=64" maxLength:"64" example:"My Runbook"`
+}
+
+// RestoreMDReq
+type RestoreMDReq struct {
+	// UpdateKey required for restoring a deleted snippet.
+	UpdateKey string `json:"updateKey" format:"uuid" validate:"required"`
+}
+
+// UploadAttachmentReq
+// Form fields sent beside an uploaded attachment.
+type UploadAttachmentReq struct {
+	// UpdateKey required for attaching files to a snippet.
+	UpdateKey string `form:"updateKey" validate:"required"`
+	// Attachment name, defaults to the uploaded file name.
+	Name string `form:"name" validate:"required,max=128,excludesall=/\\,ne=.,ne=.."`
+}
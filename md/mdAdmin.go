@@ -0,0 +1,296 @@
+package md
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/soulxburn/mdsnips/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// restoreBatchSize Number of upserts sent per bulk write when restoring.
+const restoreBatchSize = 500
+
+// CollectionStats
+// Storage statistics of the markdown collection.
+type CollectionStats struct {
+	Namespace      string  `bson:"ns" json:"namespace"`
+	Count          int64   `bson:"count" json:"count"`
+	Size           int64   `bson:"size" json:"size"`
+	AvgObjSize     float64 `bson:"avgObjSize" json:"avgObjSize"`
+	StorageSize    int64   `bson:"storageSize" json:"storageSize"`
+	IndexCount     int64   `bson:"nindexes" json:"indexCount"`
+	TotalIndexSize int64   `bson:"totalIndexSize" json:"totalIndexSize"`
+	// Snippets and title, body and file bytes in the service's tenant scope.
+	Snippets     int64 `bson:"-" json:"snippets"`
+	SnippetBytes int64 `bson:"-" json:"snippetBytes"`
+}
+
+// BackupSnippets
+// Streams every snippet in scope to w as JSON lines,
+// one relaxed extended JSON document per line, including update keys,
+// the bodies kept in the blob store and the snippet's revisions.
+// Attachments are not backed up.
+// Returns the number of snippets written.
+func (m *MDService) BackupSnippets(ctx context.Context, w io.Writer) (int64, error) {
+	mdCollection := m.getMarkdownCollection()
+	cursor, err := mdCollection.Find(ctx, m.scoped(bson.D{}), options.Find().SetProjection(bson.M{"_id": 0}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var count int64
+	for cursor.Next(ctx) {
+		doc, err := m.backupDocument(ctx, cursor.Current)
+		if err != nil {
+			return count, err
+		}
+		line, err := bson.MarshalExtJSON(doc, false, false)
+		if err != nil {
+			return count, err
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, cursor.Err()
+}
+
+// backupDocument
+// Returns the snippet document raw with a body kept in the blob
+// store read back in place of its reference, and its revisions,
+// bodies included, under revisions.
+func (m *MDService) backupDocument(ctx context.Context, raw bson.Raw) (bson.D, error) {
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	ref, _ := raw.Lookup("bodyRef").StringValueOK()
+	var body string
+	if err := m.loadBody(ctx, ref, &body); err != nil {
+		return nil, err
+	}
+	mdID, _ := raw.Lookup("id").StringValueOK()
+	revisions, err := m.backupRevisions(ctx, mdID)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := make(bson.D, 0, len(doc)+1)
+	for _, field := range doc {
+		switch {
+		case field.Key == "bodyRef":
+		case field.Key == "body" && ref != "":
+			backup = append(backup, bson.E{Key: "body", Value: body})
+		default:
+			backup = append(backup, field)
+		}
+	}
+	if len(revisions) > 0 {
+		backup = append(backup, bson.E{Key: "revisions", Value: revisions})
+	}
+	return backup, nil
+}
+
+// backupRevisions
+// Returns every revision of a snippet, oldest first, with
+// the bodies kept in the blob store read back.
+func (m *MDService) backupRevisions(ctx context.Context, mdID string) ([]*Revision, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "revision", Value: 1}})
+	cursor, err := m.revisions().Find(ctx, m.scoped(bson.D{{Key: "snippetId", Value: mdID}}), opts)
+	if err != nil {
+		return nil, err
+	}
+	var revisions []*Revision
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	for _, revision := range revisions {
+		if err := m.loadBody(ctx, revision.BodyRef, &revision.Body); err != nil {
+			return nil, err
+		}
+		revision.BodyRef, revision.TenantID = "", ""
+	}
+	return revisions, nil
+}
+
+// RestoreSnippets
+// Upserts every JSON line document read from r by snippet id, and
+// its revisions by number, so restoring the same backup twice leaves
+// one copy of each. Snippets are restored into the service's tenant
+// scope, large bodies into the blob store.
+// Returns the number of snippets restored.
+func (m *MDService) RestoreSnippets(ctx context.Context, r io.Reader) (int64, error) {
+	mdCollection := m.getMarkdownCollection()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+
+	var count int64
+	batch := make([]mongo.WriteModel, 0, restoreBatchSize)
+	var revisionBatch []mongo.WriteModel
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		// Revisions first, so a restored snippet always has them.
+		if len(revisionBatch) > 0 {
+			if _, err := m.revisions().BulkWrite(ctx, revisionBatch, options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+		}
+		if _, err := mdCollection.BulkWrite(ctx, batch, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+		count += int64(len(batch))
+		batch, revisionBatch = batch[:0], revisionBatch[:0]
+		return nil
+	}
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var doc bson.M
+		if err := bson.UnmarshalExtJSON(scanner.Bytes(), false, &doc); err != nil {
+			return count, fmt.Errorf("line %d: %w", line, err)
+		}
+		id, ok := doc["id"].(string)
+		if !ok || id == "" {
+			return count, fmt.Errorf("line %d: snippet is missing an id", line)
+		}
+		delete(doc, "_id")
+		delete(doc, "tenantId")
+		delete(doc, "purging")
+		if tenantID := m.filterTenantID(); tenantID != "" {
+			doc["tenantId"] = tenantID
+		}
+		delete(doc, "bodyRef")
+		revisions, err := m.restoreRevisions(ctx, id, doc["revisions"])
+		if err != nil {
+			return count, fmt.Errorf("line %d: %w", line, err)
+		}
+		revisionBatch = append(revisionBatch, revisions...)
+		delete(doc, "revisions")
+		if body, ok := doc["body"].(string); ok {
+			ref, err := m.storeBody(ctx, id, body)
+			if err != nil {
+				return count, fmt.Errorf("line %d: %w", line, err)
+			}
+			if ref != "" {
+				doc["body"], doc["bodyRef"] = "", ref
+			}
+		}
+
+		batch = append(batch, mongo.NewReplaceOneModel().
+			SetFilter(m.scoped(bson.D{{Key: "id", Value: id}})).
+			SetReplacement(doc).
+			SetUpsert(true))
+		if len(batch) == restoreBatchSize {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+	return count, flush()
+}
+
+// RebuildIndexes
+// Drops and recreates the markdown, fork and trash indexes, weighting
+// text index fields by weights, e.g. {"title": 10, "body": 1, "files.content": 1}.
+// language sets the text index default language, empty keeps `english`.
+func (m *MDService) RebuildIndexes(ctx context.Context, weights map[string]int32, language string) error {
+	mdCollection := m.getMarkdownCollection()
+	if _, err := mdCollection.Indexes().DropAll(ctx); err != nil && !isNamespaceNotFound(err) {
+		return err
+	}
+
+	indexes := IndexModels(m.tenancy.Mode == config.TenancyFilter)
+	textOptions := indexes[0].Options
+	if len(weights) > 0 {
+		textOptions.SetWeights(weights)
+	}
+	if language != "" {
+		textOptions.SetDefaultLanguage(language)
+	}
+	indexes = append(indexes, ForkIndexModels()...)
+	indexes = append(indexes, TrashIndexModels()...)
+
+	_, err := mdCollection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// CollectionStats
+// Returns storage statistics of the markdown collection
+// and snippet totals in the service's tenant scope.
+func (m *MDService) CollectionStats(ctx context.Context) (*CollectionStats, error) {
+	mdCollection := m.getMarkdownCollection()
+	stats := new(CollectionStats)
+	cmd := bson.D{{Key: "collStats", Value: mdCollection.Name()}}
+	if err := mdCollection.Database().RunCommand(ctx, cmd).Decode(stats); err != nil && !isNamespaceNotFound(err) {
+		return nil, err
+	}
+
+	snippets, err := m.collectStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.Snippets = snippets.Count
+	stats.SnippetBytes = snippets.Bytes
+	return stats, nil
+}
+
+// isNamespaceNotFound
+// Reports whether err is the server `NamespaceNotFound` error,
+// returned when the collection does not exist yet.
+func isNamespaceNotFound(err error) bool {
+	cmdErr, ok := err.(mongo.CommandError)
+	return ok && cmdErr.Code == 26
+}
+
+// Namespace
+// Returns the `database.collection` the service reads and writes.
+func (m *MDService) Namespace() string {
+	mdCollection := m.getMarkdownCollection()
+	return mdCollection.Database().Name() + "." + mdCollection.Name()
+}
+
+// restoreRevisions
+// Returns the upserts restoring the backed up revisions
+// of snippet mdID, nil for backups without revisions.
+func (m *MDService) restoreRevisions(ctx context.Context, mdID string, value interface{}) ([]mongo.WriteModel, error) {
+	if value == nil {
+		return nil, nil
+	}
+	raw, err := bson.Marshal(bson.M{"revisions": value})
+	if err != nil {
+		return nil, err
+	}
+	var backup struct {
+		Revisions []Revision `bson:"revisions"`
+	}
+	if err := bson.Unmarshal(raw, &backup); err != nil {
+		return nil, fmt.Errorf("revisions: %w", err)
+	}
+
+	models := make([]mongo.WriteModel, 0, len(backup.Revisions))
+	for _, revision := range backup.Revisions {
+		revision.SnippetID, revision.TenantID = mdID, m.filterTenantID()
+		if revision.BodyRef, err = m.storeBody(ctx, mdID, revision.Body); err != nil {
+			return nil, err
+		}
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(m.scoped(bson.D{{Key: "snippetId", Value: mdID}, {Key: "revision", Value: revision.Revision}})).
+			SetReplacement(revision.stored()).
+			SetUpsert(true))
+	}
+	return models, nil
+}
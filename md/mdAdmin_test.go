@@ -0,0 +1,87 @@
+package md
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/soulxburn/mdsnips/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_BackupRestore
+// Restoring a backup twice should leave one copy of
+// each snippet and its revisions, with update keys preserved.
+func Test_BackupRestore(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := context.Background()
+
+	created, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Backup", Body: "# Backup"})
+	assert.Nil(t, err)
+	_, err = mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Restore", Body: "# Restore"})
+	assert.Nil(t, err)
+	_, err = mdService.UpdateMarkdownSnippet(ctx, &UpdateMDReq{CreateMDReq: CreateMDReq{Title: "Backup", Body: "# Backup 2"}, ID: created.ID, UpdateKey: created.UpdateKey})
+	assert.Nil(t, err)
+
+	backup := new(bytes.Buffer)
+	count, err := mdService.BackupSnippets(ctx, backup)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.NotContains(t, backup.String(), `"_id"`)
+
+	assert.Nil(t, mdService.DeleteMarkdownSnippet(ctx, created.ID, created.UpdateKey))
+
+	for i := 0; i < 2; i++ {
+		count, err = mdService.RestoreSnippets(ctx, bytes.NewReader(backup.Bytes()))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), count)
+	}
+
+	snippets, err := mdService.GetAllMarkdownSnippets(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, snippets, 2)
+	assert.Nil(t, mdService.ValidateIdAndKey(ctx, created.ID, created.UpdateKey))
+
+	revisions, err := mdService.ListRevisions(ctx, created.ID)
+	assert.Nil(t, err)
+	assert.Len(t, revisions, 2)
+	revision, err := mdService.GetRevision(ctx, created.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "# Backup", revision.Body)
+}
+
+// Test_RestoreRevisions
+// Backed up revisions are upserted by snippet and number,
+// backups without revisions restore none.
+func Test_RestoreRevisions(t *testing.T) {
+	mdService := SetupUnreachableMDService(t, config.Timeouts{})
+	ctx := context.Background()
+
+	var doc bson.M
+	line := `{"id": "abc", "revisions": [{"snippetId": "other", "revision": 1, "title": "T", "body": "# 1", "source": "create"}]}`
+	assert.Nil(t, bson.UnmarshalExtJSON([]byte(line), false, &doc))
+	models, err := mdService.restoreRevisions(ctx, "abc", doc["revisions"])
+	assert.Nil(t, err)
+	assert.Len(t, models, 1)
+	revision := models[0].(*mongo.ReplaceOneModel).Replacement.(*Revision)
+	assert.Equal(t, "abc", revision.SnippetID)
+	assert.Equal(t, "# 1", revision.Body)
+
+	models, err = mdService.restoreRevisions(ctx, "abc", nil)
+	assert.Nil(t, err)
+	assert.Empty(t, models)
+}
+
+// Test_RestoreMissingID
+// Lines without a snippet id are rejected.
+func Test_RestoreMissingID(t *testing.T) {
+	mdService := SetupUnreachableMDService(t, config.Timeouts{})
+
+	_, err := mdService.RestoreSnippets(context.Background(), strings.NewReader(`{"title": "No ID"}`+"\n"))
+	assert.EqualError(t, err, "line 1: snippet is missing an id")
+}
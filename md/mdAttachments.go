@@ -0,0 +1,464 @@
+package md
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AttachmentsBucket GridFS bucket holding attachments,
+// beside the markdown collection of their snippet.
+// With a blob store only the files collection is used,
+// the content is kept in the store.
+const AttachmentsBucket = "attachments"
+
+// imageTypes Sniffed content types accepted when attachments are limited
+// to images. Only these are served inline, SVG is sniffed as text.
+var imageTypes = map[string]bool{
+	"image/bmp":    true,
+	"image/gif":    true,
+	"image/jpeg":   true,
+	"image/png":    true,
+	"image/webp":   true,
+	"image/x-icon": true,
+}
+
+// Attachment
+// A file uploaded to a snippet, such as an image it embeds.
+type Attachment struct {
+	// Attachment name, unique within the snippet.
+	Name string `json:"name" example:"diagram.png"`
+	// Content type sniffed from the uploaded bytes.
+	ContentType string `json:"contentType" example:"image/png"`
+	// Size in bytes.
+	Size int64 `json:"size" example:"20480"`
+	// Hex SHA-256 of the content, served as its ETag.
+	SHA256 string `json:"sha256"`
+	// Date the attachment was uploaded.
+	UploadDate time.Time `json:"uploadDate" format:"date-time"`
+}
+
+// attachmentFile
+// GridFS files collection document of an attachment.
+type attachmentFile struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Filename   string             `bson:"filename"`
+	Length     int64              `bson:"length"`
+	UploadDate time.Time          `bson:"uploadDate"`
+	Metadata   attachmentMetadata `bson:"metadata"`
+}
+
+// attachmentMetadata
+// Attachment fields stored in the GridFS file metadata.
+type attachmentMetadata struct {
+	SnippetID   string `bson:"snippetId"`
+	ContentType string `bson:"contentType"`
+	SHA256      string `bson:"sha256"`
+	// Empty unless tenants share the collection.
+	TenantID string `bson:"tenantId"`
+	// Key of the content in the blob store, empty when kept in GridFS chunks.
+	BlobKey string `bson:"blobKey,omitempty"`
+}
+
+// attachment
+// Returns the API representation of the file.
+func (f *attachmentFile) attachment() *Attachment {
+	return &Attachment{
+		Name:        f.Filename,
+		ContentType: f.Metadata.ContentType,
+		Size:        f.Length,
+		SHA256:      f.Metadata.SHA256,
+		UploadDate:  f.UploadDate,
+	}
+}
+
+// AttachmentIndexModels
+// Returns the index of the attachments bucket files collection
+// keeping attachment names unique within a snippet.
+// Applied to the configured database by migration 8.
+func AttachmentIndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "metadata.snippetId", Value: 1},
+				{Key: "metadata.tenantId", Value: 1},
+				{Key: "filename", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+}
+
+// withDefaultAttachments
+// Fills an unset attachment size limit with a 5 MiB default.
+func withDefaultAttachments(attachments config.Attachments) config.Attachments {
+	if attachments.MaxSize <= 0 {
+		attachments.MaxSize = 5 << 20
+	}
+	return attachments
+}
+
+// UploadAttachment
+// Stores content as a named attachment of a snippet.
+// Returns ErrNotFound or ErrInvalidKey when the update key is not valid,
+// ErrConflict when the snippet already has an attachment of that name,
+// ErrAttachmentTooLarge or ErrQuotaExceeded when content does not fit and
+// ErrUnsupportedType for content other than images when limited to them.
+func (m *MDService) UploadAttachment(ctx context.Context, mdID string, updateKey string, name string, content io.Reader) (*Attachment, error) {
+	if err := m.ValidateIdAndKey(ctx, mdID, updateKey); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, m.attachments.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > m.attachments.MaxSize {
+		return nil, snippetError("upload", mdID, ErrAttachmentTooLarge)
+	}
+	contentType := http.DetectContentType(data)
+	if m.attachments.ImagesOnly && !imageTypes[contentType] {
+		return nil, snippetError("upload", mdID, ErrUnsupportedType)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Write)
+	defer cancel()
+	ctx, end := startOperation(ctx, "upload")
+	defer end()
+
+	existing, err := m.findAttachments(ctx, mdID)
+	if err != nil {
+		recordMongoError(ctx, "upload", mdID, err)
+		return nil, err
+	}
+	used := int64(len(data))
+	for _, file := range existing {
+		if file.Filename == name {
+			return nil, snippetError("upload", mdID, ErrConflict)
+		}
+		used += file.Length
+	}
+	// Concurrent uploads may overrun the quota by one upload each.
+	if m.attachments.Quota > 0 && used > m.attachments.Quota {
+		return nil, snippetError("upload", mdID, ErrQuotaExceeded)
+	}
+
+	sum := sha256.Sum256(data)
+	metadata := attachmentMetadata{
+		SnippetID:   mdID,
+		ContentType: contentType,
+		SHA256:      hex.EncodeToString(sum[:]),
+		TenantID:    m.filterTenantID(),
+	}
+	fileID := primitive.NewObjectID()
+	if m.blobs != nil {
+		err = m.putAttachment(ctx, mdID, fileID, name, data, metadata)
+	} else {
+		err = m.uploadAttachment(ctx, mdID, fileID, name, data, metadata)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := m.findAttachment(ctx, mdID, name)
+	if err != nil {
+		return nil, err
+	}
+	return file.attachment(), nil
+}
+
+// uploadAttachment
+// Stores the content of an attachment in GridFS chunks.
+func (m *MDService) uploadAttachment(ctx context.Context, mdID string, fileID primitive.ObjectID, name string, data []byte, metadata attachmentMetadata) error {
+	bucket, err := m.attachmentBucket(ctx)
+	if err != nil {
+		return err
+	}
+	opts := options.GridFSUpload().SetMetadata(metadata)
+	if err := bucket.UploadFromStreamWithID(fileID, name, bytes.NewReader(data), opts); err != nil {
+		// Remove any chunks already written.
+		_ = bucket.Delete(fileID)
+		if mongo.IsDuplicateKeyError(err) {
+			return snippetError("upload", mdID, ErrConflict)
+		}
+		recordMongoError(ctx, "upload", mdID, err)
+		return err
+	}
+	return nil
+}
+
+// putAttachment
+// Stores the content of an attachment in the blob store
+// and records it in the files collection.
+func (m *MDService) putAttachment(ctx context.Context, mdID string, fileID primitive.ObjectID, name string, data []byte, metadata attachmentMetadata) error {
+	metadata.BlobKey = m.blobPrefix(mdID) + "attachments/" + fileID.Hex()
+	if err := m.blobs.Put(ctx, metadata.BlobKey, bytes.NewReader(data), int64(len(data))); err != nil {
+		return err
+	}
+	file := attachmentFile{
+		ID:         fileID,
+		Filename:   name,
+		Length:     int64(len(data)),
+		UploadDate: time.Now().UTC().Truncate(time.Millisecond),
+		Metadata:   metadata,
+	}
+	if _, err := m.attachmentFiles().InsertOne(ctx, file); err != nil {
+		_ = m.blobs.Delete(ctx, metadata.BlobKey)
+		if mongo.IsDuplicateKeyError(err) {
+			return snippetError("upload", mdID, ErrConflict)
+		}
+		recordMongoError(ctx, "upload", mdID, err)
+		return err
+	}
+	return nil
+}
+
+// ListAttachments
+// Returns the attachments of a snippet, ordered by name.
+// Returns ErrNotFound when the snippet does not exist.
+func (m *MDService) ListAttachments(ctx context.Context, mdID string) ([]Attachment, error) {
+	if err := m.snippetExists(ctx, mdID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Read)
+	defer cancel()
+	ctx, end := startOperation(ctx, "listAttachments")
+	defer end()
+
+	files, err := m.findAttachments(ctx, mdID)
+	if err != nil {
+		recordMongoError(ctx, "listAttachments", mdID, err)
+		return nil, err
+	}
+	attachments := make([]Attachment, len(files))
+	for i, file := range files {
+		attachments[i] = *file.attachment()
+	}
+	return attachments, nil
+}
+
+// OpenAttachment
+// Returns a named attachment of a snippet and a reader of its content,
+// which the caller must close. Reading may outlive ctx, up to the export timeout.
+// Returns ErrNotFound when the snippet does not exist,
+// or ErrAttachmentNotFound when it has no such attachment.
+func (m *MDService) OpenAttachment(ctx context.Context, mdID string, name string) (*Attachment, io.ReadCloser, error) {
+	if err := m.snippetExists(ctx, mdID); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Read)
+	defer cancel()
+	ctx, end := startOperation(ctx, "openAttachment")
+	defer end()
+
+	file, err := m.findAttachment(ctx, mdID, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if file.Metadata.BlobKey != "" {
+		if m.blobs == nil {
+			return nil, nil, errNoBlobStore
+		}
+		readCtx, cancelRead := context.WithTimeout(api.Detach(ctx), m.timeouts.Export)
+		content, err := m.blobs.Get(readCtx, file.Metadata.BlobKey)
+		if err != nil {
+			cancelRead()
+			return nil, nil, err
+		}
+		return file.attachment(), &cancelOnClose{ReadCloser: content, cancel: cancelRead}, nil
+	}
+	bucket, err := m.attachmentBucket(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	stream, err := bucket.OpenDownloadStream(file.ID)
+	if err != nil {
+		recordMongoError(ctx, "openAttachment", mdID, err)
+		return nil, nil, err
+	}
+	if err := stream.SetReadDeadline(time.Now().Add(m.timeouts.Export)); err != nil {
+		stream.Close()
+		return nil, nil, err
+	}
+	return file.attachment(), stream, nil
+}
+
+// DeleteAttachment
+// Removes a named attachment of a snippet.
+// Returns ErrNotFound or ErrInvalidKey when the update key is not valid,
+// or ErrAttachmentNotFound when the snippet has no such attachment.
+func (m *MDService) DeleteAttachment(ctx context.Context, mdID string, updateKey string, name string) error {
+	if err := m.ValidateIdAndKey(ctx, mdID, updateKey); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Write)
+	defer cancel()
+	ctx, end := startOperation(ctx, "deleteAttachment")
+	defer end()
+
+	file, err := m.findAttachment(ctx, mdID, name)
+	if err != nil {
+		return err
+	}
+	if file.Metadata.BlobKey != "" {
+		return m.deleteStoredAttachment(ctx, mdID, file)
+	}
+	bucket, err := m.attachmentBucket(ctx)
+	if err != nil {
+		return err
+	}
+	if err := bucket.Delete(file.ID); err != nil {
+		if errors.Is(err, gridfs.ErrFileNotFound) {
+			return snippetError("deleteAttachment", mdID, ErrAttachmentNotFound)
+		}
+		recordMongoError(ctx, "deleteAttachment", mdID, err)
+		return err
+	}
+	return nil
+}
+
+// deleteStoredAttachment
+// Removes the record of an attachment kept in the blob store, then its content.
+func (m *MDService) deleteStoredAttachment(ctx context.Context, mdID string, file *attachmentFile) error {
+	result, err := m.attachmentFiles().DeleteOne(ctx, bson.D{{Key: "_id", Value: file.ID}})
+	if err != nil {
+		recordMongoError(ctx, "deleteAttachment", mdID, err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return snippetError("deleteAttachment", mdID, ErrAttachmentNotFound)
+	}
+	if m.blobs == nil {
+		return errNoBlobStore
+	}
+	return m.blobs.Delete(ctx, file.Metadata.BlobKey)
+}
+
+// snippetExists
+// Returns ErrNotFound when the snippet does not exist or is in the trash.
+func (m *MDService) snippetExists(ctx context.Context, mdID string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Read)
+	defer cancel()
+
+	filter := m.active(bson.D{{Key: "id", Value: mdID}})
+	count, err := m.getMarkdownCollection().CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		recordMongoError(ctx, "exists", mdID, err)
+		return err
+	}
+	if count == 0 {
+		return snippetError("exists", mdID, ErrNotFound)
+	}
+	return nil
+}
+
+// findAttachments
+// Returns the files of every attachment of a snippet, ordered by name.
+func (m *MDService) findAttachments(ctx context.Context, mdID string) ([]attachmentFile, error) {
+	files := make([]attachmentFile, 0)
+	opts := options.Find().SetSort(bson.D{{Key: "filename", Value: 1}})
+	cursor, err := m.attachmentFiles().Find(ctx, m.attachmentFilter(mdID), opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// findAttachment
+// Returns the file of a named attachment of a snippet.
+// Returns ErrAttachmentNotFound when the snippet has no such attachment.
+func (m *MDService) findAttachment(ctx context.Context, mdID string, name string) (*attachmentFile, error) {
+	file := new(attachmentFile)
+	filter := append(m.attachmentFilter(mdID), bson.E{Key: "filename", Value: name})
+	if err := m.attachmentFiles().FindOne(ctx, filter).Decode(file); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, snippetError("getAttachment", mdID, ErrAttachmentNotFound)
+		}
+		recordMongoError(ctx, "getAttachment", mdID, err)
+		return nil, err
+	}
+	return file, nil
+}
+
+// attachmentFilter
+// Selects the attachments of a snippet of the scoped tenant.
+func (m *MDService) attachmentFilter(mdID string) bson.D {
+	return bson.D{
+		{Key: "metadata.snippetId", Value: mdID},
+		{Key: "metadata.tenantId", Value: m.filterTenantID()},
+	}
+}
+
+// attachmentFiles
+// Returns the files collection of the attachments bucket.
+func (m *MDService) attachmentFiles() *mongo.Collection {
+	return m.getMarkdownCollection().Database().Collection(AttachmentsBucket + ".files")
+}
+
+// attachmentBucket
+// Returns the attachments bucket of the scoped tenant,
+// its operations bounded by the deadline of ctx.
+func (m *MDService) attachmentBucket(ctx context.Context) (*gridfs.Bucket, error) {
+	db := m.getMarkdownCollection().Database()
+	bucket, err := gridfs.NewBucket(db, options.GridFSBucket().SetName(AttachmentsBucket))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		bucket.SetReadDeadline(deadline)
+		bucket.SetWriteDeadline(deadline)
+	}
+	return bucket, nil
+}
+
+// deleteAttachments
+// Removes every attachment of a snippet from the bucket in db.
+// Content kept in the blob store is removed with the snippet's blobs.
+func deleteAttachments(ctx context.Context, db *mongo.Database, mdID string, tenantID string) error {
+	files := db.Collection(AttachmentsBucket + ".files")
+	filter := bson.D{
+		{Key: "metadata.snippetId", Value: mdID},
+		{Key: "metadata.tenantId", Value: tenantID},
+	}
+	cursor, err := files.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	var docs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil || len(docs) == 0 {
+		return err
+	}
+
+	ids := make([]primitive.ObjectID, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	// Files go first, like Bucket.Delete, so readers never find partial content.
+	if _, err := files.DeleteMany(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}}); err != nil {
+		return err
+	}
+	chunks := db.Collection(AttachmentsBucket + ".chunks")
+	_, err = chunks.DeleteMany(ctx, bson.D{{Key: "files_id", Value: bson.D{{Key: "$in", Value: ids}}}})
+	return err
+}
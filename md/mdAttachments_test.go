@@ -0,0 +1,172 @@
+package md
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// pngHeader Bytes sniffed as image/png.
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+// uploadRequest
+// Returns a multipart attachment upload of content with the given form fields.
+func uploadRequest(t *testing.T, path string, filename string, content string, fields map[string]string) *http.Request {
+	body := new(bytes.Buffer)
+	form := multipart.NewWriter(body)
+	for key, value := range fields {
+		assert.Nil(t, form.WriteField(key, value))
+	}
+	if filename != "" {
+		part, err := form.CreateFormFile("file", filename)
+		assert.Nil(t, err)
+		io.WriteString(part, content)
+	}
+	assert.Nil(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	req.Header.Set(fiber.HeaderContentType, form.FormDataContentType())
+	return req
+}
+
+// Test_CacheControl
+// Attachments are cached for the max age, or always revalidated.
+func Test_CacheControl(t *testing.T) {
+	assert.Equal(t, "public, max-age=86400", cacheControl(24*time.Hour))
+	assert.Equal(t, "no-cache", cacheControl(0))
+}
+
+// Test_UploadAttachmentHandlerValidation
+// Uploads require a file, the update key and a valid name.
+func Test_UploadAttachmentHandlerValidation(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: api.ErrorHandler})
+	InitMDHandlers(SetupUnreachableMDService(t, config.Timeouts{})).ConfigureRoutes(app)
+
+	requests := map[string]*http.Request{
+		"no file":   uploadRequest(t, "/md/id/attachments", "", "", map[string]string{"updateKey": "key"}),
+		"no key":    uploadRequest(t, "/md/id/attachments", "a.png", pngHeader, nil),
+		"path name": uploadRequest(t, "/md/id/attachments", "a.png", pngHeader, map[string]string{"updateKey": "key", "name": "../a.png"}),
+		"dot name":  uploadRequest(t, "/md/id/attachments", "a.png", pngHeader, map[string]string{"updateKey": "key", "name": ".."}),
+		"long name": uploadRequest(t, "/md/id/attachments", strings.Repeat("a", 129), pngHeader, map[string]string{"updateKey": "key"}),
+	}
+	for name, req := range requests {
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, name)
+	}
+}
+
+// Test_Attachments
+// Attachments are stored within the size limit and quota,
+// served cacheably, and removed with their snippet.
+func Test_Attachments(t *testing.T) {
+	mdService, cleanup := SetupMDServiceWithConfig(t, &config.Config{
+		Attachments: config.Attachments{MaxSize: 1024, Quota: 1500, CacheMaxAge: time.Hour},
+	})
+	defer cleanup(t)
+	ctx := context.Background()
+	app := fiber.New(fiber.Config{ErrorHandler: api.ErrorHandler})
+	InitMDHandlers(mdService).ConfigureRoutes(app)
+
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Images", Body: "![diagram](diagram.png)"})
+	assert.Nil(t, err)
+	path := "/md/" + snippet.ID + "/attachments"
+	image := pngHeader + strings.Repeat("x", 992)
+
+	resp, err := app.Test(uploadRequest(t, path, "diagram.png", image, map[string]string{"updateKey": "wrong"}))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	// The query string is not part of the attachment's location.
+	resp, err = app.Test(uploadRequest(t, path+"?source=editor", "diagram.png", image, map[string]string{"updateKey": snippet.UpdateKey}))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, path+"/diagram.png", resp.Header.Get(fiber.HeaderLocation))
+
+	_, err = mdService.UploadAttachment(ctx, snippet.ID, snippet.UpdateKey, "diagram.png", strings.NewReader(image))
+	assert.True(t, errors.Is(err, ErrConflict))
+	_, err = mdService.UploadAttachment(ctx, snippet.ID, snippet.UpdateKey, "big.png", strings.NewReader(image+strings.Repeat("x", 25)))
+	assert.True(t, errors.Is(err, ErrAttachmentTooLarge))
+	_, err = mdService.UploadAttachment(ctx, snippet.ID, snippet.UpdateKey, "second.png", strings.NewReader(image))
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+	notes, err := mdService.UploadAttachment(ctx, snippet.ID, snippet.UpdateKey, "notes.txt", strings.NewReader("notes"))
+	assert.Nil(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", notes.ContentType)
+
+	attachments, err := mdService.ListAttachments(ctx, snippet.ID)
+	assert.Nil(t, err)
+	assert.Len(t, attachments, 2)
+	assert.Equal(t, "diagram.png", attachments[0].Name)
+	assert.Equal(t, "image/png", attachments[0].ContentType)
+	assert.Equal(t, int64(len(image)), attachments[0].Size)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, path+"/diagram.png", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/png", resp.Header.Get(fiber.HeaderContentType))
+	assert.Equal(t, "public, max-age=3600", resp.Header.Get(fiber.HeaderCacheControl))
+	assert.Empty(t, resp.Header.Get(fiber.HeaderContentDisposition))
+	served, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, image, string(served))
+
+	req := httptest.NewRequest(http.MethodGet, path+"/diagram.png", nil)
+	req.Header.Set(fiber.HeaderIfNoneMatch, resp.Header.Get(fiber.HeaderETag))
+	resp, err = app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, path+"/notes.txt", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, `attachment; filename=notes.txt`, resp.Header.Get(fiber.HeaderContentDisposition))
+
+	assert.Nil(t, mdService.DeleteAttachment(ctx, snippet.ID, snippet.UpdateKey, "notes.txt"))
+	assert.True(t, errors.Is(mdService.DeleteAttachment(ctx, snippet.ID, snippet.UpdateKey, "notes.txt"), ErrAttachmentNotFound))
+
+	mdService.attachments.ImagesOnly = true
+	_, err = mdService.UploadAttachment(ctx, snippet.ID, snippet.UpdateKey, "notes.txt", strings.NewReader("notes"))
+	assert.True(t, errors.Is(err, ErrUnsupportedType))
+
+	assert.Nil(t, mdService.DeleteMarkdownSnippet(ctx, snippet.ID, snippet.UpdateKey))
+	_, _, err = mdService.OpenAttachment(ctx, snippet.ID, "diagram.png")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	purged, err := NewPurger(mdService, config.Trash{Retention: time.Nanosecond}).Purge(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), purged)
+	files, err := mdService.findAttachments(ctx, snippet.ID)
+	assert.Nil(t, err)
+	assert.Empty(t, files)
+	chunks, err := mdService.getMarkdownCollection().Database().Collection(AttachmentsBucket+".chunks").CountDocuments(ctx, struct{}{})
+	assert.Nil(t, err)
+	assert.Zero(t, chunks)
+}
+
+// Test_RequestPath
+// Resource locations keep the tenant prefix and drop the query string.
+func Test_RequestPath(t *testing.T) {
+	app := fiber.New()
+	api.ConfigureTenancy(app, config.Tenant{Mode: config.TenancyFilter, Resolver: "path"})
+	app.Post("/md/:id/attachments", func(ctx *fiber.Ctx) error {
+		return ctx.SendString(requestPath(ctx))
+	})
+
+	for path, expected := range map[string]string{
+		"/t/acme/md/abc/attachments":                "/t/acme/md/abc/attachments",
+		"/t/acme/md/abc/attachments/?source=editor": "/t/acme/md/abc/attachments",
+	} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, path, nil))
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, expected, string(body), path)
+	}
+}
@@ -0,0 +1,302 @@
+package md
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/logging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditCollection Append-only collection of snippet changes,
+// always in the configured database like the outbox.
+const AuditCollection = "audit"
+
+// auditTTLIndex Name of the index expiring audit entries.
+const auditTTLIndex = "time_ttl"
+
+// Audited operations besides the revision sources.
+const (
+	AuditImport  = "import"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+)
+
+// Mongo error codes handled when applying audit retention.
+const (
+	codeNamespaceNotFound    = 26
+	codeIndexNotFound        = 27
+	codeIndexOptionsConflict = 85
+)
+
+// AuditEntry
+// A record of a change to a snippet, who made it and from where.
+type AuditEntry struct {
+	// Audit entry guid.
+	ID string `json:"id" bson:"id" format:"uuid"`
+	// Date the change was made.
+	Time time.Time `json:"time" bson:"time" format:"date-time"`
+	// One of create, import, fork, update, patch, merge, collab, delete or restore.
+	Operation string `json:"operation" bson:"operation" example:"update"`
+	// Affected markdown snippet guid.
+	SnippetID string `json:"snippetId" bson:"snippetId" format:"uuid"`
+	// Revision saved by the change, unset for deletes.
+	Revision int64 `json:"revision,omitempty" bson:"revision,omitempty" example:"2"`
+	// Basic auth user, empty for changes made by background work.
+	Actor string `json:"actor,omitempty" bson:"actor,omitempty" example:"admin"`
+	// Client IP address.
+	IP string `json:"ip,omitempty" bson:"ip,omitempty" example:"203.0.113.7"`
+	// Client User-Agent header.
+	UserAgent string `json:"userAgent,omitempty" bson:"userAgent,omitempty" example:"curl/7.79.1"`
+	// Request correlation id.
+	RequestID string `json:"requestId,omitempty" bson:"requestId,omitempty"`
+	// Content hash of the snippet before the change, unset for creates and restores.
+	BeforeHash string `json:"beforeHash,omitempty" bson:"beforeHash,omitempty"`
+	// Content hash of the snippet after the change, unset for deletes.
+	AfterHash string `json:"afterHash,omitempty" bson:"afterHash,omitempty"`
+	// Tenant the snippet belongs to, empty when tenancy is disabled.
+	Tenant string `json:"tenant,omitempty" bson:"tenant,omitempty"`
+}
+
+// AuditParams
+// Filters of an audit log query. Zero values match every entry.
+type AuditParams struct {
+	Actor     string
+	IP        string
+	RequestID string
+	Operation string
+	SnippetID string
+	Tenant    string
+	// Entries made at or after Since and before Until.
+	Since time.Time
+	Until time.Time
+	Limit int64
+	Skip  int64
+}
+
+// AuditIndexModels
+// Returns the audit collection query indexes. Expiry is
+// configured separately by ApplyAuditRetention.
+// Applied to the configured database by migration 5.
+func AuditIndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "time", Value: -1}}},
+		{Keys: bson.D{{Key: "snippetId", Value: 1}, {Key: "time", Value: -1}}},
+		{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "time", Value: -1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "time", Value: -1}}},
+	}
+}
+
+// ApplyAuditRetention
+// Expires audit entries older than retention with a TTL index,
+// updating the index when the retention changed.
+// Entries are kept forever when retention is zero.
+func ApplyAuditRetention(ctx context.Context, db *mongo.Database, retention time.Duration) error {
+	indexes := db.Collection(AuditCollection).Indexes()
+	if retention <= 0 {
+		_, err := indexes.DropOne(ctx, auditTTLIndex)
+		if hasErrorCode(err, codeIndexNotFound, codeNamespaceNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	seconds := int32(retention / time.Second)
+	_, err := indexes.CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "time", Value: 1}},
+		Options: options.Index().SetName(auditTTLIndex).SetExpireAfterSeconds(seconds),
+	})
+	if !hasErrorCode(err, codeIndexOptionsConflict) {
+		return err
+	}
+	cmd := bson.D{
+		{Key: "collMod", Value: AuditCollection},
+		{Key: "index", Value: bson.D{{Key: "name", Value: auditTTLIndex}, {Key: "expireAfterSeconds", Value: seconds}}},
+	}
+	return db.RunCommand(ctx, cmd).Err()
+}
+
+// ListAudit
+// Returns the audit entries matching params, most recent first.
+// Entries of every tenant are listed unless params names one.
+func (m *MDService) ListAudit(ctx context.Context, params AuditParams) ([]AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Search)
+	defer cancel()
+	ctx, end := startOperation(ctx, "listAudit")
+	defer end()
+
+	entries := make([]AuditEntry, 0)
+	opts := auditFindOptions(params).SetProjection(bson.M{"_id": 0})
+	cursor, err := m.auditLog().Find(ctx, auditFilter(params), opts)
+	if err != nil {
+		recordMongoError(ctx, "listAudit", "", err)
+		return nil, err
+	}
+	if err := cursor.All(ctx, &entries); err != nil {
+		recordMongoError(ctx, "listAudit", "", err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ExportAudit
+// Writes the audit entries matching params to w as JSON lines,
+// most recent first. A zero Limit exports every entry.
+// Returns the number of entries written.
+func (m *MDService) ExportAudit(ctx context.Context, params AuditParams, w io.Writer) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Export)
+	defer cancel()
+	ctx, end := startOperation(ctx, "exportAudit")
+	defer end()
+
+	opts := auditFindOptions(params).SetProjection(bson.M{"_id": 0})
+	cursor, err := m.auditLog().Find(ctx, auditFilter(params), opts)
+	if err != nil {
+		recordMongoError(ctx, "exportAudit", "", err)
+		return 0, err
+	}
+	defer cursor.Close(context.Background())
+
+	encoder := json.NewEncoder(w)
+	var count int64
+	for cursor.Next(ctx) {
+		entry := new(AuditEntry)
+		if err := cursor.Decode(entry); err != nil {
+			return count, err
+		}
+		if err := encoder.Encode(entry); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		recordMongoError(ctx, "exportAudit", "", err)
+		return count, err
+	}
+	return count, nil
+}
+
+// auditFilter
+// Builds the audit log query for params.
+func auditFilter(params AuditParams) bson.D {
+	filter := bson.D{}
+	fields := []struct{ key, value string }{
+		{"actor", params.Actor},
+		{"ip", params.IP},
+		{"requestId", params.RequestID},
+		{"operation", params.Operation},
+		{"snippetId", params.SnippetID},
+		{"tenant", params.Tenant},
+	}
+	for _, field := range fields {
+		if field.value != "" {
+			filter = append(filter, bson.E{Key: field.key, Value: field.value})
+		}
+	}
+
+	period := bson.D{}
+	if !params.Since.IsZero() {
+		period = append(period, bson.E{Key: "$gte", Value: params.Since})
+	}
+	if !params.Until.IsZero() {
+		period = append(period, bson.E{Key: "$lt", Value: params.Until})
+	}
+	if len(period) > 0 {
+		filter = append(filter, bson.E{Key: "time", Value: period})
+	}
+	return filter
+}
+
+// auditFindOptions
+// Sorts and pages an audit log query by params.
+func auditFindOptions(params AuditParams) *options.FindOptions {
+	return options.Find().
+		SetSort(bson.D{{Key: "time", Value: -1}}).
+		SetLimit(params.Limit).
+		SetSkip(params.Skip)
+}
+
+// newAuditEntry
+// Returns the audit entry of a change from before to after,
+// made by the actor and request carried by ctx.
+func (m *MDService) newAuditEntry(ctx context.Context, operation string, snippetID string, before *MarkdownSnippet, after *MarkdownSnippet) *AuditEntry {
+	actor := api.ActorFromContext(ctx)
+	entry := &AuditEntry{
+		ID:         uuid.NewString(),
+		Time:       time.Now(),
+		Operation:  operation,
+		SnippetID:  snippetID,
+		Actor:      actor.Name,
+		IP:         actor.IP,
+		UserAgent:  actor.UserAgent,
+		RequestID:  logging.RequestID(ctx),
+		BeforeHash: contentHash(before),
+		AfterHash:  contentHash(after),
+		Tenant:     m.tenant,
+	}
+	if after != nil {
+		entry.Revision = after.Revision
+	}
+	return entry
+}
+
+// auditLog
+// Returns the audit collection.
+func (m *MDService) auditLog() *mongo.Collection {
+	return m.client.Database(m.mongo.Database).Collection(AuditCollection)
+}
+
+// audit
+// Appends entries to the audit log.
+func (m *MDService) audit(ctx context.Context, entries ...*AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		docs[i] = entry
+	}
+	_, err := m.auditLog().InsertMany(ctx, docs)
+	return err
+}
+
+// contentHash
+// Returns the hex SHA-256 of the snippet title and body, followed by
+// the name and content of each file, all separated by NUL bytes.
+// Empty when snippet is nil.
+func contentHash(snippet *MarkdownSnippet) string {
+	if snippet == nil {
+		return ""
+	}
+	hash := sha256.New()
+	io.WriteString(hash, snippet.Title+"\x00"+snippet.Body)
+	for _, file := range snippet.Files {
+		io.WriteString(hash, "\x00"+file.Name+"\x00"+file.Content)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// hasErrorCode
+// Reports whether err is a server error with one of codes.
+func hasErrorCode(err error, codes ...int32) bool {
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	for _, code := range codes {
+		if cmdErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
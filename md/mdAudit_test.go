@@ -0,0 +1,126 @@
+package md
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/logging"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Test_ContentHash
+// Hashes cover the title, body and files only, and are empty without a snippet.
+func Test_ContentHash(t *testing.T) {
+	assert.Empty(t, contentHash(nil))
+	assert.Equal(t, "db77367124c687e202230ba800e390811d7040fa33832766fa9d307fe6590b49",
+		contentHash(&MarkdownSnippet{ID: "id", Title: "Title", Body: "# Body", Revision: 3}))
+	assert.NotEqual(t, contentHash(&MarkdownSnippet{Title: "ab"}), contentHash(&MarkdownSnippet{Title: "a", Body: "b"}))
+	assert.NotEqual(t, contentHash(&MarkdownSnippet{Title: "a"}), contentHash(&MarkdownSnippet{Title: "a", Files: []SnippetFile{{Name: "b"}}}))
+}
+
+// Test_ApplySet
+// The updated snippet keeps the fields not set and increments its revision.
+func Test_ApplySet(t *testing.T) {
+	files := []SnippetFile{{Name: "a.sh", Content: "ls"}, {Name: "b.sh", Content: "ls"}}
+	before := &MarkdownSnippet{ID: "id", Title: "Title", Body: "# Body", Files: files, Revision: 3}
+	after := new(MarkdownSnippet)
+	assert.Nil(t, applySet(before, bson.D{{Key: "body", Value: "# New"}}, after))
+	assert.Equal(t, &MarkdownSnippet{ID: "id", Title: "Title", Body: "# New", Files: files, Revision: 4}, after)
+	assert.Equal(t, "# Body", before.Body)
+
+	assert.Nil(t, applySet(before, bson.D{{Key: "files", Value: []SnippetFile{{Name: "c.sh", Content: "ls"}}}}, after))
+	assert.Equal(t, []SnippetFile{{Name: "c.sh", Content: "ls"}}, after.Files)
+	assert.Equal(t, "a.sh", before.Files[0].Name)
+}
+
+// Test_AuditFilter
+// Only the given filters are queried, times bound the entry time.
+func Test_AuditFilter(t *testing.T) {
+	assert.Equal(t, bson.D{}, auditFilter(AuditParams{Limit: 10}))
+
+	since := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, bson.D{
+		{Key: "actor", Value: "admin"},
+		{Key: "snippetId", Value: "id"},
+		{Key: "time", Value: bson.D{{Key: "$gte", Value: since}}},
+	}, auditFilter(AuditParams{Actor: "admin", SnippetID: "id", Since: since}))
+}
+
+// Test_AuditHandlerValidation
+// Invalid paging and times are rejected before the log is read.
+func Test_AuditHandlerValidation(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: api.ErrorHandler})
+	InitMDHandlers(SetupUnreachableMDService(t, config.Timeouts{})).ConfigureAdminRoutes(app.Group("/admin"))
+
+	for _, path := range []string{"/admin/audit?limit=-1", "/admin/audit?skip=x", "/admin/audit?since=yesterday", "/admin/audit/export?until=2021-10-01"} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, path)
+	}
+}
+
+// Test_Audit
+// Every change is recorded with its actor, request and content hashes.
+func Test_Audit(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := api.WithActor(logging.WithRequestID(context.Background(), "req-1"),
+		api.Actor{Name: "admin", IP: "203.0.113.7", UserAgent: "curl/7.79.1"})
+
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Audit", Body: "# One"})
+	assert.Nil(t, err)
+	updated, err := mdService.UpdateMarkdownSnippet(ctx, &UpdateMDReq{CreateMDReq: CreateMDReq{Title: "Audit", Body: "# Two"}, ID: snippet.ID, UpdateKey: snippet.UpdateKey})
+	assert.Nil(t, err)
+	assert.Equal(t, "# Two", updated.Body)
+	assert.Equal(t, int64(2), updated.Revision)
+	assert.Nil(t, mdService.DeleteMarkdownSnippet(ctx, snippet.ID, snippet.UpdateKey))
+	_, err = mdService.RestoreMarkdownSnippet(context.Background(), snippet.ID, snippet.UpdateKey)
+	assert.Nil(t, err)
+
+	entries, err := mdService.ListAudit(context.Background(), AuditParams{SnippetID: snippet.ID})
+	assert.Nil(t, err)
+	assert.Len(t, entries, 4)
+	byOperation := make(map[string]AuditEntry)
+	for _, entry := range entries {
+		byOperation[entry.Operation] = entry
+	}
+
+	created, update := byOperation[SourceCreate], byOperation[SourceUpdate]
+	assert.Equal(t, "admin", created.Actor)
+	assert.Equal(t, "203.0.113.7", created.IP)
+	assert.Equal(t, "curl/7.79.1", created.UserAgent)
+	assert.Equal(t, "req-1", created.RequestID)
+	assert.Empty(t, created.BeforeHash)
+	assert.Equal(t, created.AfterHash, update.BeforeHash)
+	assert.Equal(t, contentHash(updated), update.AfterHash)
+	assert.Equal(t, int64(2), update.Revision)
+	assert.Equal(t, update.AfterHash, byOperation[AuditDelete].BeforeHash)
+	assert.Empty(t, byOperation[AuditDelete].AfterHash)
+	assert.Empty(t, byOperation[AuditRestore].Actor)
+	assert.Equal(t, update.AfterHash, byOperation[AuditRestore].AfterHash)
+
+	entries, err = mdService.ListAudit(context.Background(), AuditParams{Operation: AuditDelete, Since: time.Now().Add(time.Minute)})
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	out := new(bytes.Buffer)
+	count, err := mdService.ExportAudit(context.Background(), AuditParams{Actor: "admin"}, out)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), count)
+	assert.Equal(t, 3, strings.Count(out.String(), "\n"))
+
+	db := mdService.client.Database(mdService.mongo.Database)
+	assert.Nil(t, ApplyAuditRetention(context.Background(), db, time.Hour))
+	assert.Nil(t, ApplyAuditRetention(context.Background(), db, 2*time.Hour))
+	assert.Nil(t, ApplyAuditRetention(context.Background(), db, 0))
+	assert.Nil(t, ApplyAuditRetention(context.Background(), db, 0))
+}
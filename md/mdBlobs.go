@@ -0,0 +1,192 @@
+package md
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/soulxburn/mdsnips/blob"
+	"github.com/soulxburn/mdsnips/logging"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// errNoBlobStore is returned reading a body or attachment
+// kept in a blob store when none is configured.
+var errNoBlobStore = errors.New("blob store not configured")
+
+// WithBlobStore
+// Returns a copy of the MDService keeping bodies over the offload
+// size, and new attachments, in store. A nil store keeps them in Mongo.
+func (m *MDService) WithBlobStore(store blob.Store) *MDService {
+	withStore := *m
+	withStore.blobs = store
+	if withStore.offloadSize <= 0 {
+		withStore.offloadSize = 16 << 10
+	}
+	return &withStore
+}
+
+// blobPrefix
+// Returns the blob key prefix of everything stored for a snippet.
+func (m *MDService) blobPrefix(mdID string) string {
+	if m.tenant == "" {
+		return "snippets/" + mdID + "/"
+	}
+	return "tenants/" + m.tenant + "/snippets/" + mdID + "/"
+}
+
+// storeBody
+// Puts body in the blob store when it is larger than the offload size,
+// returning its key. Revisions with the same body share a key.
+// Returns an empty key for bodies kept in Mongo.
+func (m *MDService) storeBody(ctx context.Context, mdID string, body string) (string, error) {
+	if m.blobs == nil || int64(len(body)) <= m.offloadSize {
+		return "", nil
+	}
+	sum := sha256.Sum256([]byte(body))
+	key := m.blobPrefix(mdID) + "body/" + hex.EncodeToString(sum[:])
+	if err := m.blobs.Put(ctx, key, strings.NewReader(body), int64(len(body))); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// offloadBody
+// Moves the body of a new snippet to the blob store when it is too large, setting its BodyRef.
+func (m *MDService) offloadBody(ctx context.Context, snippet *MarkdownSnippet) error {
+	ref, err := m.storeBody(ctx, snippet.ID, snippet.Body)
+	if err != nil {
+		return err
+	}
+	snippet.BodyRef = ref
+	return nil
+}
+
+// loadBody
+// Reads a body kept in the blob store under ref into body.
+// Bodies kept in Mongo, with an empty ref, are left unchanged.
+func (m *MDService) loadBody(ctx context.Context, ref string, body *string) error {
+	if ref == "" {
+		return nil
+	}
+	if m.blobs == nil {
+		return errNoBlobStore
+	}
+	content, err := blob.ReadString(ctx, m.blobs, ref)
+	if err != nil {
+		return err
+	}
+	*body = content
+	return nil
+}
+
+// storeSet
+// Returns the update applying set, incrementing the revision, with
+// a body too large for Mongo moved to the blob store, and its key.
+func (m *MDService) storeSet(ctx context.Context, mdID string, set bson.D) (bson.D, string, error) {
+	stored := make(bson.D, 0, len(set)+1)
+	var ref string
+	var unset bson.D
+	for _, field := range set {
+		body, ok := field.Value.(string)
+		if field.Key != "body" || !ok {
+			stored = append(stored, field)
+			continue
+		}
+		var err error
+		if ref, err = m.storeBody(ctx, mdID, body); err != nil {
+			return nil, "", err
+		}
+		if ref == "" {
+			stored = append(stored, field)
+			unset = bson.D{{Key: "bodyRef", Value: ""}}
+		} else {
+			stored = append(stored, bson.E{Key: "body", Value: ""}, bson.E{Key: "bodyRef", Value: ref})
+		}
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: stored},
+		{Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}},
+	}
+	if len(unset) > 0 {
+		update = append(update, bson.E{Key: "$unset", Value: unset})
+	}
+	return update, ref, nil
+}
+
+// setsBody
+// Reports whether set replaces the body.
+func setsBody(set bson.D) bool {
+	for _, field := range set {
+		if field.Key == "body" {
+			return true
+		}
+	}
+	return false
+}
+
+// stored
+// Returns the snippet as saved to Mongo, without a body kept in the blob store.
+func (s MarkdownSnippet) stored() *MarkdownSnippet {
+	if s.BodyRef != "" {
+		s.Body = ""
+	}
+	return &s
+}
+
+// stored
+// Returns the revision as saved to Mongo, without a body kept in the blob store.
+func (r Revision) stored() *Revision {
+	if r.BodyRef != "" {
+		r.Body = ""
+	}
+	return &r
+}
+
+// deleteBlobs
+// Removes everything kept in the blob store for a snippet.
+func (m *MDService) deleteBlobs(ctx context.Context, mdID string) error {
+	if m.blobs == nil {
+		return nil
+	}
+	return m.blobs.DeletePrefix(ctx, m.blobPrefix(mdID))
+}
+
+// discardBlobs
+// Removes the blobs stored for new snippets whose insert failed.
+// Snippets found in Mongo keep theirs, a duplicate id belongs to
+// another snippet and a failed write may still have been applied.
+func (m *MDService) discardBlobs(ctx context.Context, mdIDs ...string) {
+	if m.blobs == nil {
+		return
+	}
+	// The write may have failed on the caller's deadline.
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), m.timeouts.Write)
+	defer cancel()
+	log := logging.FromContext(ctx)
+	for _, mdID := range mdIDs {
+		count, err := m.getMarkdownCollection().CountDocuments(cleanupCtx, m.scoped(bson.D{{Key: "id", Value: mdID}}))
+		if err == nil && count == 0 {
+			err = m.deleteBlobs(cleanupCtx, mdID)
+		}
+		if err != nil {
+			log.WithError(err).WithField("snippetId", mdID).Warn("Failed to remove blobs of a snippet that was not created")
+		}
+	}
+}
+
+// cancelOnClose
+// Releases a context once its reader is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
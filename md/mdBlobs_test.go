@@ -0,0 +1,132 @@
+package md
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulxburn/mdsnips/blob"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Test_StoreSet
+// Large bodies are replaced by a reference, small ones clear it.
+func Test_StoreSet(t *testing.T) {
+	store, err := blob.NewLocalStore(t.TempDir())
+	assert.Nil(t, err)
+	mdService := SetupUnreachableMDService(t, config.Timeouts{}).WithBlobStore(store)
+	mdService.offloadSize = 8
+	ctx := context.Background()
+
+	update, ref, err := mdService.storeSet(ctx, "id", bson.D{{Key: "title", Value: "Large"}, {Key: "body", Value: "# Large body"}})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(ref, "snippets/id/body/"))
+	assert.Equal(t, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "title", Value: "Large"}, {Key: "body", Value: ""}, {Key: "bodyRef", Value: ref}}},
+		{Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}},
+	}, update)
+	var body string
+	assert.Nil(t, mdService.loadBody(ctx, ref, &body))
+	assert.Equal(t, "# Large body", body)
+
+	update, ref, err = mdService.storeSet(ctx, "id", bson.D{{Key: "body", Value: "# Small"}})
+	assert.Nil(t, err)
+	assert.Empty(t, ref)
+	assert.Equal(t, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "body", Value: "# Small"}}},
+		{Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}},
+		{Key: "$unset", Value: bson.D{{Key: "bodyRef", Value: ""}}},
+	}, update)
+
+	assert.Equal(t, "tenants/acme/snippets/id/", mdService.ForTenant("acme").blobPrefix("id"))
+	assert.True(t, errors.Is(SetupUnreachableMDService(t, config.Timeouts{}).loadBody(ctx, "ref", &body), errNoBlobStore))
+}
+
+// Test_BlobStorage
+// Large bodies and attachments are kept in the blob store,
+// read back transparently and removed when the snippet is purged.
+func Test_BlobStorage(t *testing.T) {
+	mdService, cleanup := SetupMDServiceWithConfig(t, &config.Config{Blob: config.Blob{OffloadSize: 16}})
+	defer cleanup(t)
+	store, err := blob.NewLocalStore(t.TempDir())
+	assert.Nil(t, err)
+	mdService = mdService.WithBlobStore(store)
+	ctx := context.Background()
+	large := "# Large\n" + strings.Repeat("Details...", 10)
+
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Large", Body: large})
+	assert.Nil(t, err)
+	stored := new(MarkdownSnippet)
+	assert.Nil(t, mdService.getMarkdownCollection().FindOne(ctx, bson.D{{Key: "id", Value: snippet.ID}}).Decode(stored))
+	assert.Empty(t, stored.Body)
+	assert.NotEmpty(t, stored.BodyRef)
+
+	found, err := mdService.GetMarkdownSnippet(ctx, snippet.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, large, found.Body)
+
+	updated, err := mdService.UpdateMarkdownSnippet(ctx, &UpdateMDReq{
+		CreateMDReq: CreateMDReq{Title: "Small", Body: "# Small"},
+		ID:          snippet.ID,
+		UpdateKey:   snippet.UpdateKey,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "# Small", updated.Body)
+	first, err := mdService.GetRevision(ctx, snippet.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, large, first.Body)
+
+	_, err = mdService.UploadAttachment(ctx, snippet.ID, snippet.UpdateKey, "diagram.png", strings.NewReader(pngHeader))
+	assert.Nil(t, err)
+	_, content, err := mdService.OpenAttachment(ctx, snippet.ID, "diagram.png")
+	assert.Nil(t, err)
+	served := new(bytes.Buffer)
+	served.ReadFrom(content)
+	assert.Nil(t, content.Close())
+	assert.Equal(t, pngHeader, served.String())
+
+	backup := new(bytes.Buffer)
+	_, err = mdService.BackupSnippets(ctx, backup)
+	assert.Nil(t, err)
+	assert.NotContains(t, backup.String(), "bodyRef")
+
+	assert.Nil(t, mdService.DeleteMarkdownSnippet(ctx, snippet.ID, snippet.UpdateKey))
+	_, err = NewPurger(mdService, config.Trash{Retention: time.Nanosecond}).Purge(ctx)
+	assert.Nil(t, err)
+	_, err = store.Get(ctx, stored.BodyRef)
+	assert.True(t, errors.Is(err, blob.ErrNotFound))
+}
+
+// Test_DiscardBlobs
+// Blobs of snippets that were not created are removed,
+// those of an existing snippet with the same id are kept.
+func Test_DiscardBlobs(t *testing.T) {
+	mdService, cleanup := SetupMDServiceWithConfig(t, &config.Config{Blob: config.Blob{OffloadSize: 16}})
+	defer cleanup(t)
+	store, err := blob.NewLocalStore(t.TempDir())
+	assert.Nil(t, err)
+	mdService = mdService.WithBlobStore(store)
+	ctx := context.Background()
+	large := "# Large\n" + strings.Repeat("Details...", 10)
+
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Large", Body: large})
+	assert.Nil(t, err)
+	duplicate := mdService.newSnippet("Large", large)
+	duplicate.ID = snippet.ID
+	err = mdService.insert(ctx, "create", duplicate, SourceCreate, SourceCreate)
+	assert.True(t, errors.Is(err, ErrConflict))
+	found, err := mdService.GetMarkdownSnippet(ctx, snippet.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, large, found.Body)
+
+	ref, err := mdService.storeBody(ctx, "missing", large)
+	assert.Nil(t, err)
+	mdService.discardBlobs(ctx, "missing")
+	_, err = store.Get(ctx, ref)
+	assert.True(t, errors.Is(err, blob.ErrNotFound))
+}
@@ -4,30 +4,138 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/soulxburn/mdsnips/config"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/x/bsonx"
 )
 
-// ConfigureIndexes
-// Creates/Updates the markdown collection indexes.
-func ConfigureIndexes(mClient *mongo.Client) {
-	collection := getMarkdownCollection(mClient)
+// TextIndexName Name of the markdown text index, kept from
+// before it covered file contents.
+const TextIndexName = "title_text_body_text"
 
+// IndexModels
+// Returns the markdown collection indexes,
+// including the tenantId index when tenantFilter is set.
+// Migration 1 created them with a title and body text index,
+// migration 7 extended it to files.
+func IndexModels(tenantFilter bool) []mongo.IndexModel {
 	index := []mongo.IndexModel{
 		{
 			Keys: bsonx.Doc{
 				{Key: "title", Value: bsonx.String("text")},
 				{Key: "body", Value: bsonx.String("text")},
+				{Key: "files.content", Value: bsonx.String("text")},
 			},
+			Options: options.Index().SetName(TextIndexName),
 		},
 		{
 			Keys: bsonx.Doc{{Key: "createDate", Value: bsonx.Int32(1)}},
 		},
 	}
-	name, err := collection.Indexes().CreateMany(context.TODO(), index)
+	if tenantFilter {
+		index = append(index, mongo.IndexModel{
+			Keys: bsonx.Doc{
+				{Key: "tenantId", Value: bsonx.Int32(1)},
+				{Key: "createDate", Value: bsonx.Int32(1)},
+			},
+		})
+	}
+	return index
+}
+
+// UpgradeTextIndex
+// Rebuilds a text index created before it covered file contents,
+// keeping the weights and language set by `reindex`. Files are
+// weighted like the body. Collections without one are left alone.
+// Applied by migration 7.
+func UpgradeTextIndex(ctx context.Context, collection *mongo.Collection) error {
+	cursor, err := collection.Indexes().List(ctx)
+	if isNamespaceNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var indexes []struct {
+		Name            string           `bson:"name"`
+		Weights         map[string]int32 `bson:"weights"`
+		DefaultLanguage string           `bson:"default_language"`
+	}
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		if index.Name != TextIndexName {
+			continue
+		}
+		if _, ok := index.Weights["files.content"]; ok {
+			return nil
+		}
+		textIndex := IndexModels(false)[0]
+		index.Weights["files.content"] = index.Weights["body"]
+		textIndex.Options.SetWeights(index.Weights).SetDefaultLanguage(index.DefaultLanguage)
+		if _, err := collection.Indexes().DropOne(ctx, TextIndexName); err != nil {
+			return err
+		}
+		_, err := collection.Indexes().CreateOne(ctx, textIndex)
+		return err
+	}
+	return nil
+}
+
+// CheckIndexes
+// Returns an error when the markdown collection indexes are missing,
+// from the configured database or, when tenants have their own
+// database, from any configured tenant database.
+func CheckIndexes(ctx context.Context, mClient *mongo.Client, cfg *config.Config) error {
+	databases := []string{cfg.Mongo.Database}
+	if cfg.Tenant.Mode == config.TenancyDatabase {
+		databases = databases[:0]
+		for _, tenant := range cfg.Tenant.Names {
+			databases = append(databases, cfg.Tenant.DatabasePrefix+tenant)
+		}
+	}
+
+	expected := []string{TextIndexName, "createDate_1"}
+	if cfg.Tenant.Mode == config.TenancyFilter {
+		expected = append(expected, "tenantId_1_createDate_1")
+	}
+	for _, database := range databases {
+		existing, err := IndexNames(ctx, mClient.Database(database).Collection(cfg.Mongo.Collection))
+		if err != nil {
+			return err
+		}
+		for _, name := range expected {
+			if !existing[name] {
+				return fmt.Errorf("index %s missing on %s.%s", name, database, cfg.Mongo.Collection)
+			}
+		}
+	}
+	return nil
+}
+
+// IndexNames
+// Returns the names of the indexes on collection,
+// none when the collection does not exist.
+func IndexNames(ctx context.Context, collection *mongo.Collection) (map[string]bool, error) {
+	names := make(map[string]bool)
+	cursor, err := collection.Indexes().List(ctx)
+	if isNamespaceNotFound(err) {
+		return names, nil
+	}
 	if err != nil {
-		fmt.Printf("Error Creating Text Index: %s", err)
-		return
+		return nil, err
+	}
+	var indexes []struct {
+		Name string `bson:"name"`
+	}
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		names[index.Name] = true
 	}
-	fmt.Printf("Index Created: %s\n", name)
+	return names, nil
 }
@@ -0,0 +1,447 @@
+package md
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// match
+// A line of a, at A, equal to the line of b at B.
+type match struct {
+	A, B int
+}
+
+// matchLines
+// Returns the lines a and b have in common, in order, as a longest
+// common subsequence found with Myers' linear space diff algorithm.
+func matchLines(a []string, b []string) []match {
+	// Comparing ids is cheaper than comparing lines.
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+	d := &differ{a: intern(a), b: intern(b)}
+	d.compare(0, len(a), 0, len(b))
+	return d.matches
+}
+
+// differ
+// State of one matchLines comparison.
+type differ struct {
+	a, b    []int
+	matches []match
+}
+
+// compare
+// Appends the matches between a[a0:a1] and b[b0:b1].
+func (d *differ) compare(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.matches = append(d.matches, match{a0, b0})
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a1 > a0 && b1 > b0 && d.a[a1-1] == d.b[b1-1] {
+		a1--
+		b1--
+		suffix++
+	}
+	if a0 < a1 && b0 < b1 {
+		x, y, u, v := d.middleSnake(a0, a1, b0, b1)
+		d.compare(a0, x, b0, y)
+		for i := 0; i < u-x; i++ {
+			d.matches = append(d.matches, match{x + i, y + i})
+		}
+		d.compare(u, a1, v, b1)
+	}
+	for i := 0; i < suffix; i++ {
+		d.matches = append(d.matches, match{a1 + i, b1 + i})
+	}
+}
+
+// middleSnake
+// Returns the start and end of the diagonal run in the middle of a
+// shortest edit script of a[a0:a1] into b[b0:b1], searching forwards
+// from the start and backwards from the end until the paths overlap.
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (x, y, u, v int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+	off := max + 1
+	// Furthest x reached on each diagonal k = x - y, the backward search
+	// counts x from the end on diagonals of the reversed sequences.
+	forward := make([]int, 2*max+3)
+	backward := make([]int, 2*max+3)
+	for depth := 0; depth <= max; depth++ {
+		for k := -depth; k <= depth; k += 2 {
+			var x int
+			if k == -depth || (k != depth && forward[off+k-1] < forward[off+k+1]) {
+				x = forward[off+k+1]
+			} else {
+				x = forward[off+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x++
+				y++
+			}
+			forward[off+k] = x
+			if r := delta - k; odd && r >= -(depth-1) && r <= depth-1 && x+backward[off+r] >= n {
+				return a0 + startX, b0 + startY, a0 + x, b0 + y
+			}
+		}
+		for r := -depth; r <= depth; r += 2 {
+			var x int
+			if r == -depth || (r != depth && backward[off+r-1] < backward[off+r+1]) {
+				x = backward[off+r+1]
+			} else {
+				x = backward[off+r-1] + 1
+			}
+			y := x - r
+			startX, startY := x, y
+			for x < n && y < m && d.a[a1-1-x] == d.b[b1-1-y] {
+				x++
+				y++
+			}
+			backward[off+r] = x
+			if k := delta - r; !odd && k >= -depth && k <= depth && forward[off+k]+x >= n {
+				return a1 - x, b1 - y, a1 - startX, b1 - startY
+			}
+		}
+	}
+	panic("md: diff searches did not meet")
+}
+
+// Diff line operations.
+const (
+	DiffContext = "context"
+	DiffAdd     = "add"
+	DiffDelete  = "delete"
+)
+
+// Word change operations.
+const (
+	WordEqual  = "equal"
+	WordInsert = "insert"
+	WordDelete = "delete"
+)
+
+// DefaultDiffContext Unchanged lines shown around each change.
+const DefaultDiffContext = 3
+
+// SnippetDiff
+// The changes between two revisions of a snippet.
+type SnippetDiff struct {
+	// Markdown snippet guid.
+	SnippetID string `json:"snippetId" format:"uuid"`
+	// Revision compared from, 0 for an empty snippet.
+	From int64 `json:"from" example:"2"`
+	// Revision compared to.
+	To int64 `json:"to" example:"3"`
+	// Title at each revision.
+	FromTitle string `json:"fromTitle" example:"SouLxBurN Is Awesome!"`
+	ToTitle   string `json:"toTitle" example:"SouLxBurN Is Very Awesome!"`
+	// Lines added and deleted in the body.
+	Additions int `json:"additions" example:"1"`
+	Deletions int `json:"deletions" example:"1"`
+	// Body changes as a unified diff.
+	Unified string `json:"unified" example:"--- revision 2\n+++ revision 3\n@@ -1 +1 @@\n-# Old\n+# New\n"`
+	// Body changes with surrounding context.
+	Hunks []DiffHunk `json:"hunks"`
+}
+
+// DiffHunk
+// A run of body changes and their context. Starts are line numbers
+// counted from 1, or the line before for an empty range, as in
+// unified diff hunk headers.
+type DiffHunk struct {
+	OldStart int        `json:"oldStart" example:"1"`
+	OldLines int        `json:"oldLines" example:"1"`
+	NewStart int        `json:"newStart" example:"1"`
+	NewLines int        `json:"newLines" example:"1"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// DiffLine
+// A line of a hunk, one of context, add or delete.
+type DiffLine struct {
+	Op   string `json:"op" example:"add"`
+	Text string `json:"text" example:"# New"`
+	// Line number in the old and new body, 0 when absent from it.
+	OldLine int `json:"oldLine,omitempty" example:"0"`
+	NewLine int `json:"newLine,omitempty" example:"1"`
+	// Whether the line ends the body without a newline.
+	NoNewline bool `json:"noNewline,omitempty"`
+	// Word changes from the paired deleted or added line, with word diffs.
+	Words []WordChange `json:"words,omitempty"`
+}
+
+// WordChange
+// A run of words kept, inserted or deleted within a line.
+type WordChange struct {
+	Op   string `json:"op" example:"insert"`
+	Text string `json:"text" example:"Very "`
+}
+
+// diffBodies
+// Returns the changes from old to new with contextLines unchanged lines
+// around each. When words is set, each deleted line followed by an added
+// one records the words changed between them.
+func diffBodies(old string, new string, contextLines int, words bool) (hunks []DiffHunk, additions int, deletions int) {
+	oldLines, oldEOL := splitLines(old)
+	newLines, newEOL := splitLines(new)
+	// A last line without a newline only matches another one.
+	oldKeys, newKeys := oldLines, newLines
+	if !oldEOL {
+		oldKeys = markLastLine(oldLines)
+	}
+	if !newEOL {
+		newKeys = markLastLine(newLines)
+	}
+
+	// Every line of both bodies in order, as context, delete or add,
+	// with the number of old and new lines before it.
+	var script []DiffLine
+	var before []match
+	i, j := 0, 0
+	line := func(op string) {
+		before = append(before, match{i, j})
+		l := DiffLine{Op: op}
+		if op != DiffAdd {
+			i++
+			l.Text, l.OldLine, l.NoNewline = oldLines[i-1], i, i == len(oldLines) && !oldEOL
+		}
+		if op != DiffDelete {
+			j++
+			l.Text, l.NewLine, l.NoNewline = newLines[j-1], j, j == len(newLines) && !newEOL
+		}
+		script = append(script, l)
+	}
+	emit := func(a int, b int) {
+		for i < a {
+			line(DiffDelete)
+			deletions++
+		}
+		for j < b {
+			line(DiffAdd)
+			additions++
+		}
+	}
+	for _, m := range matchLines(oldKeys, newKeys) {
+		emit(m.A, m.B)
+		line(DiffContext)
+	}
+	emit(len(oldLines), len(newLines))
+
+	for start := 0; start < len(script); {
+		for start < len(script) && script[start].Op == DiffContext {
+			start++
+		}
+		if start == len(script) {
+			break
+		}
+		// Extend the hunk while changes are separated by at most twice the context.
+		end := start
+		for k := start; k < len(script) && k-end <= 2*contextLines; k++ {
+			if script[k].Op != DiffContext {
+				end = k + 1
+			}
+		}
+		from, to := start-contextLines, end+contextLines
+		if from < 0 {
+			from = 0
+		}
+		if to > len(script) {
+			to = len(script)
+		}
+		hunks = append(hunks, newHunk(script[from:to], before[from], words))
+		start = to
+	}
+	return hunks, additions, deletions
+}
+
+// newHunk
+// Returns the hunk of lines, which follow the old and new lines counted by before.
+func newHunk(lines []DiffLine, before match, words bool) DiffHunk {
+	hunk := DiffHunk{OldStart: before.A, NewStart: before.B, Lines: append([]DiffLine(nil), lines...)}
+	for _, line := range lines {
+		if line.Op != DiffAdd {
+			hunk.OldLines++
+		}
+		if line.Op != DiffDelete {
+			hunk.NewLines++
+		}
+	}
+	// Empty ranges start at the line before the hunk.
+	if hunk.OldLines > 0 {
+		hunk.OldStart++
+	}
+	if hunk.NewLines > 0 {
+		hunk.NewStart++
+	}
+	if words {
+		pairWords(hunk.Lines)
+	}
+	return hunk
+}
+
+// markLastLine
+// Returns lines with the last changed, so a last line without a
+// newline matches no line of the other body ending with one.
+func markLastLine(lines []string) []string {
+	if len(lines) == 0 {
+		return lines
+	}
+	marked := append([]string(nil), lines...)
+	marked[len(marked)-1] += "\x00"
+	return marked
+}
+
+// pairWords
+// Records the word changes between each run of deleted lines and
+// the added lines following it, pairing them in order.
+func pairWords(lines []DiffLine) {
+	for k := 0; k < len(lines); {
+		deleted := k
+		for k < len(lines) && lines[k].Op == DiffDelete {
+			k++
+		}
+		added := k
+		for k < len(lines) && lines[k].Op == DiffAdd {
+			k++
+		}
+		for n := 0; n < added-deleted && added+n < k; n++ {
+			lines[deleted+n].Words, lines[added+n].Words = diffWords(lines[deleted+n].Text, lines[added+n].Text)
+		}
+		if k == deleted {
+			k++
+		}
+	}
+}
+
+var wordPattern = regexp.MustCompile(`\s+|[\p{L}\p{N}_]+|.`)
+
+// diffWords
+// Returns the words of old kept and deleted, and of new kept and inserted.
+func diffWords(old string, new string) (oldWords []WordChange, newWords []WordChange) {
+	a, b := wordPattern.FindAllString(old, -1), wordPattern.FindAllString(new, -1)
+	i, j := 0, 0
+	appendWord := func(words []WordChange, op string, text string) []WordChange {
+		if last := len(words) - 1; last >= 0 && words[last].Op == op {
+			words[last].Text += text
+			return words
+		}
+		return append(words, WordChange{Op: op, Text: text})
+	}
+	for _, m := range append(matchLines(a, b), match{len(a), len(b)}) {
+		for ; i < m.A; i++ {
+			oldWords = appendWord(oldWords, WordDelete, a[i])
+		}
+		for ; j < m.B; j++ {
+			newWords = appendWord(newWords, WordInsert, b[j])
+		}
+		if i < len(a) {
+			oldWords = appendWord(oldWords, WordEqual, a[i])
+			newWords = appendWord(newWords, WordEqual, b[j])
+			i, j = i+1, j+1
+		}
+	}
+	return oldWords, newWords
+}
+
+// renderUnified
+// Renders the hunks as a unified diff between the named files.
+func renderUnified(hunks []DiffHunk, oldName string, newName string) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for _, hunk := range hunks {
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(hunk.OldStart, hunk.OldLines), hunkRange(hunk.NewStart, hunk.NewLines))
+		for _, line := range hunk.Lines {
+			switch line.Op {
+			case DiffAdd:
+				b.WriteByte('+')
+			case DiffDelete:
+				b.WriteByte('-')
+			default:
+				b.WriteByte(' ')
+			}
+			b.WriteString(line.Text)
+			b.WriteByte('\n')
+			if line.NoNewline {
+				b.WriteString("\\ No newline at end of file\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+func hunkRange(start int, lines int) string {
+	if lines == 1 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// HTML
+// Renders the hunks as an HTML table, one row per line with the
+// classes diff-hunk, diff-context, diff-add and diff-delete. Changed
+// words are marked with ins and del when the diff has word changes.
+func (d *SnippetDiff) HTML() string {
+	var b strings.Builder
+	b.WriteString(`<table class="diff">` + "\n")
+	for _, hunk := range d.Hunks {
+		fmt.Fprintf(&b, `<tr class="diff-hunk"><td colspan="3">@@ -%s +%s @@</td></tr>`+"\n",
+			hunkRange(hunk.OldStart, hunk.OldLines), hunkRange(hunk.NewStart, hunk.NewLines))
+		for _, line := range hunk.Lines {
+			fmt.Fprintf(&b, `<tr class="diff-%s"><td class="diff-old">%s</td><td class="diff-new">%s</td><td class="diff-text">`,
+				line.Op, lineNumber(line.OldLine), lineNumber(line.NewLine))
+			switch {
+			case line.Words != nil:
+				for _, word := range line.Words {
+					text := html.EscapeString(word.Text)
+					switch word.Op {
+					case WordInsert:
+						b.WriteString("<ins>" + text + "</ins>")
+					case WordDelete:
+						b.WriteString("<del>" + text + "</del>")
+					default:
+						b.WriteString(text)
+					}
+				}
+			case line.Op == DiffAdd:
+				b.WriteString("<ins>" + html.EscapeString(line.Text) + "</ins>")
+			case line.Op == DiffDelete:
+				b.WriteString("<del>" + html.EscapeString(line.Text) + "</del>")
+			default:
+				b.WriteString(html.EscapeString(line.Text))
+			}
+			b.WriteString("</td></tr>\n")
+		}
+	}
+	b.WriteString("</table>\n")
+	return b.String()
+}
+
+func lineNumber(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
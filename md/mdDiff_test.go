@@ -0,0 +1,135 @@
+package md
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_DiffBodies
+// Changes are grouped into hunks with context and numbered like diff -u.
+func Test_DiffBodies(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	new := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+
+	hunks, additions, deletions := diffBodies(old, new, 1, false)
+	assert.Equal(t, 2, additions)
+	assert.Equal(t, 1, deletions)
+	assert.Len(t, hunks, 2)
+	assert.Equal(t, "--- revision 1\n+++ revision 2\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n@@ -10 +10,2 @@\n j\n+k\n",
+		renderUnified(hunks, "revision 1", "revision 2"))
+
+	hunks, _, _ = diffBodies(old, new, 3, false)
+	assert.Len(t, hunks, 2)
+	hunks, _, _ = diffBodies(old, new, 4, false)
+	assert.Len(t, hunks, 1)
+
+	hunks, _, _ = diffBodies("", "# New\n", 3, false)
+	assert.Equal(t, DiffHunk{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1, Lines: []DiffLine{{Op: DiffAdd, Text: "# New", NewLine: 1}}}, hunks[0])
+
+	hunks, _, _ = diffBodies("same", "same", 3, false)
+	assert.Empty(t, hunks)
+	assert.Empty(t, renderUnified(hunks, "a", "b"))
+}
+
+// Test_DiffRoundTrip
+// Unified diffs of random bodies, with and without trailing
+// newlines, apply as patches turning the old body into the new.
+func Test_DiffRoundTrip(t *testing.T) {
+	random := rand.New(rand.NewSource(11))
+	body := func() string {
+		lines := make([]string, random.Intn(12))
+		for i := range lines {
+			lines[i] = []string{"", "a", "b", "c", "# d"}[random.Intn(5)]
+		}
+		text := strings.Join(lines, "\n")
+		if len(lines) > 0 && random.Intn(2) == 0 {
+			text += "\n"
+		}
+		return text
+	}
+	for n := 0; n < 500; n++ {
+		old, new := body(), body()
+		hunks, _, _ := diffBodies(old, new, random.Intn(4), false)
+		if len(hunks) == 0 {
+			assert.Equal(t, old, new)
+			continue
+		}
+		patch, err := ParseUnifiedDiff(renderUnified(hunks, "old", "new"))
+		if !assert.Nil(t, err, "%q -> %q", old, new) {
+			continue
+		}
+		snippet := &MarkdownSnippet{Body: old}
+		assert.Nil(t, patch.Apply(snippet))
+		assert.Equal(t, new, snippet.Body, "%q -> %q", old, new)
+	}
+}
+
+// Test_DiffWords
+// Paired changed lines record their word changes, rendered as ins and del.
+func Test_DiffWords(t *testing.T) {
+	hunks, _, _ := diffBodies("The quick fox.\n", "The slow <fox>.\n", 3, true)
+	lines := hunks[0].Lines
+	assert.Equal(t, []WordChange{{WordEqual, "The "}, {WordDelete, "quick"}, {WordEqual, " fox."}}, lines[0].Words)
+	assert.Equal(t, []WordChange{{WordEqual, "The "}, {WordInsert, "slow"}, {WordEqual, " "}, {WordInsert, "<"}, {WordEqual, "fox"}, {WordInsert, ">"}, {WordEqual, "."}}, lines[1].Words)
+
+	html := (&SnippetDiff{Hunks: hunks}).HTML()
+	assert.Contains(t, html, `<tr class="diff-add"><td class="diff-old"></td><td class="diff-new">1</td><td class="diff-text">The <ins>slow</ins> <ins>&lt;</ins>fox<ins>&gt;</ins>.</td></tr>`)
+	assert.Contains(t, html, `<td class="diff-text">The <del>quick</del> fox.</td>`)
+}
+
+// Test_DiffHandlerValidation
+// Invalid revisions and options are rejected before the snippet is read.
+func Test_DiffHandlerValidation(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: api.ErrorHandler})
+	InitMDHandlers(SetupUnreachableMDService(t, config.Timeouts{})).ConfigureRoutes(app)
+
+	for _, query := range []string{"from=0", "to=latest", "context=-1", "context=101", "format=xml"} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/md/id/diff?"+query, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+// Test_DiffRevisions
+// Revisions compare with each other, the current
+// snippet, and the empty snippet before the first.
+func Test_DiffRevisions(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := context.Background()
+
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Runbook", Body: "# Steps\none\n"})
+	assert.Nil(t, err)
+	_, err = mdService.UpdateMarkdownSnippet(ctx, &UpdateMDReq{CreateMDReq: CreateMDReq{Title: "Runbook v2", Body: "# Steps\none\ntwo\n"}, ID: snippet.ID, UpdateKey: snippet.UpdateKey})
+	assert.Nil(t, err)
+
+	diff, err := mdService.DiffRevisions(ctx, snippet.ID, DiffParams{Context: DefaultDiffContext})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), diff.From)
+	assert.Equal(t, int64(2), diff.To)
+	assert.Equal(t, "Runbook v2", diff.ToTitle)
+	assert.Equal(t, 1, diff.Additions)
+	assert.Equal(t, "--- revision 1\n+++ revision 2\n@@ -1,2 +1,3 @@\n # Steps\n one\n+two\n", diff.Unified)
+
+	diff, err = mdService.DiffRevisions(ctx, snippet.ID, DiffParams{To: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), diff.From)
+	assert.Equal(t, 2, diff.Additions)
+
+	diff, err = mdService.DiffRevisions(ctx, snippet.ID, DiffParams{From: CurrentRevision, To: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, diff.Deletions)
+
+	_, err = mdService.DiffRevisions(ctx, snippet.ID, DiffParams{From: 7})
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
@@ -0,0 +1,133 @@
+package md
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// OutboxCollection Collection of lifecycle events awaiting dispatch.
+const OutboxCollection = "outbox"
+
+// Snippet lifecycle event types.
+const (
+	EventCreated  = "snippet.created"
+	EventUpdated  = "snippet.updated"
+	EventDeleted  = "snippet.deleted"
+	EventRestored = "snippet.restored"
+)
+
+// Event
+// A snippet lifecycle event, persisted to the outbox
+// alongside the write that caused it.
+type Event struct {
+	// Event guid.
+	ID string `json:"id" bson:"id" format:"uuid"`
+	// One of snippet.created, snippet.updated, snippet.deleted or snippet.restored.
+	Type string `json:"type" bson:"type" example:"snippet.created"`
+	// Affected markdown snippet guid.
+	SnippetID string `json:"snippetId" bson:"snippetId" format:"uuid"`
+	// Snippet after the change, without its update key. Unset for deletes.
+	Snippet *MarkdownSnippet `json:"snippet,omitempty" bson:"snippet,omitempty"`
+	// Date the change was committed.
+	CreateDate time.Time `json:"createDate" bson:"createDate" format:"date-time"`
+	// Tenant the snippet belongs to, empty when tenancy is disabled.
+	Tenant string `json:"-" bson:"tenant"`
+	// Date the event was handed to subscribers, unset while pending.
+	DispatchedAt *time.Time `json:"-" bson:"dispatchedAt,omitempty"`
+}
+
+// newEvent
+// Returns an outbox event for snippet in the service's tenant.
+func (m *MDService) newEvent(eventType string, snippetID string, snippet *MarkdownSnippet) *Event {
+	if snippet != nil {
+		copied := *snippet
+		copied.UpdateKey = ""
+		snippet = &copied
+	}
+	return &Event{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		SnippetID:  snippetID,
+		Snippet:    snippet,
+		CreateDate: time.Now(),
+		Tenant:     m.tenant,
+	}
+}
+
+// outbox
+// Returns the outbox collection, always in the configured
+// database so one dispatcher serves every tenant.
+func (m *MDService) outbox() *mongo.Collection {
+	return m.client.Database(m.mongo.Database).Collection(OutboxCollection)
+}
+
+// publish
+// Inserts events into the outbox.
+func (m *MDService) publish(ctx context.Context, events ...*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(events))
+	for i, event := range events {
+		docs[i] = event
+	}
+	_, err := m.outbox().InsertMany(ctx, docs)
+	return err
+}
+
+// transactions
+// Caches whether the deployment supports multi-document transactions.
+type transactions struct {
+	mu        sync.Mutex
+	checked   bool
+	supported bool
+}
+
+// withTransaction
+// Runs fn in a transaction when the deployment is a replica set
+// or sharded cluster, so a write and its outbox event commit together.
+// Standalone servers run fn directly.
+func (m *MDService) withTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !m.supportsTransactions(ctx) {
+		return fn(ctx)
+	}
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+// supportsTransactions
+// Asks the server once whether it is a replica set member or mongos.
+// A failed check is retried on the next call.
+func (m *MDService) supportsTransactions(ctx context.Context) bool {
+	txn := m.transactions
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	if txn.checked {
+		return txn.supported
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	cmd := bson.D{{Key: "isMaster", Value: 1}}
+	if err := m.client.Database("admin").RunCommand(ctx, cmd).Decode(&hello); err != nil {
+		return false
+	}
+	txn.checked = true
+	txn.supported = hello.SetName != "" || hello.Msg == "isdbgrid"
+	return txn.supported
+}
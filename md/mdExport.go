@@ -0,0 +1,70 @@
+package md
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Export formats accepted by GET /md/export.
+const (
+	ExportJSONL = "jsonl"
+	ExportZip   = "zip"
+)
+
+// writeJSONLines
+// Writes each snippet of cursor to w as a line of JSON.
+// Returns the number of snippets written.
+func writeJSONLines(w io.Writer, cursor *SnippetCursor) (int, error) {
+	encoder := json.NewEncoder(w)
+	count := 0
+	for cursor.Next() {
+		if err := encoder.Encode(cursor.Snippet()); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, cursor.Err()
+}
+
+// writeZip
+// Writes each snippet of cursor to a zip archive on w as
+// `<id>.md`, with its title in front matter so it can be imported.
+// Returns the number of snippets written.
+func writeZip(w io.Writer, cursor *SnippetCursor) (int, error) {
+	archive := zip.NewWriter(w)
+	count := 0
+	for cursor.Next() {
+		snippet := cursor.Snippet()
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     snippet.ID + ".md",
+			Method:   zip.Deflate,
+			Modified: snippet.CreateDate,
+		})
+		if err != nil {
+			return count, err
+		}
+		if _, err := io.WriteString(file, markdownFile(snippet)); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return count, err
+	}
+	return count, archive.Close()
+}
+
+// markdownFile
+// Renders snippet as markdown with a front matter header.
+func markdownFile(snippet *MarkdownSnippet) string {
+	return fmt.Sprintf("---\ntitle: %s\nid: %s\ncreateDate: %s\n---\n%s",
+		strconv.Quote(snippet.Title),
+		snippet.ID,
+		snippet.CreateDate.UTC().Format(time.RFC3339),
+		snippet.Body,
+	)
+}
@@ -0,0 +1,122 @@
+package md
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"path"
+	"strings"
+)
+
+// BodyFileName Name the snippet body is served and archived as.
+const BodyFileName = "README.md"
+
+// fileLanguages Languages detected from file extensions.
+var fileLanguages = map[string]string{
+	".bash": "shell",
+	".c":    "c",
+	".conf": "ini",
+	".cpp":  "cpp",
+	".cs":   "csharp",
+	".css":  "css",
+	".go":   "go",
+	".html": "html",
+	".ini":  "ini",
+	".java": "java",
+	".js":   "javascript",
+	".json": "json",
+	".md":   "markdown",
+	".php":  "php",
+	".ps1":  "powershell",
+	".py":   "python",
+	".rb":   "ruby",
+	".rs":   "rust",
+	".sh":   "shell",
+	".sql":  "sql",
+	".tf":   "hcl",
+	".toml": "toml",
+	".ts":   "typescript",
+	".xml":  "xml",
+	".yaml": "yaml",
+	".yml":  "yaml",
+}
+
+// fileNameLanguages Languages detected from whole file names.
+var fileNameLanguages = map[string]string{
+	"dockerfile": "dockerfile",
+	"makefile":   "makefile",
+}
+
+// fileLanguage
+// Returns the language of a file detected from its name, `text` when unknown.
+func fileLanguage(name string) string {
+	lower := strings.ToLower(name)
+	if language, ok := fileNameLanguages[lower]; ok {
+		return language
+	}
+	if language, ok := fileLanguages[path.Ext(lower)]; ok {
+		return language
+	}
+	return "text"
+}
+
+// prepareFiles
+// Returns a copy of files with their sizes set and missing
+// languages detected. nil stays nil, so updates can tell
+// omitted files from an empty list.
+func prepareFiles(files []SnippetFile) []SnippetFile {
+	if files == nil {
+		return nil
+	}
+	prepared := make([]SnippetFile, len(files))
+	for i, file := range files {
+		if file.Language == "" {
+			file.Language = fileLanguage(file.Name)
+		}
+		file.Size = len(file.Content)
+		prepared[i] = file
+	}
+	return prepared
+}
+
+// GetSnippetFile
+// Returns the named file of a snippet, the body as BodyFileName.
+// Returns ErrNotFound when the snippet does not exist,
+// or ErrFileNotFound when it has no such file.
+func (m *MDService) GetSnippetFile(ctx context.Context, mdID string, name string) (*SnippetFile, error) {
+	snippet, err := m.GetMarkdownSnippet(ctx, mdID)
+	if err != nil {
+		return nil, err
+	}
+	if name == BodyFileName {
+		return &SnippetFile{Name: BodyFileName, Language: "markdown", Content: snippet.Body, Size: len(snippet.Body)}, nil
+	}
+	for _, file := range snippet.Files {
+		if file.Name == name {
+			return &file, nil
+		}
+	}
+	return nil, snippetError("getFile", mdID, ErrFileNotFound)
+}
+
+// WriteSnippetZip
+// Writes snippet to a zip archive on w, its body as
+// BodyFileName followed by each of its files.
+func WriteSnippetZip(w io.Writer, snippet *MarkdownSnippet) error {
+	archive := zip.NewWriter(w)
+	files := append([]SnippetFile{{Name: BodyFileName, Content: snippet.Body}}, snippet.Files...)
+	for _, file := range files {
+		entry, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.Name,
+			Method:   zip.Deflate,
+			Modified: snippet.CreateDate,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(entry, file.Content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,152 @@
+package md
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Test_FileLanguage
+// Languages are detected from extensions and well known names.
+func Test_FileLanguage(t *testing.T) {
+	assert.Equal(t, "shell", fileLanguage("deploy.sh"))
+	assert.Equal(t, "yaml", fileLanguage("config/app.YML"))
+	assert.Equal(t, "dockerfile", fileLanguage("Dockerfile"))
+	assert.Equal(t, "text", fileLanguage("notes"))
+
+	assert.Nil(t, prepareFiles(nil))
+	files := prepareFiles([]SnippetFile{{Name: "run.py", Content: "print('é')"}, {Name: "run", Language: "bash", Content: "ls"}})
+	assert.Equal(t, []SnippetFile{{Name: "run.py", Language: "python", Content: "print('é')", Size: 11}, {Name: "run", Language: "bash", Content: "ls", Size: 2}}, files)
+}
+
+// Test_FileValidation
+// File names must be unique plain names, other than the body's.
+func Test_FileValidation(t *testing.T) {
+	req := func(files ...SnippetFile) *CreateMDReq {
+		return &CreateMDReq{Title: "Runbook", Body: "# Runbook", Files: files}
+	}
+	assert.Nil(t, api.ValidateStruct(req(SnippetFile{Name: "deploy.sh", Content: "ls"}, SnippetFile{Name: ".env", Content: "A=1"})))
+
+	for _, name := range []string{"", "bin/deploy.sh", `bin\deploy.sh`, ".", "..", BodyFileName, strings.Repeat("a", 129)} {
+		assert.NotNil(t, api.ValidateStruct(req(SnippetFile{Name: name, Content: "ls"})), name)
+	}
+	assert.NotNil(t, api.ValidateStruct(req(SnippetFile{Name: "a", Content: "ls"}, SnippetFile{Name: "a", Content: "ls"})))
+	assert.NotNil(t, api.ValidateStruct(req(SnippetFile{Name: "a"})))
+	assert.NotNil(t, api.ValidateStruct(req(make([]SnippetFile, 21)...)))
+}
+
+// Test_WriteSnippetZip
+// Archives hold the body as README.md followed by the files.
+func Test_WriteSnippetZip(t *testing.T) {
+	out := new(bytes.Buffer)
+	assert.Nil(t, WriteSnippetZip(out, &MarkdownSnippet{Body: "# Runbook", Files: []SnippetFile{{Name: "deploy.sh", Content: "ls"}}}))
+
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	assert.Nil(t, err)
+	contents := make(map[string]string)
+	for _, file := range archive.File {
+		r, err := file.Open()
+		assert.Nil(t, err)
+		content, _ := io.ReadAll(r)
+		contents[file.Name] = string(content)
+	}
+	assert.Equal(t, map[string]string{BodyFileName: "# Runbook", "deploy.sh": "ls"}, contents)
+}
+
+// Test_Files
+// Files are stored, served, searched and kept by updates that omit them.
+func Test_Files(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := context.Background()
+	_, err := mdService.getMarkdownCollection().Indexes().CreateMany(ctx, IndexModels(false))
+	assert.Nil(t, err)
+
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{
+		Title: "Runbook",
+		Body:  "# Runbook",
+		Files: []SnippetFile{{Name: "deploy.sh", Content: "kubectl rollout restart"}, {Name: "app.yaml", Content: "replicas: 3"}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "shell", snippet.Files[0].Language)
+
+	file, err := mdService.GetSnippetFile(ctx, snippet.ID, "app.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, "replicas: 3", file.Content)
+	file, err = mdService.GetSnippetFile(ctx, snippet.ID, BodyFileName)
+	assert.Nil(t, err)
+	assert.Equal(t, "# Runbook", file.Content)
+	_, err = mdService.GetSnippetFile(ctx, snippet.ID, "missing.sh")
+	assert.True(t, errors.Is(err, ErrFileNotFound))
+
+	found, err := mdService.SearchMarkdownSnippets(ctx, MDSearchParams{Text: "kubectl", Limit: 10})
+	assert.Nil(t, err)
+	assert.Len(t, found, 1)
+
+	app := fiber.New(fiber.Config{ErrorHandler: api.ErrorHandler})
+	InitMDHandlers(mdService).ConfigureRoutes(app)
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/md/"+snippet.ID+"/files/deploy.sh", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, fiber.MIMETextPlainCharsetUTF8, resp.Header.Get(fiber.HeaderContentType))
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "kubectl rollout restart", string(body))
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/md/"+snippet.ID+"/zip", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "application/zip", resp.Header.Get(fiber.HeaderContentType))
+
+	update := &UpdateMDReq{CreateMDReq: CreateMDReq{Title: "Runbook", Body: "# Runbook v2"}, ID: snippet.ID, UpdateKey: snippet.UpdateKey}
+	updated, err := mdService.UpdateMarkdownSnippet(ctx, update)
+	assert.Nil(t, err)
+	assert.Len(t, updated.Files, 2)
+	update.Files = []SnippetFile{}
+	updated, err = mdService.UpdateMarkdownSnippet(ctx, update)
+	assert.Nil(t, err)
+	assert.Empty(t, updated.Files)
+
+	revision, err := mdService.GetRevision(ctx, snippet.ID, 1)
+	assert.Nil(t, err)
+	assert.Len(t, revision.Files, 2)
+}
+
+// Test_UpgradeTextIndex
+// Text indexes from before files were searched are rebuilt
+// with their weights, files weighted like the body.
+func Test_UpgradeTextIndex(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := context.Background()
+	collection := mdService.getMarkdownCollection()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "title", Value: "text"}, {Key: "body", Value: "text"}},
+		Options: options.Index().SetName(TextIndexName).SetWeights(bson.M{"title": 10, "body": 2}).SetDefaultLanguage("spanish"),
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, UpgradeTextIndex(ctx, collection))
+	assert.Nil(t, UpgradeTextIndex(ctx, collection))
+
+	cursor, err := collection.Indexes().List(ctx)
+	assert.Nil(t, err)
+	var indexes []bson.M
+	assert.Nil(t, cursor.All(ctx, &indexes))
+	for _, index := range indexes {
+		if index["name"] == TextIndexName {
+			assert.EqualValues(t, bson.M{"title": int32(10), "body": int32(2), "files.content": int32(2)}, index["weights"])
+			assert.Equal(t, "spanish", index["default_language"])
+		}
+	}
+}
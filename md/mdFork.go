@@ -0,0 +1,84 @@
+package md
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// ForkIndexModels
+// Returns the markdown collection index listing a snippet's forks.
+// Applied to the configured database by migration 6.
+func ForkIndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{
+				{Key: "forkedFrom", Value: bsonx.Int32(1)},
+				{Key: "createDate", Value: bsonx.Int32(1)},
+			},
+			Options: options.Index().SetSparse(true),
+		},
+	}
+}
+
+// ForkMarkdownSnippet
+// Copies the current revision of a snippet and its files into a new
+// snippet, with its own id and update key, recording where it was forked from.
+// An empty title keeps the title of the forked snippet.
+// Returns ErrNotFound when the snippet does not exist.
+func (m *MDService) ForkMarkdownSnippet(ctx context.Context, mdID string, title string) (*MarkdownSnippet, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Write)
+	defer cancel()
+	ctx, end := startOperation(ctx, "fork")
+	defer end()
+
+	parent, err := m.GetMarkdownSnippet(ctx, mdID)
+	if err != nil {
+		return nil, err
+	}
+	if title == "" {
+		title = parent.Title
+	}
+
+	fork := m.newSnippet(title, parent.Body)
+	fork.Files = parent.Files
+	fork.ForkedFrom = parent.ID
+	fork.Lineage = append([]ForkRef{{ID: parent.ID, Revision: parent.Revision}}, parent.Lineage...)
+	if err := m.insert(ctx, "fork", fork, SourceFork, SourceFork); err != nil {
+		return nil, err
+	}
+	return fork, nil
+}
+
+// ListForks
+// Returns the snippets forked directly from a snippet
+// without their body, oldest first.
+// Returns ErrNotFound when the snippet does not exist.
+func (m *MDService) ListForks(ctx context.Context, mdID string) ([]MDListItem, error) {
+	if _, err := m.GetMarkdownSnippet(ctx, mdID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Search)
+	defer cancel()
+	ctx, end := startOperation(ctx, "listForks")
+	defer end()
+
+	forks := make([]MDListItem, 0)
+	opts := options.Find().
+		SetProjection(listProjection).
+		SetSort(bson.D{{Key: "createDate", Value: 1}})
+	cursor, err := m.getMarkdownCollection().Find(ctx, m.active(bson.D{{Key: "forkedFrom", Value: mdID}}), opts)
+	if err != nil {
+		recordMongoError(ctx, "listForks", mdID, err)
+		return nil, err
+	}
+	if err := cursor.All(ctx, &forks); err != nil {
+		recordMongoError(ctx, "listForks", mdID, err)
+		return nil, err
+	}
+	return forks, nil
+}
@@ -0,0 +1,84 @@
+package md
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_ForkHandlerValidation
+// Fork titles are limited like snippet titles.
+func Test_ForkHandlerValidation(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: api.ErrorHandler})
+	InitMDHandlers(SetupUnreachableMDService(t, config.Timeouts{})).ConfigureRoutes(app)
+
+	body := `{"title":"` + strings.Repeat("a", 65) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/md/id/fork", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// Test_Fork
+// Forks are independent copies recording the snippets they descend from.
+func Test_Fork(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := context.Background()
+
+	original, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Runbook", Body: "# Steps\none\n"})
+	assert.Nil(t, err)
+	_, err = mdService.UpdateMarkdownSnippet(ctx, &UpdateMDReq{CreateMDReq: CreateMDReq{Title: "Runbook", Body: "# Steps\none\ntwo\n"}, ID: original.ID, UpdateKey: original.UpdateKey})
+	assert.Nil(t, err)
+
+	fork, err := mdService.ForkMarkdownSnippet(ctx, original.ID, "")
+	assert.Nil(t, err)
+	assert.NotEqual(t, original.ID, fork.ID)
+	assert.NotEmpty(t, fork.UpdateKey)
+	assert.NotEqual(t, original.UpdateKey, fork.UpdateKey)
+	assert.Equal(t, "Runbook", fork.Title)
+	assert.Equal(t, "# Steps\none\ntwo\n", fork.Body)
+	assert.Equal(t, int64(1), fork.Revision)
+	assert.Equal(t, original.ID, fork.ForkedFrom)
+	assert.Equal(t, []ForkRef{{ID: original.ID, Revision: 2}}, fork.Lineage)
+
+	nested, err := mdService.ForkMarkdownSnippet(ctx, fork.ID, "My Runbook")
+	assert.Nil(t, err)
+	assert.Equal(t, "My Runbook", nested.Title)
+	got, err := mdService.GetMarkdownSnippet(ctx, nested.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, []ForkRef{{ID: fork.ID, Revision: 1}, {ID: original.ID, Revision: 2}}, got.Lineage)
+
+	_, err = mdService.UpdateMarkdownSnippet(ctx, &UpdateMDReq{CreateMDReq: CreateMDReq{Title: "Mine", Body: "# Mine"}, ID: fork.ID, UpdateKey: fork.UpdateKey})
+	assert.Nil(t, err)
+	got, err = mdService.GetMarkdownSnippet(ctx, original.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "# Steps\none\ntwo\n", got.Body)
+
+	forks, err := mdService.ListForks(ctx, original.ID)
+	assert.Nil(t, err)
+	assert.Len(t, forks, 1)
+	assert.Equal(t, fork.ID, forks[0].ID)
+	assert.Equal(t, original.ID, forks[0].ForkedFrom)
+	forks, err = mdService.ListForks(ctx, nested.ID)
+	assert.Nil(t, err)
+	assert.Empty(t, forks)
+
+	revisions, err := mdService.ListRevisions(ctx, nested.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, SourceFork, revisions[0].Source)
+
+	_, err = mdService.ForkMarkdownSnippet(ctx, "missing", "")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = mdService.ListForks(ctx, "missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
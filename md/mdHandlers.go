@@ -1,13 +1,21 @@
 package md
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
 	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/logging"
 )
 
 type MDHandlers struct {
@@ -20,6 +28,12 @@ func InitMDHandlers(mdService *MDService) *MDHandlers {
 	return &MDHandlers{mdService: mdService}
 }
 
+// service
+// Returns the MDService scoped to the request tenant.
+func (m *MDHandlers) service(ctx *fiber.Ctx) *MDService {
+	return m.mdService.ForTenant(api.Tenant(ctx))
+}
+
 // ConfiugureRoutes
 // Imports and configures various routes for
 // all modules.
@@ -27,9 +41,34 @@ func (m *MDHandlers) ConfigureRoutes(app *fiber.App) {
 	app.Post("/md", m.CreateMDHandler)
 	app.Patch("/md", m.UpdateMDHandler)
 	app.Get("/md/search", m.SearchMDHandler)
+	app.Post("/md/import", m.ImportMDHandler)
+	app.Get("/md/export", m.ExportMDHandler)
+	app.Get("/md/:id/events", m.EventsMDHandler)
+	app.Get("/md/:id/revisions", m.ListRevisionsHandler)
+	app.Get("/md/:id/revisions/:revision", m.GetRevisionHandler)
+	app.Get("/md/:id/diff", m.DiffMDHandler)
+	app.Post("/md/:id/fork", m.ForkMDHandler)
+	app.Get("/md/:id/files/:name", m.GetFileHandler)
+	app.Get("/md/:id/zip", m.ZipMDHandler)
+	app.Post("/md/:id/attachments", m.UploadAttachmentHandler)
+	app.Get("/md/:id/attachments", m.ListAttachmentsHandler)
+	app.Get("/md/:id/attachments/:name", m.GetAttachmentHandler)
+	app.Delete("/md/:id/attachments/:name", m.DeleteAttachmentHandler)
+	app.Get("/md/:id/forks", m.ListForksHandler)
 	app.Get("/md/:id", m.GetMDHandler)
+	app.Patch("/md/:id", m.PatchMDHandler)
 	app.Get("/md", m.GetAllMDHandler)
 	app.Delete("/md/:id", m.DeleteMDHandler)
+	app.Post("/md/:id/restore", m.RestoreMDHandler)
+}
+
+// ConfigureAdminRoutes
+// Configures the administration routes on the
+// `/admin` router, which spans every tenant.
+func (m *MDHandlers) ConfigureAdminRoutes(admin fiber.Router) {
+	admin.Get("/audit", m.AuditHandler)
+	admin.Get("/audit/export", m.ExportAuditHandler)
+	admin.Get("/trash", m.ListTrashHandler)
 }
 
 // CreateMDHandler POST - creates a MarkdownSnippet from the provided body
@@ -39,26 +78,24 @@ func (m *MDHandlers) ConfigureRoutes(app *fiber.App) {
 // @Tags md
 // @Success 201 {object} MarkdownSnippet
 // @Failure 400 {object} api.ErrorResponse
-// @Failure 400 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
 // @Failure 500 {object} api.ErrorResponse
 // @Router /md [post]
 // @Param message body CreateMDReq true "Post Body"
 func (m *MDHandlers) CreateMDHandler(ctx *fiber.Ctx) error {
 	snippetRequest := new(CreateMDReq)
 	if err := ctx.BodyParser(snippetRequest); err != nil {
-		log.Printf("Failed to parse CreateMarkdownSnippet: %s", err)
+		logging.FromContext(ctx.UserContext()).WithError(err).Warn("Failed to parse CreateMarkdownSnippet")
 		return fiber.NewError(http.StatusBadRequest, err.Error())
 	}
 
 	if errs := api.ValidateStruct(snippetRequest); errs != nil {
-		ctx.Status(http.StatusBadRequest)
-		return ctx.JSON(errs)
+		return errs
 	}
 
-	newSnippet, err := m.mdService.CreateMarkdownSnippet(snippetRequest)
+	newSnippet, err := m.service(ctx).CreateMarkdownSnippet(ctx.UserContext(), snippetRequest)
 	if err != nil {
-		log.Printf("Failed in insert new MarkdownSnippet: %s", err)
-		return fiber.NewError(http.StatusInternalServerError, err.Error())
+		return httpError(err)
 	}
 
 	ctx.Status(http.StatusCreated)
@@ -71,27 +108,183 @@ func (m *MDHandlers) CreateMDHandler(ctx *fiber.Ctx) error {
 // @Produce json
 // @Tags md
 // @Success 200 {object} MarkdownSnippet
-// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
 // @Failure 500 {object} api.ErrorResponse
 // @Router /md/{id} [get]
 // @Param id path string true "Snippet ID"
 func (m *MDHandlers) GetMDHandler(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 
-	snippet, err := m.mdService.GetMarkdownSnippet(id)
+	snippet, err := m.service(ctx).GetMarkdownSnippet(ctx.UserContext(), id)
 	if err != nil {
-		log.Printf("Error Retrieving Markdown Snippet %s: %s", id, err)
-		ctx.Status(http.StatusInternalServerError)
-		return fiber.NewError(http.StatusInternalServerError, err.Error())
-	}
-	if snippet == nil && err == nil {
-		ctx.Status(http.StatusNotFound)
-		return fiber.NewError(http.StatusNotFound, "Markdown Snippet Not Found")
+		return httpError(err)
 	}
 
 	return ctx.JSON(snippet)
 }
 
+// EventsMDHandler GET - Streams live MarkdownSnippet changes
+// @Summary Stream live changes to a markdown snippet
+// @Description Server-Sent Events of `snippet.updated` and `snippet.deleted`, ending after a delete.
+// @Description Idle streams receive a `: heartbeat` comment. Send `Upgrade: websocket` to receive
+// @Description the same events as JSON WebSocket messages instead, kept alive with pings.
+// @Produce text/event-stream
+// @Tags md
+// @Success 200 {object} Event
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 429 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id}/events [get]
+// @Param id path string true "Snippet ID"
+func (m *MDHandlers) EventsMDHandler(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if _, err := m.service(ctx).GetMarkdownSnippet(ctx.UserContext(), id); err != nil {
+		return httpError(err)
+	}
+
+	live := m.mdService.Live()
+	sub, err := live.Subscribe(api.Tenant(ctx), id)
+	if err != nil {
+		return httpError(err)
+	}
+	// Both streams outlive the handler chain.
+	log := logging.FromContext(api.Detach(ctx.UserContext())).WithField("id", id)
+
+	if websocket.IsWebSocketUpgrade(ctx) {
+		err := websocket.New(func(conn *websocket.Conn) {
+			defer sub.Close()
+			if err := writeWebSocket(conn, sub, live.Heartbeat()); err != nil {
+				log.WithError(err).Debug("Live WebSocket closed")
+			}
+		})(ctx)
+		if err != nil {
+			sub.Close()
+		}
+		return err
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/event-stream")
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Set("X-Accel-Buffering", "no")
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+		if err := writeEventStream(w, sub, live.Heartbeat()); err != nil {
+			log.WithError(err).Debug("Live event stream closed")
+		}
+	})
+	return nil
+}
+
+// ListRevisionsHandler GET - Lists saved MarkdownSnippet revisions
+// @Summary List the revisions of a markdown snippet
+// @Description Revisions are listed newest first, without their bodies.
+// @Produce json
+// @Tags md
+// @Success 200 {object} []Revision
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id}/revisions [get]
+// @Param id path string true "Snippet ID"
+func (m *MDHandlers) ListRevisionsHandler(ctx *fiber.Ctx) error {
+	revisions, err := m.service(ctx).ListRevisions(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return httpError(err)
+	}
+	return ctx.JSON(revisions)
+}
+
+// GetRevisionHandler GET - MarkdownSnippet Revision Retrieval
+// @Summary Retrieve a revision of a markdown snippet
+// @Produce json
+// @Tags md
+// @Success 200 {object} Revision
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id}/revisions/{revision} [get]
+// @Param id path string true "Snippet ID"
+// @Param revision path int true "Revision Number"
+func (m *MDHandlers) GetRevisionHandler(ctx *fiber.Ctx) error {
+	rev, err := strconv.ParseInt(ctx.Params("revision"), 10, 64)
+	if err != nil || rev < 1 {
+		return fiber.NewError(http.StatusBadRequest, "revision: invalid value")
+	}
+	revision, err := m.service(ctx).GetRevision(ctx.UserContext(), ctx.Params("id"), rev)
+	if err != nil {
+		return httpError(err)
+	}
+	return ctx.JSON(revision)
+}
+
+// DiffMDHandler GET - Diffs MarkdownSnippet revisions
+// @Summary Compare two revisions of a markdown snippet
+// @Description Returns the body changes between revisions `from` and `to` as JSON hunks with a unified diff,
+// @Description or with `format` as the unified diff alone or an HTML table. Either revision may be `current`,
+// @Description `to` defaults to the current snippet and `from` to the revision before `to`.
+// @Description With `words`, changed lines record the words inserted and deleted.
+// @Produce json,plain,html
+// @Tags md
+// @Success 200 {object} SnippetDiff
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id}/diff [get]
+// @Param id path string true "Snippet ID"
+// @Param from query string false "Revision number or current"
+// @Param to query string false "Revision number or current"
+// @Param context query int false "Unchanged lines around each change" default(3)
+// @Param words query bool false "Include word changes"
+// @Param format query string false "Response format" Enums(json, unified, html) default(json)
+func (m *MDHandlers) DiffMDHandler(ctx *fiber.Ctx) error {
+	params := DiffParams{Words: ctx.Query("words") == "true"}
+	var err error
+	if params.From, err = revisionParam(ctx.Query("from")); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "from: invalid value")
+	}
+	if params.To, err = revisionParam(ctx.Query("to")); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "to: invalid value")
+	}
+	params.Context, err = strconv.Atoi(ctx.Query("context", strconv.Itoa(DefaultDiffContext)))
+	if err != nil || params.Context < 0 || params.Context > 100 {
+		return fiber.NewError(http.StatusBadRequest, "context: must be between 0 and 100")
+	}
+	format := ctx.Query("format", "json")
+	if format != "json" && format != "unified" && format != "html" {
+		return fiber.NewError(http.StatusBadRequest, "format: must be one of json, unified or html")
+	}
+
+	diff, err := m.service(ctx).DiffRevisions(ctx.UserContext(), ctx.Params("id"), params)
+	if err != nil {
+		return httpError(err)
+	}
+
+	switch format {
+	case "unified":
+		ctx.Set(fiber.HeaderContentType, DiffType+"; charset=utf-8")
+		return ctx.SendString(diff.Unified)
+	case "html":
+		ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return ctx.SendString(diff.HTML())
+	}
+	return ctx.JSON(diff)
+}
+
+// revisionParam
+// Parses a revision number or current, 0 when empty.
+func revisionParam(value string) (int64, error) {
+	switch value {
+	case "":
+		return 0, nil
+	case "current":
+		return CurrentRevision, nil
+	}
+	rev, err := strconv.ParseInt(value, 10, 64)
+	if err == nil && rev < 1 {
+		err = strconv.ErrRange
+	}
+	return rev, err
+}
+
 // GetAllMDHandler GET - Get All MarkdownSnippets Retrieval
 // @Deprecated
 // @Summary Retrieve All Markdown Snippets
@@ -99,15 +292,12 @@ func (m *MDHandlers) GetMDHandler(ctx *fiber.Ctx) error {
 // @Produce json
 // @Tags md
 // @Success 200 {object} []MDListItem
-// @Failure 400 {object} api.ErrorResponse
 // @Failure 500 {object} api.ErrorResponse
 // @Router /md [get]
 func (m *MDHandlers) GetAllMDHandler(ctx *fiber.Ctx) error {
-	snippets, err := m.mdService.GetAllMarkdownSnippets()
+	snippets, err := m.service(ctx).GetAllMarkdownSnippets(ctx.UserContext())
 	if err != nil {
-		log.Printf("Error Retrieving All Markdown Snippet: %s", err)
-		ctx.Status(http.StatusInternalServerError)
-		return fiber.NewError(http.StatusBadRequest, err.Error())
+		return httpError(err)
 	}
 
 	return ctx.JSON(snippets)
@@ -127,88 +317,296 @@ func (m *MDHandlers) GetAllMDHandler(ctx *fiber.Ctx) error {
 // @Failure 500 {object} api.ErrorResponse
 // @Router /md/search [get]
 func (m *MDHandlers) SearchMDHandler(ctx *fiber.Ctx) error {
-	limit, err := strconv.ParseInt(ctx.Query("limit", "10"), 10, 64)
+	params, err := searchParams(ctx, "10")
 	if err != nil {
-		return fiber.NewError(http.StatusBadRequest, "limit: invalid value")
+		return err
 	}
-	skip, err := strconv.ParseInt(ctx.Query("skip", "0"), 10, 64)
+
+	snippets, err := m.service(ctx).SearchMarkdownSnippets(ctx.UserContext(), params)
+	if err != nil {
+		return httpError(err)
+	}
+
+	return ctx.JSON(snippets)
+}
+
+// ImportMDHandler POST - Creates MarkdownSnippets in bulk
+// @Summary Import markdown snippets in bulk
+// @Description Accepts JSON lines of `CreateMDReq`, or a zip or tarball (optionally gzipped) of `.md` files.
+// @Description Files are titled by their front matter `title`, or else their file name.
+// @Description Each snippet is reported individually, with the update key of those created.
+// @Accept application/x-ndjson,application/zip,application/x-tar,application/gzip
+// @Produce json
+// @Tags md
+// @Success 200 {object} ImportResponse
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 413 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/import [post]
+// @Param message body string true "JSON lines or archive"
+func (m *MDHandlers) ImportMDHandler(ctx *fiber.Ctx) error {
+	items, err := parseImport(ctx.Body())
+	switch {
+	case errors.Is(err, errTooManyItems):
+		return fiber.NewError(http.StatusRequestEntityTooLarge, err.Error())
+	case err != nil:
+		logging.FromContext(ctx.UserContext()).WithError(err).Warn("Failed to parse import")
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	case len(items) == 0:
+		return fiber.NewError(http.StatusBadRequest, "import contains no snippets")
+	}
+
+	response := &ImportResponse{Results: make([]*ImportResult, len(items))}
+	var reqs []*CreateMDReq
+	var pending []*ImportResult
+	for i, item := range items {
+		result := &ImportResult{Item: i + 1, Name: item.name}
+		response.Results[i] = result
+		if item.err != nil {
+			result.Error = item.err.Error()
+			continue
+		}
+		if errs := api.ValidateStruct(item.req); errs != nil {
+			result.Error, result.Errors = errs.Error(), errs
+			continue
+		}
+		reqs = append(reqs, item.req)
+		pending = append(pending, result)
+	}
+
+	snippets, errs := m.service(ctx).CreateMarkdownSnippets(ctx.UserContext(), reqs)
+	for i, result := range pending {
+		if errs[i] != nil {
+			result.Error = "An unexpected error occurred"
+			var fiberErr *fiber.Error
+			if errors.As(httpError(errs[i]), &fiberErr) {
+				result.Error = fiberErr.Message
+			}
+			continue
+		}
+		result.ID, result.UpdateKey = snippets[i].ID, snippets[i].UpdateKey
+	}
+
+	for _, result := range response.Results {
+		if result.ID != "" {
+			response.Created++
+		} else {
+			response.Failed++
+		}
+	}
+	return ctx.JSON(response)
+}
+
+// ExportMDHandler GET - Streams MarkdownSnippets in bulk
+// @Summary Export markdown snippets in bulk
+// @Description Streams every snippet matching the search filters, without update keys.
+// @Description `zip` archives hold one `.md` file per snippet, titled in front matter, and can be imported.
+// @Param text query string false "Search Term"
+// @Param limit query int false "Number of Snippets, 0 for all" default(0)
+// @Param skip query int false "Skip Number of Snippets" default(0)
+// @Param sort query string false "Sort By" Enums(createDate_ASC, createDate_DESC)
+// @Param format query string false "Export Format" Enums(jsonl, zip) default(jsonl)
+// @Produce application/x-ndjson,application/zip
+// @Tags md
+// @Success 200 {object} MarkdownSnippet
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/export [get]
+func (m *MDHandlers) ExportMDHandler(ctx *fiber.Ctx) error {
+	params, err := searchParams(ctx, "0")
+	if err != nil {
+		return err
+	}
+	format := ctx.Query("format", ExportJSONL)
+	write := writeJSONLines
+	switch format {
+	case ExportJSONL:
+		ctx.Set(fiber.HeaderContentType, "application/x-ndjson")
+	case ExportZip:
+		ctx.Set(fiber.HeaderContentType, "application/zip")
+		write = writeZip
+	default:
+		return fiber.NewError(http.StatusBadRequest, fmt.Sprintf("`%s` is not a valid value for format", format))
+	}
+
+	// The body is streamed after the handler returns,
+	// outliving the request context.
+	streamCtx := api.Detach(ctx.UserContext())
+	cursor, err := m.service(ctx).ExportMarkdownSnippets(streamCtx, params)
 	if err != nil {
-		return fiber.NewError(http.StatusBadRequest, "skip: invalid value")
+		return httpError(err)
+	}
+
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="mdsnips-export.%s"`, format))
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cursor.Close()
+		count, err := write(w, cursor)
+		if err == nil {
+			err = w.Flush()
+		}
+		log := logging.FromContext(streamCtx).WithField("snippets", count)
+		if err != nil {
+			log.WithError(err).Error("Export interrupted")
+			return
+		}
+		log.Info("Export complete")
+	})
+	return nil
+}
+
+// searchParams
+// Parses the search query parameters shared by search and export,
+// with limit defaulting to defaultLimit.
+func searchParams(ctx *fiber.Ctx, defaultLimit string) (MDSearchParams, error) {
+	limit, err := strconv.ParseInt(ctx.Query("limit", defaultLimit), 10, 64)
+	if err != nil || limit < 0 {
+		return MDSearchParams{}, fiber.NewError(http.StatusBadRequest, "limit: invalid value")
+	}
+	skip, err := strconv.ParseInt(ctx.Query("skip", "0"), 10, 64)
+	if err != nil || skip < 0 {
+		return MDSearchParams{}, fiber.NewError(http.StatusBadRequest, "skip: invalid value")
 	}
 
 	sort := SortBy(ctx.Query("sort", CreateDate_DESC))
 	if err := sort.validate(); err != nil {
-		return fiber.NewError(http.StatusBadRequest, fmt.Sprintf("`%s` is not a valid value for sort", sort))
+		return MDSearchParams{}, fiber.NewError(http.StatusBadRequest, fmt.Sprintf("`%s` is not a valid value for sort", sort))
 	}
 
-	params := MDSearchParams{
+	return MDSearchParams{
 		Text:   ctx.Query("text"),
 		Limit:  limit,
 		Skip:   skip,
 		SortBy: sort,
-	}
-
-	snippets, err := m.mdService.SearchMarkdownSnippets(params)
-	if err != nil {
-		log.Printf("Error Searching for Markdown Snippets: %s", err)
-		ctx.Status(http.StatusInternalServerError)
-		return fiber.NewError(http.StatusBadRequest, err.Error())
-	}
-
-	return ctx.JSON(snippets)
+	}, nil
 }
 
 // UpdateMDHandler PATCH - Updates a MarkdownSnippet
 // @Summary Updates a markdown snippet
+// @Description With a `baseRevision`, changes saved since that revision are merged line by line
+// @Description with the update. Overlapping changes are returned with conflict markers to resolve
+// @Description and send again based on the returned `revision`.
 // @Accept json
 // @Produce json
 // @Tags md
 // @Success 200 {object} MarkdownSnippet
 // @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
 // @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} MergeConflictResponse
 // @Failure 500 {object} api.ErrorResponse
 // @Router /md [patch]
 // @Param message body UpdateMDReq true "Patch Body"
 func (m *MDHandlers) UpdateMDHandler(ctx *fiber.Ctx) error {
 	patchSnippet := new(UpdateMDReq)
 	if err := ctx.BodyParser(patchSnippet); err != nil {
-		ctx.Status(http.StatusBadRequest)
 		return fiber.NewError(http.StatusBadRequest, err.Error())
 	}
 
 	if errs := api.ValidateStruct(patchSnippet); errs != nil {
-		ctx.Status(http.StatusBadRequest)
-		return ctx.JSON(errs)
+		return errs
 	}
 
-	if !m.mdService.ValidateIdAndKey(patchSnippet.ID, patchSnippet.UpdateKey) {
-		ctx.Status(http.StatusUnauthorized)
-		return fiber.NewError(http.StatusUnauthorized, "Invalid Update Key")
+	if err := m.service(ctx).ValidateIdAndKey(ctx.UserContext(), patchSnippet.ID, patchSnippet.UpdateKey); err != nil {
+		return httpError(err)
 	}
 
-	err := m.mdService.UpdateMarkdownSnippet(patchSnippet)
+	updatedSnippet, err := m.service(ctx).UpdateMarkdownSnippet(ctx.UserContext(), patchSnippet)
+	var conflict *MergeConflictError
+	if errors.As(err, &conflict) {
+		return mergeConflict(ctx, conflict)
+	}
 	if err != nil {
-		log.Printf("Failed in update MarkdownSnippet %s: %s", patchSnippet.ID, err)
-		ctx.Status(http.StatusInternalServerError)
-		return fiber.NewError(http.StatusInternalServerError, err.Error())
+		return httpError(err)
 	}
 
-	updatedSnippet, err := m.mdService.GetMarkdownSnippet(patchSnippet.ID)
+	return ctx.JSON(updatedSnippet)
+}
+
+// PatchMDHandler PATCH - Partially updates a MarkdownSnippet
+// @Summary Partially updates a markdown snippet
+// @Description The patch format is chosen by Content-Type:
+// @Description `application/merge-patch+json` replaces the title or body given (RFC 7396),
+// @Description `text/x-diff` or `text/x-patch` applies a unified diff to the body, and
+// @Description `application/vnd.mdsnips.line-edits+json` replaces line ranges of the body.
+// @Description Diff hunks and line edits with `expect` must match the current body exactly.
+// @Accept json,plain
+// @Produce json
+// @Tags md
+// @Success 200 {object} MarkdownSnippet
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Failure 415 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id} [patch]
+// @Param id path string true "Snippet ID"
+// @Param X-Update-Key header string true "Update Key"
+// @Param message body MergePatch true "Merge patch, unified diff or LineEdits"
+func (m *MDHandlers) PatchMDHandler(ctx *fiber.Ctx) error {
+	id, key := ctx.Params("id"), ctx.Get("X-Update-Key")
+	if key == "" {
+		return fiber.NewError(http.StatusBadRequest, "X-Update-Key header is required")
+	}
+
+	patch, err := parsePatch(ctx)
 	if err != nil {
-		ctx.Status(http.StatusInternalServerError)
-		return fiber.NewError(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	return ctx.JSON(updatedSnippet)
+	if err := m.service(ctx).ValidateIdAndKey(ctx.UserContext(), id, key); err != nil {
+		return httpError(err)
+	}
+
+	patchedSnippet, err := m.service(ctx).PatchMarkdownSnippet(ctx.UserContext(), id, patch)
+	if err != nil {
+		return httpError(err)
+	}
+
+	return ctx.JSON(patchedSnippet)
+}
+
+// parsePatch
+// Parses the request body as the patch format named by its Content-Type.
+func parsePatch(ctx *fiber.Ctx) (Patch, error) {
+	mediaType := strings.TrimSpace(strings.SplitN(ctx.Get(fiber.HeaderContentType), ";", 2)[0])
+	switch strings.ToLower(mediaType) {
+	case MergePatchType:
+		patch, err := ParseMergePatch(ctx.Body())
+		if err != nil {
+			return nil, httpError(err)
+		}
+		return patch, nil
+	case DiffType, PatchType:
+		patch, err := ParseUnifiedDiff(string(ctx.Body()))
+		if err != nil {
+			return nil, httpError(err)
+		}
+		return patch, nil
+	case LineEditsType:
+		patch := new(LineEdits)
+		if err := json.Unmarshal(ctx.Body(), patch); err != nil {
+			return nil, fiber.NewError(http.StatusBadRequest, err.Error())
+		}
+		if errs := api.ValidateStruct(patch); errs != nil {
+			return nil, errs
+		}
+		return patch, nil
+	}
+	return nil, fiber.NewError(http.StatusUnsupportedMediaType,
+		fmt.Sprintf("Content-Type must be one of %s, %s, %s or %s", MergePatchType, DiffType, PatchType, LineEditsType))
 }
 
-// DeleteMDHandler DELETE - Removes MarkdownSnippet permanantly
-// @Summary Removes MarkdownSnippet permanantly
+// DeleteMDHandler DELETE - Moves a MarkdownSnippet to the trash
+// @Summary Moves a markdown snippet to the trash
+// @Description Deleted snippets are hidden and can be restored until the trash retention period expires.
 // @Accept json
 // @Produce json
 // @Tags md
 // @Success 204
 // @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
 // @Failure 500 {object} api.ErrorResponse
 // @Router /md/{id} [delete]
 // @Param id path string true "Snippet ID"
@@ -218,24 +616,528 @@ func (m *MDHandlers) DeleteMDHandler(ctx *fiber.Ctx) error {
 	deleteBody := new(DeleteMDReq)
 
 	if err := ctx.BodyParser(deleteBody); err != nil {
-		ctx.Status(http.StatusBadRequest)
 		return fiber.NewError(http.StatusBadRequest, err.Error())
 	}
 
 	if errs := api.ValidateStruct(deleteBody); errs != nil {
-		ctx.Status(http.StatusBadRequest)
-		return ctx.JSON(errs)
+		return errs
 	}
 
-	if !m.mdService.ValidateIdAndKey(id, deleteBody.UpdateKey) {
-		return fiber.NewError(http.StatusUnauthorized, "Invalid Update Key")
+	if err := m.service(ctx).ValidateIdAndKey(ctx.UserContext(), id, deleteBody.UpdateKey); err != nil {
+		return httpError(err)
+	}
+
+	if err := m.service(ctx).DeleteMarkdownSnippet(ctx.UserContext(), id, deleteBody.UpdateKey); err != nil {
+		return httpError(err)
+	}
+
+	ctx.Status(fiber.StatusNoContent)
+	return nil
+}
+
+// RestoreMDHandler POST - Restores a MarkdownSnippet from the trash
+// @Summary Restores a deleted markdown snippet
+// @Accept json
+// @Produce json
+// @Tags md
+// @Success 200 {object} MarkdownSnippet
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id}/restore [post]
+// @Param id path string true "Snippet ID"
+// @Param message body RestoreMDReq true "Restore Body"
+func (m *MDHandlers) RestoreMDHandler(ctx *fiber.Ctx) error {
+	restoreBody := new(RestoreMDReq)
+	if err := ctx.BodyParser(restoreBody); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	if errs := api.ValidateStruct(restoreBody); errs != nil {
+		return errs
+	}
+
+	restoredSnippet, err := m.service(ctx).RestoreMarkdownSnippet(ctx.UserContext(), ctx.Params("id"), restoreBody.UpdateKey)
+	if err != nil {
+		return httpError(err)
+	}
+
+	return ctx.JSON(restoredSnippet)
+}
+
+// ListTrashHandler GET - Lists the MarkdownSnippets in the trash
+// @Summary List deleted markdown snippets
+// @Description Lists the snippets of a tenant awaiting purge, most recently deleted first.
+// @Description A configured tenant is required when multi-tenant mode is enabled.
+// @Produce json
+// @Tags admin
+// @Success 200 {array} MDListItem
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /admin/trash [get]
+// @Param tenant query string false "Tenant"
+func (m *MDHandlers) ListTrashHandler(ctx *fiber.Ctx) error {
+	tenant := ctx.Query("tenant")
+	if m.mdService.tenancy.Enabled() && !m.mdService.tenancy.Allowed(tenant) {
+		return fiber.NewError(http.StatusBadRequest, "tenant: a configured tenant is required in multi-tenant mode")
+	}
+
+	trash, err := m.mdService.ForTenant(tenant).ListTrash(ctx.UserContext())
+	if err != nil {
+		return httpError(err)
+	}
+
+	return ctx.JSON(trash)
+}
+
+// GetFileHandler GET - Serves a file of a MarkdownSnippet
+// @Summary Get the raw content of a snippet file
+// @Description Serves the named file as plain text. The body is served as `README.md`.
+// @Produce plain
+// @Tags md
+// @Success 200 {string} string
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id}/files/{name} [get]
+// @Param id path string true "Snippet ID"
+// @Param name path string true "File Name"
+func (m *MDHandlers) GetFileHandler(ctx *fiber.Ctx) error {
+	name, err := url.PathUnescape(ctx.Params("name"))
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "name: invalid value")
+	}
+
+	file, err := m.service(ctx).GetSnippetFile(ctx.UserContext(), ctx.Params("id"), name)
+	if err != nil {
+		return httpError(err)
+	}
+
+	// Files are never rendered, whatever their content.
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	ctx.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	return ctx.SendString(file.Content)
+}
+
+// ZipMDHandler GET - Downloads a MarkdownSnippet as a zip archive
+// @Summary Download a snippet and its files
+// @Description Archives the body as `README.md` beside each of the snippet's files.
+// @Produce application/zip
+// @Tags md
+// @Success 200 {file} file
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id}/zip [get]
+// @Param id path string true "Snippet ID"
+func (m *MDHandlers) ZipMDHandler(ctx *fiber.Ctx) error {
+	snippet, err := m.service(ctx).GetMarkdownSnippet(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return httpError(err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "application/zip")
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.zip"`, snippet.ID))
+	return WriteSnippetZip(ctx, snippet)
+}
+
+// UploadAttachmentHandler POST - Attaches a file to a MarkdownSnippet
+// @Summary Upload a snippet attachment
+// @Description Stores an uploaded file, such as an image the snippet embeds, under its name or the given `name`.
+// @Description The content type is sniffed from the file, the response `Location` is where it is served.
+// @Accept multipart/form-data
+// @Produce json
+// @Tags md
+// @Success 201 {object} Attachment
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Failure 413 {object} api.ErrorResponse
+// @Failure 415 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id}/attachments [post]
+// @Param id path string true "Snippet ID"
+// @Param file formData file true "Attachment"
+// @Param updateKey formData string true "Update Key"
+// @Param name formData string false "Attachment Name"
+func (m *MDHandlers) UploadAttachmentHandler(ctx *fiber.Ctx) error {
+	uploadBody := new(UploadAttachmentReq)
+	if err := ctx.BodyParser(uploadBody); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "file: required")
+	}
+	if uploadBody.Name == "" {
+		uploadBody.Name = fileHeader.Filename
+	}
+
+	if errs := api.ValidateStruct(uploadBody); errs != nil {
+		return errs
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	id := ctx.Params("id")
+	attachment, err := m.service(ctx).UploadAttachment(ctx.UserContext(), id, uploadBody.UpdateKey, uploadBody.Name, file)
+	if err != nil {
+		return httpError(err)
+	}
+
+	ctx.Location(requestPath(ctx) + "/" + url.PathEscape(attachment.Name))
+	ctx.Status(http.StatusCreated)
+	return ctx.JSON(attachment)
+}
+
+// requestPath
+// Returns the path the client requested, without the query string
+// or a trailing slash. Unlike ctx.Path it keeps the `/t/{tenant}`
+// prefix the path resolver strips.
+func requestPath(ctx *fiber.Ctx) string {
+	path := strings.SplitN(ctx.OriginalURL(), "?", 2)[0]
+	return strings.TrimSuffix(path, "/")
+}
+
+// ListAttachmentsHandler GET - Lists the attachments of a MarkdownSnippet
+// @Summary List snippet attachments
+// @Produce json
+// @Tags md
+// @Success 200 {array} Attachment
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id}/attachments [get]
+// @Param id path string true "Snippet ID"
+func (m *MDHandlers) ListAttachmentsHandler(ctx *fiber.Ctx) error {
+	attachments, err := m.service(ctx).ListAttachments(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return httpError(err)
+	}
+
+	return ctx.JSON(attachments)
+}
+
+// GetAttachmentHandler GET - Serves an attachment of a MarkdownSnippet
+// @Summary Get a snippet attachment
+// @Description Serves the attachment with its sniffed content type, cacheable and revalidated by its `ETag`.
+// @Description Only images are served inline, anything else is served as a download.
+// @Produce octet-stream
+// @Tags md
+// @Success 200 {file} file
+// @Success 304
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id}/attachments/{name} [get]
+// @Param id path string true "Snippet ID"
+// @Param name path string true "Attachment Name"
+func (m *MDHandlers) GetAttachmentHandler(ctx *fiber.Ctx) error {
+	name, err := url.PathUnescape(ctx.Params("name"))
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "name: invalid value")
+	}
+
+	mdService := m.service(ctx)
+	attachment, content, err := mdService.OpenAttachment(ctx.UserContext(), ctx.Params("id"), name)
+	if err != nil {
+		return httpError(err)
+	}
+
+	ctx.Set(fiber.HeaderETag, `"`+attachment.SHA256+`"`)
+	ctx.Set(fiber.HeaderLastModified, attachment.UploadDate.UTC().Format(http.TimeFormat))
+	ctx.Set(fiber.HeaderCacheControl, cacheControl(mdService.attachments.CacheMaxAge))
+	if ctx.Fresh() {
+		content.Close()
+		return ctx.SendStatus(http.StatusNotModified)
+	}
+
+	ctx.Set(fiber.HeaderContentType, attachment.ContentType)
+	ctx.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	if !imageTypes[attachment.ContentType] {
+		ctx.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	}
+	// Closed once sent.
+	return ctx.SendStream(content, int(attachment.Size))
+}
+
+// DeleteAttachmentHandler DELETE - Removes an attachment of a MarkdownSnippet
+// @Summary Delete a snippet attachment
+// @Accept json
+// @Tags md
+// @Success 204
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id}/attachments/{name} [delete]
+// @Param id path string true "Snippet ID"
+// @Param name path string true "Attachment Name"
+// @Param message body DeleteMDReq true "Delete Body"
+func (m *MDHandlers) DeleteAttachmentHandler(ctx *fiber.Ctx) error {
+	name, err := url.PathUnescape(ctx.Params("name"))
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "name: invalid value")
+	}
+	deleteBody := new(DeleteMDReq)
+	if err := ctx.BodyParser(deleteBody); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
 	}
 
-	if err := m.mdService.DeleteMarkdownSnippet(id, deleteBody.UpdateKey); err != nil {
-		log.Printf("An error occurred when deleting %s", id)
-		return fiber.NewError(http.StatusInternalServerError, "Unexpected Error When Deleting Snippet")
+	if errs := api.ValidateStruct(deleteBody); errs != nil {
+		return errs
+	}
+
+	if err := m.service(ctx).DeleteAttachment(ctx.UserContext(), ctx.Params("id"), deleteBody.UpdateKey, name); err != nil {
+		return httpError(err)
 	}
 
 	ctx.Status(fiber.StatusNoContent)
 	return nil
 }
+
+// cacheControl
+// Returns the Cache-Control header letting clients
+// cache a response for maxAge, revalidating when zero.
+func cacheControl(maxAge time.Duration) string {
+	if maxAge <= 0 {
+		return "no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", int64(maxAge/time.Second))
+}
+
+// ForkMDHandler POST - Forks a MarkdownSnippet
+// @Summary Fork a markdown snippet
+// @Description Copies the current revision into a new snippet with its own id and update key,
+// @Description recording `forkedFrom` and the `lineage` of snippets it descends from.
+// @Description The body is optional, the fork keeps the forked snippet's title unless one is given.
+// @Accept json
+// @Produce json
+// @Tags md
+// @Success 201 {object} MarkdownSnippet
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id}/fork [post]
+// @Param id path string true "Snippet ID"
+// @Param message body ForkMDReq false "Fork Body"
+func (m *MDHandlers) ForkMDHandler(ctx *fiber.Ctx) error {
+	forkBody := new(ForkMDReq)
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(forkBody); err != nil {
+			return fiber.NewError(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	if errs := api.ValidateStruct(forkBody); errs != nil {
+		return errs
+	}
+
+	fork, err := m.service(ctx).ForkMarkdownSnippet(ctx.UserContext(), ctx.Params("id"), forkBody.Title)
+	if err != nil {
+		return httpError(err)
+	}
+
+	ctx.Status(http.StatusCreated)
+	return ctx.JSON(fork)
+}
+
+// ListForksHandler GET - Lists forks of a MarkdownSnippet
+// @Summary List the forks of a markdown snippet
+// @Description Lists the snippets forked directly from the snippet, oldest first, without their body.
+// @Produce json
+// @Tags md
+// @Success 200 {object} []MDListItem
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /md/{id}/forks [get]
+// @Param id path string true "Snippet ID"
+func (m *MDHandlers) ListForksHandler(ctx *fiber.Ctx) error {
+	forks, err := m.service(ctx).ListForks(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return httpError(err)
+	}
+
+	return ctx.JSON(forks)
+}
+
+// AuditHandler GET - Lists audit log entries
+// @Summary Query the audit log
+// @Description Lists changes to snippets of every tenant, most recent first, matching the given filters.
+// @Produce json
+// @Tags admin
+// @Success 200 {object} []AuditEntry
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /admin/audit [get]
+// @Param actor query string false "Basic auth user"
+// @Param ip query string false "Client IP address"
+// @Param requestId query string false "Request ID"
+// @Param operation query string false "Operation" Enums(create, import, fork, update, patch, merge, collab, delete, restore)
+// @Param snippetId query string false "Snippet ID"
+// @Param tenant query string false "Tenant"
+// @Param since query string false "Entries at or after, RFC 3339" format(date-time)
+// @Param until query string false "Entries before, RFC 3339" format(date-time)
+// @Param limit query int false "Number of Entries" default(50)
+// @Param skip query int false "Skip Number of Entries" default(0)
+func (m *MDHandlers) AuditHandler(ctx *fiber.Ctx) error {
+	params, err := auditParams(ctx, "50")
+	if err != nil {
+		return err
+	}
+
+	entries, err := m.mdService.ListAudit(ctx.UserContext(), params)
+	if err != nil {
+		return httpError(err)
+	}
+
+	return ctx.JSON(entries)
+}
+
+// ExportAuditHandler GET - Streams audit log entries
+// @Summary Export the audit log
+// @Description Streams every audit entry matching the filters of `GET /admin/audit` as JSON lines.
+// @Produce application/x-ndjson
+// @Tags admin
+// @Success 200 {object} AuditEntry
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /admin/audit/export [get]
+// @Param actor query string false "Basic auth user"
+// @Param ip query string false "Client IP address"
+// @Param requestId query string false "Request ID"
+// @Param operation query string false "Operation" Enums(create, import, fork, update, patch, merge, collab, delete, restore)
+// @Param snippetId query string false "Snippet ID"
+// @Param tenant query string false "Tenant"
+// @Param since query string false "Entries at or after, RFC 3339" format(date-time)
+// @Param until query string false "Entries before, RFC 3339" format(date-time)
+// @Param limit query int false "Number of Entries, 0 for all" default(0)
+// @Param skip query int false "Skip Number of Entries" default(0)
+func (m *MDHandlers) ExportAuditHandler(ctx *fiber.Ctx) error {
+	params, err := auditParams(ctx, "0")
+	if err != nil {
+		return err
+	}
+
+	// The body is streamed after the handler returns,
+	// outliving the request context.
+	streamCtx := api.Detach(ctx.UserContext())
+	ctx.Set(fiber.HeaderContentType, "application/x-ndjson")
+	ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="mdsnips-audit.jsonl"`)
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		count, err := m.mdService.ExportAudit(streamCtx, params, w)
+		if err == nil {
+			err = w.Flush()
+		}
+		log := logging.FromContext(streamCtx).WithField("entries", count)
+		if err != nil {
+			log.WithError(err).Error("Audit export interrupted")
+			return
+		}
+		log.Info("Audit export complete")
+	})
+	return nil
+}
+
+// auditParams
+// Parses the audit log query parameters shared by
+// listing and export, with limit defaulting to defaultLimit.
+func auditParams(ctx *fiber.Ctx, defaultLimit string) (AuditParams, error) {
+	limit, err := strconv.ParseInt(ctx.Query("limit", defaultLimit), 10, 64)
+	if err != nil || limit < 0 {
+		return AuditParams{}, fiber.NewError(http.StatusBadRequest, "limit: invalid value")
+	}
+	skip, err := strconv.ParseInt(ctx.Query("skip", "0"), 10, 64)
+	if err != nil || skip < 0 {
+		return AuditParams{}, fiber.NewError(http.StatusBadRequest, "skip: invalid value")
+	}
+
+	params := AuditParams{
+		Actor:     ctx.Query("actor"),
+		IP:        ctx.Query("ip"),
+		RequestID: ctx.Query("requestId"),
+		Operation: ctx.Query("operation"),
+		SnippetID: ctx.Query("snippetId"),
+		Tenant:    ctx.Query("tenant"),
+		Limit:     limit,
+		Skip:      skip,
+	}
+	bounds := []struct {
+		name string
+		t    *time.Time
+	}{{"since", &params.Since}, {"until", &params.Until}}
+	for _, bound := range bounds {
+		if value := ctx.Query(bound.name); value != "" {
+			if *bound.t, err = time.Parse(time.RFC3339, value); err != nil {
+				return AuditParams{}, fiber.NewError(http.StatusBadRequest, bound.name+": invalid value")
+			}
+		}
+	}
+	return params, nil
+}
+
+// mergeConflict
+// Responds with the conflict marked result of a merge.
+func mergeConflict(ctx *fiber.Ctx, conflict *MergeConflictError) error {
+	ctx.Status(http.StatusConflict)
+	err := ctx.JSON(MergeConflictResponse{
+		ErrorResponse: api.ErrorResponse{
+			Type:     "about:blank",
+			Title:    http.StatusText(http.StatusConflict),
+			Status:   http.StatusConflict,
+			Detail:   "Update Conflicts With Revision " + strconv.FormatInt(conflict.Revision, 10),
+			Instance: ctx.OriginalURL(),
+		},
+		Revision:      conflict.Revision,
+		Title:         conflict.Title,
+		TitleConflict: conflict.TitleConflict,
+		Body:          conflict.Body,
+		Files:         conflict.Files,
+		FileConflicts: conflict.FileConflicts,
+		Conflicts:     conflict.Conflicts,
+	})
+	if err != nil {
+		return err
+	}
+	ctx.Set(fiber.HeaderContentType, api.ProblemContentType)
+	return nil
+}
+
+// httpError
+// Maps md domain errors onto fiber errors,
+// any other error is passed through as an internal error.
+func httpError(err error) error {
+	var conflict *PatchConflictError
+	var snippetErr *SnippetError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return fiber.NewError(http.StatusNotFound, "Markdown Snippet Not Found")
+	case errors.Is(err, ErrFileNotFound):
+		return fiber.NewError(http.StatusNotFound, "Snippet File Not Found")
+	case errors.Is(err, ErrAttachmentNotFound):
+		return fiber.NewError(http.StatusNotFound, "Snippet Attachment Not Found")
+	case errors.Is(err, ErrAttachmentTooLarge):
+		return fiber.NewError(http.StatusRequestEntityTooLarge, "Attachment Too Large")
+	case errors.Is(err, ErrQuotaExceeded):
+		return fiber.NewError(http.StatusRequestEntityTooLarge, "Attachment Quota Exceeded")
+	case errors.Is(err, ErrUnsupportedType):
+		return fiber.NewError(http.StatusUnsupportedMediaType, "Unsupported Attachment Type")
+	case errors.Is(err, ErrInvalidKey):
+		return fiber.NewError(http.StatusUnauthorized, "Invalid Update Key")
+	case errors.As(err, &conflict):
+		return fiber.NewError(http.StatusConflict, "Patch Does Not Apply: "+conflict.Error())
+	case errors.Is(err, ErrInvalidPatch), errors.Is(err, ErrInvalidRevision):
+		if errors.As(err, &snippetErr) {
+			err = snippetErr.Err
+		}
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrConflict):
+		return fiber.NewError(http.StatusConflict, "Markdown Snippet Conflict")
+	case errors.Is(err, ErrTooManySubscribers):
+		return fiber.NewError(http.StatusTooManyRequests, "Too Many Live Subscribers")
+	}
+	return err
+}
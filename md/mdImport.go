@@ -0,0 +1,231 @@
+package md
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/soulxburn/mdsnips/api"
+)
+
+// maxImportItems Maximum number of snippets accepted by one import.
+const maxImportItems = 1000
+
+// errTooManyItems Returned when an upload exceeds maxImportItems.
+var errTooManyItems = fmt.Errorf("import exceeds %d snippets", maxImportItems)
+
+// ImportResult
+// Outcome of importing one snippet.
+type ImportResult struct {
+	// Position of the snippet in the upload, starting at 1.
+	Item int `json:"item" example:"1"`
+	// Source file name, for archive uploads.
+	Name string `json:"name,omitempty" example:"notes/go.md"`
+	// Markdown snippet guid, set when created.
+	ID string `json:"id,omitempty" format:"uuid"`
+	// Update hash key, set when created.
+	UpdateKey string `json:"updateKey,omitempty" format:"uuid"`
+	// Reason the snippet was not created.
+	Error string `json:"error,omitempty" example:"CreateMDReq.Title failed required validation"`
+	// Field level validation failures.
+	Errors []*api.ValidationError `json:"errors,omitempty"`
+}
+
+// ImportResponse
+type ImportResponse struct {
+	// Number of snippets created.
+	Created int `json:"created" example:"2"`
+	// Number of snippets rejected.
+	Failed int `json:"failed" example:"0"`
+	// Per snippet outcome, in upload order.
+	Results []*ImportResult `json:"results"`
+}
+
+// importItem
+// A snippet read from an upload, or the reason it could not be read.
+type importItem struct {
+	name string
+	req  *CreateMDReq
+	err  error
+}
+
+// parseImport
+// Reads the snippets of an upload, detected from its content:
+// a zip archive, a tarball, optionally gzip compressed, of `.md`
+// files, or otherwise JSON lines of CreateMDReq.
+func parseImport(body []byte) ([]*importItem, error) {
+	switch {
+	case bytes.HasPrefix(body, []byte("PK\x03\x04")):
+		return parseZip(body)
+	case bytes.HasPrefix(body, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		return parseTar(gz)
+	case len(body) > 262 && string(body[257:262]) == "ustar":
+		return parseTar(bytes.NewReader(body))
+	}
+	return parseJSONLines(body)
+}
+
+// parseJSONLines
+// Reads one CreateMDReq per non-empty line.
+func parseJSONLines(body []byte) ([]*importItem, error) {
+	var items []*importItem
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		item := &importItem{req: new(CreateMDReq)}
+		if err := json.Unmarshal(line, item.req); err != nil {
+			item.req, item.err = nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if items = append(items, item); len(items) > maxImportItems {
+			return nil, errTooManyItems
+		}
+	}
+	return items, scanner.Err()
+}
+
+// parseZip
+// Reads every markdown file of a zip archive.
+func parseZip(body []byte) ([]*importItem, error) {
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*importItem
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || !isMarkdownFile(file.Name) {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			items = append(items, &importItem{name: file.Name, err: err})
+			continue
+		}
+		items = append(items, readMarkdownFile(file.Name, r))
+		r.Close()
+		if len(items) > maxImportItems {
+			return nil, errTooManyItems
+		}
+	}
+	return items, nil
+}
+
+// parseTar
+// Reads every markdown file of a tarball.
+func parseTar(r io.Reader) ([]*importItem, error) {
+	var items []*importItem
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg || !isMarkdownFile(header.Name) {
+			continue
+		}
+		if items = append(items, readMarkdownFile(header.Name, archive)); len(items) > maxImportItems {
+			return nil, errTooManyItems
+		}
+	}
+}
+
+// isMarkdownFile
+// Reports whether name is a markdown file, skipping
+// hidden files and archive metadata such as __MACOSX.
+func isMarkdownFile(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return false
+		}
+	}
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// readMarkdownFile
+// Reads a markdown file, titled by its front matter
+// `title` or else its file name without extension.
+func readMarkdownFile(name string, r io.Reader) *importItem {
+	content, err := ioutil.ReadAll(io.LimitReader(r, 1<<20))
+	if err != nil {
+		return &importItem{name: name, err: err}
+	}
+	title, body := splitFrontMatter(string(content))
+	if title == "" {
+		base := path.Base(name)
+		title = strings.TrimSuffix(base, path.Ext(base))
+	}
+	return &importItem{name: name, req: &CreateMDReq{Title: title, Body: body}}
+}
+
+// splitFrontMatter
+// Splits a `---` delimited front matter block from content,
+// returning its `title` and the remaining body. Content without
+// front matter is returned unchanged.
+func splitFrontMatter(content string) (string, string) {
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return "", content
+	}
+	rest := normalized[len("---\n"):]
+	end := strings.Index(rest, "\n---\n")
+	if end < 0 {
+		if !strings.HasSuffix(rest, "\n---") {
+			return "", content
+		}
+		end = len(rest) - len("\n---")
+	}
+
+	var title string
+	for _, line := range strings.Split(rest[:end], "\n") {
+		key, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			key, value = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		if strings.TrimSpace(key) == "title" {
+			title = unquoteYAML(value)
+		}
+	}
+
+	body := ""
+	if end+len("\n---\n") <= len(rest) {
+		body = rest[end+len("\n---\n"):]
+	}
+	return title, strings.TrimPrefix(body, "\n")
+}
+
+// unquoteYAML
+// Removes the quotes of a single or double quoted scalar.
+func unquoteYAML(value string) string {
+	if len(value) >= 2 {
+		switch {
+		case value[0] == '"' && value[len(value)-1] == '"':
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				return unquoted
+			}
+		case value[0] == '\'' && value[len(value)-1] == '\'':
+			return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		}
+	}
+	return value
+}
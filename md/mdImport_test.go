@@ -0,0 +1,151 @@
+package md
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_ParseJSONLines
+// Lines that are not JSON are reported individually.
+func Test_ParseJSONLines(t *testing.T) {
+	items, err := parseImport([]byte(`{"title":"One","body":"# One"}` + "\n\n" + `not json` + "\n"))
+	assert.Nil(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, &CreateMDReq{Title: "One", Body: "# One"}, items[0].req)
+	assert.Contains(t, items[1].err.Error(), "invalid JSON")
+}
+
+// Test_ParseZip
+// Markdown files are titled by front matter or file name,
+// other and hidden files are skipped.
+func Test_ParseZip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+	for name, content := range map[string]string{
+		"notes/go.md":          "# Go",
+		"notes/titled.md":      "---\ntitle: \"Front: Matter\"\ntags: go\n---\n# Body",
+		"notes/image.png":      "png",
+		"__MACOSX/notes/go.md": "junk",
+	} {
+		w, _ := archive.Create(name)
+		w.Write([]byte(content))
+	}
+	archive.Close()
+
+	items, err := parseImport(buf.Bytes())
+	assert.Nil(t, err)
+	byName := map[string]*CreateMDReq{}
+	for _, item := range items {
+		byName[item.name] = item.req
+	}
+	assert.Len(t, byName, 2)
+	assert.Equal(t, &CreateMDReq{Title: "go", Body: "# Go"}, byName["notes/go.md"])
+	assert.Equal(t, &CreateMDReq{Title: "Front: Matter", Body: "# Body"}, byName["notes/titled.md"])
+}
+
+// Test_ParseTarGz
+func Test_ParseTarGz(t *testing.T) {
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	archive := tar.NewWriter(gz)
+	content := "---\ntitle: 'It''s titled'\n---\n\n# Tar"
+	archive.WriteHeader(&tar.Header{Name: "tar.md", Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	archive.Write([]byte(content))
+	archive.Close()
+	gz.Close()
+
+	items, err := parseImport(buf.Bytes())
+	assert.Nil(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, &CreateMDReq{Title: "It's titled", Body: "# Tar"}, items[0].req)
+}
+
+// Test_ParseTooManyItems
+func Test_ParseTooManyItems(t *testing.T) {
+	line := `{"title":"T","body":"B"}` + "\n"
+	_, err := parseImport([]byte(strings.Repeat(line, maxImportItems+1)))
+	assert.Equal(t, errTooManyItems, err)
+}
+
+// Test_MarkdownFileRoundTrip
+// Exported markdown files import with their title and body intact.
+func Test_MarkdownFileRoundTrip(t *testing.T) {
+	snippet := &MarkdownSnippet{ID: "abc", Title: `Quotes "and" colons: yes`, Body: "---\n# Body", CreateDate: time.Now()}
+
+	title, body := splitFrontMatter(markdownFile(snippet))
+	assert.Equal(t, snippet.Title, title)
+	assert.Equal(t, snippet.Body, body)
+}
+
+// Test_ImportHandlerValidation
+// Invalid snippets are reported per item without failing the import.
+func Test_ImportHandlerValidation(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: api.ErrorHandler})
+	InitMDHandlers(SetupUnreachableMDService(t, config.Timeouts{})).ConfigureRoutes(app)
+
+	body := `{"title":"","body":"# No title"}` + "\n" + `{"title":"T"` + "\n"
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/md/import", strings.NewReader(body)))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	response := new(ImportResponse)
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(response))
+	assert.Equal(t, 0, response.Created)
+	assert.Equal(t, 2, response.Failed)
+	assert.Equal(t, "CreateMDReq.Title", response.Results[0].Errors[0].FailedField)
+	assert.Contains(t, response.Results[1].Error, "invalid JSON")
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodPost, "/md/import", strings.NewReader("")))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// Test_ImportExport
+// Snippets created in bulk are audited and revisioned,
+// and export as a zip that imports again.
+func Test_ImportExport(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := context.Background()
+
+	reqs := []*CreateMDReq{{Title: "One", Body: "# One"}, {Title: "One", Body: "# One"}}
+	snippets, errs := mdService.CreateMarkdownSnippets(ctx, reqs)
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.NotEqual(t, snippets[0].ID, snippets[1].ID)
+	// Each snippet is created with its revision and audit entry.
+	for _, snippet := range snippets {
+		revisions, err := mdService.ListRevisions(ctx, snippet.ID)
+		assert.Nil(t, err)
+		assert.Len(t, revisions, 1)
+		entries, err := mdService.ListAudit(ctx, AuditParams{SnippetID: snippet.ID, Operation: AuditImport})
+		assert.Nil(t, err)
+		assert.Len(t, entries, 1)
+	}
+
+	cursor, err := mdService.ExportMarkdownSnippets(ctx, MDSearchParams{SortBy: CreateDate_ASC})
+	assert.Nil(t, err)
+	buf := new(bytes.Buffer)
+	count, err := writeZip(buf, cursor)
+	assert.Nil(t, err)
+	assert.Nil(t, cursor.Close())
+	assert.Equal(t, 2, count)
+
+	items, err := parseImport(buf.Bytes())
+	assert.Nil(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, reqs[0], items[0].req)
+}
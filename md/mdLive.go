@@ -0,0 +1,275 @@
+package md
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/logging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrTooManySubscribers is returned when a live subscriber limit is reached.
+var ErrTooManySubscribers = errors.New("too many live subscribers")
+
+// subscriberBuffer Events queued per subscriber before it is dropped as too slow.
+const subscriberBuffer = 16
+
+// LiveBus
+// Fans snippet events out to live subscribers in this process.
+// Events are fed from a change stream on the outbox when the
+// deployment supports one, otherwise from writes made locally.
+type LiveBus struct {
+	cfg config.Live
+
+	mu     sync.Mutex
+	subs   map[string]map[*Subscription]struct{}
+	count  int
+	closed bool
+	// Set while a change stream is feeding the bus,
+	// local writes are then not published directly.
+	watching int32
+}
+
+// Subscription
+// A live subscriber to the events of one snippet.
+// Done is closed when the bus drops the subscriber,
+// because it fell behind or the bus was closed.
+type Subscription struct {
+	bus    *LiveBus
+	key    string
+	events chan *Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewLiveBus
+// Returns an empty LiveBus, filling unset limits with their defaults.
+func NewLiveBus(cfg config.Live) *LiveBus {
+	if cfg.MaxSubscribers <= 0 {
+		cfg.MaxSubscribers = 1000
+	}
+	if cfg.MaxPerSnippet <= 0 {
+		cfg.MaxPerSnippet = 100
+	}
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = 15 * time.Second
+	}
+	return &LiveBus{cfg: cfg, subs: make(map[string]map[*Subscription]struct{})}
+}
+
+// Heartbeat
+// Returns the interval between keep-alive messages on idle streams.
+func (b *LiveBus) Heartbeat() time.Duration {
+	return b.cfg.Heartbeat
+}
+
+// Subscribe
+// Registers a subscriber to events of snippet id in tenant.
+// Returns ErrTooManySubscribers when either limit is reached.
+func (b *LiveBus) Subscribe(tenant string, id string) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := liveKey(tenant, id)
+	if b.closed || b.count >= b.cfg.MaxSubscribers || len(b.subs[key]) >= b.cfg.MaxPerSnippet {
+		return nil, snippetError("subscribe", id, ErrTooManySubscribers)
+	}
+	sub := &Subscription{
+		bus:    b,
+		key:    key,
+		events: make(chan *Event, subscriberBuffer),
+		done:   make(chan struct{}),
+	}
+	if b.subs[key] == nil {
+		b.subs[key] = make(map[*Subscription]struct{})
+	}
+	b.subs[key][sub] = struct{}{}
+	b.count++
+	return sub, nil
+}
+
+// Publish
+// Sends events to the subscribers of their snippet.
+// Subscribers with a full queue are dropped rather than blocking.
+func (b *LiveBus) Publish(events ...*Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, event := range events {
+		for sub := range b.subs[liveKey(event.Tenant, event.SnippetID)] {
+			select {
+			case sub.events <- event:
+			default:
+				b.remove(sub)
+			}
+		}
+	}
+}
+
+// publishLocal
+// Publishes events written by this process, unless
+// a change stream is already delivering them.
+func (b *LiveBus) publishLocal(events ...*Event) {
+	if atomic.LoadInt32(&b.watching) == 0 {
+		b.Publish(events...)
+	}
+}
+
+// Subscribers
+// Returns the number of live subscribers.
+func (b *LiveBus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+// Close
+// Drops every subscriber and rejects new ones,
+// ending open streams on shutdown.
+func (b *LiveBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for _, subs := range b.subs {
+		for sub := range subs {
+			b.remove(sub)
+		}
+	}
+}
+
+// remove
+// Unregisters sub and closes its done channel. Requires b.mu.
+func (b *LiveBus) remove(sub *Subscription) {
+	subs, ok := b.subs[sub.key]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subs, sub.key)
+	}
+	b.count--
+	sub.once.Do(func() { close(sub.done) })
+}
+
+// Watch
+// Feeds the bus from a change stream on collection, resuming after
+// errors with backoff until ctx is cancelled. Standalone servers
+// do not support change streams, local writes are published instead.
+func (b *LiveBus) Watch(ctx context.Context, collection *mongo.Collection) {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.D{{Key: "operationType", Value: "insert"}}}}}
+	var resumeToken bson.Raw
+	wait := time.Second
+	for ctx.Err() == nil {
+		opts := options.ChangeStream()
+		if resumeToken != nil {
+			opts.SetResumeAfter(resumeToken)
+		}
+		stream, err := collection.Watch(ctx, pipeline, opts)
+		if isChangeStreamUnsupported(err) {
+			logging.Logger.Info("Change streams unsupported, publishing live events from local writes")
+			return
+		}
+		if err == nil {
+			atomic.StoreInt32(&b.watching, 1)
+			wait = time.Second
+			resumeToken, err = b.relay(ctx, stream)
+			atomic.StoreInt32(&b.watching, 0)
+		} else if isHistoryLost(err) {
+			// The resume point fell off the oplog, start from now.
+			resumeToken = nil
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		logging.Logger.WithError(err).Warn("Live event change stream interrupted, retrying")
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > 30*time.Second {
+			wait = 30 * time.Second
+		}
+	}
+}
+
+// relay
+// Publishes inserted events from stream until it fails,
+// returning the last resume token seen.
+func (b *LiveBus) relay(ctx context.Context, stream *mongo.ChangeStream) (bson.Raw, error) {
+	defer stream.Close(context.Background())
+	for stream.Next(ctx) {
+		var change struct {
+			FullDocument Event `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			logging.Logger.WithError(err).Warn("Failed to decode live event")
+			continue
+		}
+		b.Publish(&change.FullDocument)
+	}
+	return stream.ResumeToken(), stream.Err()
+}
+
+// isChangeStreamUnsupported
+// Reports whether err is the server refusing change streams
+// because it is not a replica set member.
+func isChangeStreamUnsupported(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == 40573
+}
+
+// isHistoryLost
+// Reports whether err is the server unable to resume
+// a change stream from the requested token.
+func isHistoryLost(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 280 || cmdErr.Code == 286)
+}
+
+// liveKey
+// Returns the subscription key of a snippet in a tenant.
+func liveKey(tenant string, id string) string {
+	return tenant + "/" + id
+}
+
+// Live
+// Returns the bus of live snippet events.
+func (m *MDService) Live() *LiveBus {
+	return m.live
+}
+
+// WatchEvents
+// Feeds the live bus from the outbox change stream until ctx is cancelled.
+func (m *MDService) WatchEvents(ctx context.Context) {
+	m.live.Watch(ctx, m.outbox())
+}
+
+// Events
+// Returns the channel of events for the subscribed snippet.
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+// Done
+// Returns a channel closed once the subscription ends.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close
+// Unsubscribes, it is safe to call more than once.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
@@ -0,0 +1,107 @@
+package md
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_LiveBusLimits
+// Subscribers are limited per snippet and in total.
+func Test_LiveBusLimits(t *testing.T) {
+	bus := NewLiveBus(config.Live{MaxSubscribers: 3, MaxPerSnippet: 2})
+
+	first, err := bus.Subscribe("", "a")
+	assert.Nil(t, err)
+	_, err = bus.Subscribe("", "a")
+	assert.Nil(t, err)
+	_, err = bus.Subscribe("", "a")
+	assert.True(t, errors.Is(err, ErrTooManySubscribers))
+
+	_, err = bus.Subscribe("tenant", "a")
+	assert.Nil(t, err)
+	_, err = bus.Subscribe("", "b")
+	assert.True(t, errors.Is(err, ErrTooManySubscribers))
+
+	first.Close()
+	first.Close()
+	assert.Equal(t, 2, bus.Subscribers())
+	_, err = bus.Subscribe("", "b")
+	assert.Nil(t, err)
+}
+
+// Test_LiveBusPublish
+// Events reach subscribers of their snippet and tenant only,
+// subscribers falling behind are dropped.
+func Test_LiveBusPublish(t *testing.T) {
+	bus := NewLiveBus(config.Live{})
+	sub, _ := bus.Subscribe("", "a")
+	other, _ := bus.Subscribe("tenant", "a")
+
+	bus.Publish(&Event{Type: EventUpdated, SnippetID: "a"}, &Event{Type: EventUpdated, SnippetID: "b"})
+	assert.Equal(t, "a", (<-sub.Events()).SnippetID)
+	assert.Len(t, sub.Events(), 0)
+	assert.Len(t, other.Events(), 0)
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		bus.Publish(&Event{Type: EventUpdated, SnippetID: "a"})
+	}
+	<-sub.Done()
+	assert.Equal(t, 1, bus.Subscribers())
+
+	bus.Close()
+	<-other.Done()
+	_, err := bus.Subscribe("", "a")
+	assert.True(t, errors.Is(err, ErrTooManySubscribers))
+}
+
+// Test_WriteEventStream
+// Events are written as SSE and the stream ends after a delete.
+func Test_WriteEventStream(t *testing.T) {
+	bus := NewLiveBus(config.Live{})
+	sub, _ := bus.Subscribe("", "a")
+	bus.Publish(
+		&Event{ID: "1", Type: EventUpdated, SnippetID: "a", Snippet: &MarkdownSnippet{ID: "a", Title: "T"}},
+		&Event{ID: "2", Type: EventDeleted, SnippetID: "a"},
+	)
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, writeEventStream(bufio.NewWriter(buf), sub, time.Minute))
+	frames := strings.Split(strings.TrimSpace(buf.String()), "\n\n")
+	assert.Len(t, frames, 3)
+	assert.Equal(t, ": connected", frames[0])
+	assert.True(t, strings.HasPrefix(frames[1], "id: 1\nevent: snippet.updated\ndata: {"))
+	assert.Contains(t, frames[1], `"title":"T"`)
+	assert.True(t, strings.HasPrefix(frames[2], "id: 2\nevent: snippet.deleted\n"))
+}
+
+// Test_LiveLocalWrites
+// Without a change stream, writes are published to live subscribers.
+func Test_LiveLocalWrites(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := context.Background()
+
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Live", Body: "# Live"})
+	assert.Nil(t, err)
+	sub, err := mdService.Live().Subscribe("", snippet.ID)
+	assert.Nil(t, err)
+	defer sub.Close()
+
+	_, err = mdService.UpdateMarkdownSnippet(ctx, &UpdateMDReq{CreateMDReq: CreateMDReq{Title: "Live", Body: "# Edited"}, ID: snippet.ID, UpdateKey: snippet.UpdateKey})
+	assert.Nil(t, err)
+	assert.Nil(t, mdService.DeleteMarkdownSnippet(ctx, snippet.ID, snippet.UpdateKey))
+
+	updated := <-sub.Events()
+	assert.Equal(t, EventUpdated, updated.Type)
+	assert.Equal(t, "# Edited", updated.Snippet.Body)
+	assert.Empty(t, updated.Snippet.UpdateKey)
+	assert.Equal(t, EventDeleted, (<-sub.Events()).Type)
+}
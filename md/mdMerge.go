@@ -0,0 +1,310 @@
+package md
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/soulxburn/mdsnips/api"
+)
+
+// Conflict markers written around conflicting lines of a merged body,
+// the current lines come first and the update's lines second.
+const (
+	conflictStart  = "<<<<<<<"
+	conflictMiddle = "======="
+	conflictEnd    = ">>>>>>>"
+)
+
+// MergeConflictError
+// Reports changes of an update that overlap changes saved
+// since the revision the update was based on.
+type MergeConflictError struct {
+	// Revision the update was merged with, the base revision
+	// to send once the conflicts are resolved.
+	Revision int64
+	// Merged title, the update's title when both changed it.
+	Title string
+	// Whether the update and the current revision changed the title differently.
+	TitleConflict bool
+	// Merged body, conflicting lines are placed between conflict markers.
+	Body string
+	// Merged files, nil when the update leaves them unchanged. Conflicting
+	// lines of a file are placed between conflict markers.
+	Files []SnippetFile
+	// Names of the files the update and the current revision changed differently.
+	FileConflicts []string
+	// Number of conflicting regions, including the title.
+	Conflicts int
+}
+
+func (e *MergeConflictError) Error() string {
+	return fmt.Sprintf("%d conflicts merging with revision %d", e.Conflicts, e.Revision)
+}
+
+// Unwrap
+// Merge conflicts are conflicts, so errors.Is(err, ErrConflict) holds.
+func (e *MergeConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// MergeConflictResponse
+// Problem details for an update conflicting with newer changes,
+// carrying the conflict marked content for manual resolution.
+type MergeConflictResponse struct {
+	api.ErrorResponse
+	// Revision to send as baseRevision with the resolved update.
+	Revision int64 `json:"revision" example:"5"`
+	// Merged title, the update's title when both changed it.
+	Title string `json:"title" example:"SouLxBurN Is Awesome!"`
+	// Whether the title was changed differently.
+	TitleConflict bool `json:"titleConflict"`
+	// Merged body with conflict markers.
+	Body string `json:"body" example:"<<<<<<< revision 5\nTheirs\n=======\nMine\n>>>>>>> update"`
+	// Merged files with conflict markers, omitted when the update leaves them unchanged.
+	Files []SnippetFile `json:"files,omitempty"`
+	// Names of the files changed differently.
+	FileConflicts []string `json:"fileConflicts,omitempty" example:"deploy.sh"`
+	// Number of conflicting regions.
+	Conflicts int `json:"conflicts" example:"1"`
+}
+
+// mergeUpdate
+// Saves an update edited from an older revision of the snippet,
+// merging it three ways with the changes saved since. Files
+// are merged by name.
+// Returns a *MergeConflictError when the changes overlap.
+func (m *MDService) mergeUpdate(ctx context.Context, req *UpdateMDReq) (*MarkdownSnippet, error) {
+	for attempt := 1; ; attempt++ {
+		current, err := m.GetMarkdownSnippet(ctx, req.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		title, body, files, source := req.Title, req.Body, req.Files, SourceUpdate
+		switch {
+		case req.BaseRevision > current.Revision:
+			return nil, snippetError("update", req.ID,
+				fmt.Errorf("%w: base revision %d is newer than revision %d", ErrInvalidRevision, req.BaseRevision, current.Revision))
+		case req.BaseRevision < current.Revision:
+			base, err := m.GetRevision(ctx, req.ID, req.BaseRevision)
+			if errors.Is(err, ErrNotFound) {
+				return nil, snippetError("update", req.ID,
+					fmt.Errorf("%w: base revision %d not found", ErrInvalidRevision, req.BaseRevision))
+			}
+			if err != nil {
+				return nil, err
+			}
+			merged := mergeSnippet(base, current, &req.CreateMDReq)
+			if merged.Conflicts > 0 {
+				return nil, snippetError("update", req.ID, merged)
+			}
+			title, body, files, source = merged.Title, merged.Body, merged.Files, SourceMerge
+			if errs := api.ValidateStruct(&CreateMDReq{Title: title, Body: body, Files: files}); errs != nil {
+				return nil, snippetError("update", req.ID, errs)
+			}
+		}
+
+		set := updateFields(title, body, files)
+		snippet, err := m.revise(ctx, "update", req.ID, set, source, current.Revision)
+		if errors.Is(err, ErrConflict) && attempt < patchAttempts {
+			continue
+		}
+		return snippet, err
+	}
+}
+
+// mergeSnippet
+// Merges the changes from base to update into current.
+func mergeSnippet(base *Revision, current *MarkdownSnippet, update *CreateMDReq) *MergeConflictError {
+	result := &MergeConflictError{Revision: current.Revision}
+
+	result.Title = update.Title
+	switch {
+	case update.Title == base.Title:
+		result.Title = current.Title
+	case current.Title != base.Title && current.Title != update.Title:
+		result.TitleConflict = true
+		result.Conflicts++
+	}
+
+	label := fmt.Sprintf("revision %d", current.Revision)
+	body, conflicts := mergeText(base.Body, current.Body, update.Body, label)
+	result.Body = body
+	result.Conflicts += conflicts
+
+	if update.Files != nil {
+		files, conflicts := mergeFiles(base.Files, current.Files, prepareFiles(update.Files), label)
+		result.Files, result.FileConflicts = prepareFiles(files), conflicts
+		result.Conflicts += len(conflicts)
+	}
+	return result
+}
+
+// mergeText
+// Merges the changes from base to update into current line by line,
+// keeping a changed trailing newline. Returns the merged text and
+// the number of conflicting regions, labelled label and `update`.
+func mergeText(base string, current string, update string, label string) (string, int) {
+	baseLines, baseEOL := splitLines(base)
+	currentLines, currentEOL := splitLines(current)
+	updateLines, updateEOL := splitLines(update)
+	lines, conflicts := merge3(baseLines, currentLines, updateLines, label, "update")
+
+	eol := currentEOL
+	if currentEOL == baseEOL {
+		eol = updateEOL
+	}
+	return joinLines(lines, eol), conflicts
+}
+
+// mergeFiles
+// Merges the changes from base to update into current file by file,
+// matching them by name. Files added, removed or edited by one side only
+// take that side, files both sides edited have their contents merged.
+// A file removed by one side and edited by the other is kept and conflicts.
+// Returns the merged files, in update order followed by those only the
+// current revision added, and the names of conflicting files.
+func mergeFiles(base []SnippetFile, current []SnippetFile, update []SnippetFile, label string) ([]SnippetFile, []string) {
+	baseFiles, currentFiles, updateFiles := filesByName(base), filesByName(current), filesByName(update)
+	names := make([]string, 0, len(update)+len(current))
+	for _, file := range update {
+		names = append(names, file.Name)
+	}
+	for _, file := range current {
+		if updateFiles[file.Name] == nil {
+			names = append(names, file.Name)
+		}
+	}
+
+	merged := make([]SnippetFile, 0, len(names))
+	var conflicts []string
+	for _, name := range names {
+		b, c, u := baseFiles[name], currentFiles[name], updateFiles[name]
+		var file *SnippetFile
+		switch {
+		case sameFile(u, b):
+			file = c
+		case sameFile(c, b), sameFile(c, u):
+			file = u
+		case c == nil || u == nil:
+			// Removed by one side, edited by the other.
+			conflicts = append(conflicts, name)
+			file = c
+			if file == nil {
+				file = u
+			}
+		default:
+			edited := *u
+			if b != nil && u.Language == b.Language {
+				edited.Language = c.Language
+			}
+			var baseContent string
+			if b != nil {
+				baseContent = b.Content
+			}
+			content, n := mergeText(baseContent, c.Content, u.Content, label)
+			if n > 0 {
+				conflicts = append(conflicts, name)
+			}
+			edited.Content = content
+			file = &edited
+		}
+		if file != nil {
+			merged = append(merged, *file)
+		}
+	}
+	return merged, conflicts
+}
+
+// filesByName
+// Returns files indexed by name.
+func filesByName(files []SnippetFile) map[string]*SnippetFile {
+	byName := make(map[string]*SnippetFile, len(files))
+	for i := range files {
+		byName[files[i].Name] = &files[i]
+	}
+	return byName
+}
+
+// sameFile
+// Reports whether a and b are both absent, or have the same content and language.
+func sameFile(a *SnippetFile, b *SnippetFile) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Content == b.Content && a.Language == b.Language
+}
+
+// merge3
+// Merges the changes from base to b into a, line by line as diff3 does.
+// Regions a and b both changed differently are kept between conflict
+// markers labelled labelA and labelB. Returns the merged lines and the
+// number of conflicting regions.
+func merge3(base []string, a []string, b []string, labelA string, labelB string) ([]string, int) {
+	inA := matchIndex(base, a)
+	inB := matchIndex(base, b)
+
+	var merged []string
+	conflicts := 0
+	o, i, j := 0, 0, 0
+	for o < len(base) || i < len(a) || j < len(b) {
+		// The next base line kept by both sides ends the current region.
+		next := o
+		for next < len(base) && (inA[next] < 0 || inB[next] < 0) {
+			next++
+		}
+		endA, endB := len(a), len(b)
+		if next < len(base) {
+			endA, endB = inA[next], inB[next]
+		}
+
+		if next == o && endA == i && endB == j {
+			merged = append(merged, base[o])
+			o, i, j = o+1, i+1, j+1
+			continue
+		}
+
+		baseRegion, aRegion, bRegion := base[o:next], a[i:endA], b[j:endB]
+		switch {
+		case equalLines(aRegion, baseRegion):
+			merged = append(merged, bRegion...)
+		case equalLines(bRegion, baseRegion), equalLines(aRegion, bRegion):
+			merged = append(merged, aRegion...)
+		default:
+			conflicts++
+			merged = append(merged, conflictStart+" "+labelA)
+			merged = append(merged, aRegion...)
+			merged = append(merged, conflictMiddle)
+			merged = append(merged, bRegion...)
+			merged = append(merged, conflictEnd+" "+labelB)
+		}
+		o, i, j = next, endA, endB
+	}
+	return merged, conflicts
+}
+
+// matchIndex
+// Returns the line of other matching each line of base, or -1.
+func matchIndex(base []string, other []string) []int {
+	index := make([]int, len(base))
+	for i := range index {
+		index[i] = -1
+	}
+	for _, m := range matchLines(base, other) {
+		index[m.A] = m.B
+	}
+	return index
+}
+
+func equalLines(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
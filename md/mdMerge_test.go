@@ -0,0 +1,182 @@
+package md
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_MatchLines
+// Matches are increasing, equal lines, and as many as
+// the longest common subsequence found by dynamic programming.
+func Test_MatchLines(t *testing.T) {
+	random := rand.New(rand.NewSource(7))
+	lines := func() []string {
+		out := make([]string, random.Intn(30))
+		for i := range out {
+			out[i] = string(rune('a' + random.Intn(4)))
+		}
+		return out
+	}
+	for n := 0; n < 500; n++ {
+		a, b := lines(), lines()
+		matches := matchLines(a, b)
+		for i, m := range matches {
+			assert.Equal(t, a[m.A], b[m.B])
+			if i > 0 {
+				assert.True(t, m.A > matches[i-1].A && m.B > matches[i-1].B)
+			}
+		}
+		assert.Equal(t, lcsLength(a, b), len(matches), "%v %v", a, b)
+	}
+}
+
+func lcsLength(a []string, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				table[i][j] = table[i+1][j+1] + 1
+			case table[i+1][j] > table[i][j+1]:
+				table[i][j] = table[i+1][j]
+			default:
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+	return table[0][0]
+}
+
+// Test_Merge3
+// Changes to separate lines merge, changes to the same lines conflict.
+func Test_Merge3(t *testing.T) {
+	base := strings.Split("one two three four five", " ")
+
+	merged, conflicts := merge3(base,
+		strings.Split("zero one two three four five", " "),
+		strings.Split("one two 3 four", " "), "current", "update")
+	assert.Equal(t, 0, conflicts)
+	assert.Equal(t, strings.Split("zero one two 3 four", " "), merged)
+
+	merged, conflicts = merge3(base,
+		strings.Split("one 2 three four five", " "),
+		strings.Split("one 2 three four five six", " "), "current", "update")
+	assert.Equal(t, 0, conflicts)
+	assert.Equal(t, strings.Split("one 2 three four five six", " "), merged)
+
+	merged, conflicts = merge3(base,
+		strings.Split("one TWO three four five", " "),
+		strings.Split("one deux three four cinq", " "), "current", "update")
+	assert.Equal(t, 1, conflicts)
+	assert.Equal(t, []string{"one", "<<<<<<< current", "TWO", "=======", "deux", ">>>>>>> update", "three", "four", "cinq"}, merged)
+}
+
+// Test_MergeSnippet
+// Titles merge like lines, the body keeps a changed trailing newline.
+func Test_MergeSnippet(t *testing.T) {
+	base := &Revision{Title: "Runbook", Body: "# Steps\none\ntwo"}
+	current := &MarkdownSnippet{Title: "Runbook v2", Body: "# Steps\none\ntwo\n", Revision: 4}
+
+	merged := mergeSnippet(base, current, &CreateMDReq{Title: "Runbook", Body: "# Steps\none\nthree"})
+	assert.Equal(t, 0, merged.Conflicts)
+	assert.Equal(t, "Runbook v2", merged.Title)
+	assert.Equal(t, "# Steps\none\nthree\n", merged.Body)
+
+	merged = mergeSnippet(base, current, &CreateMDReq{Title: "Runbook v3", Body: base.Body})
+	assert.Equal(t, 1, merged.Conflicts)
+	assert.True(t, merged.TitleConflict)
+	assert.Equal(t, "Runbook v3", merged.Title)
+	assert.True(t, errors.Is(merged, ErrConflict))
+}
+
+// Test_MergeFiles
+// Files merge by name, edits by both sides merge line by line,
+// a file removed by one side and edited by the other conflicts.
+func Test_MergeFiles(t *testing.T) {
+	base := []SnippetFile{
+		{Name: "a.sh", Language: "shell", Content: "one\ntwo\n"},
+		{Name: "b.sh", Language: "shell", Content: "b\n"},
+		{Name: "c.sh", Language: "shell", Content: "c\n"},
+	}
+	current := []SnippetFile{
+		{Name: "a.sh", Language: "shell", Content: "zero\none\ntwo\n"},
+		{Name: "b.sh", Language: "shell", Content: "b\n"},
+		{Name: "c.sh", Language: "shell", Content: "c\n"},
+		{Name: "d.sh", Language: "shell", Content: "d\n"},
+	}
+	update := []SnippetFile{
+		{Name: "a.sh", Language: "shell", Content: "one\ntwo\nthree\n"},
+		{Name: "c.sh", Language: "shell", Content: "c\n"},
+		{Name: "e.sh", Language: "shell", Content: "e\n"},
+	}
+
+	merged, conflicts := mergeFiles(base, current, update, "revision 4")
+	assert.Empty(t, conflicts)
+	assert.Equal(t, []SnippetFile{
+		{Name: "a.sh", Language: "shell", Content: "zero\none\ntwo\nthree\n"},
+		{Name: "c.sh", Language: "shell", Content: "c\n"},
+		{Name: "e.sh", Language: "shell", Content: "e\n"},
+		{Name: "d.sh", Language: "shell", Content: "d\n"},
+	}, merged)
+
+	current[0].Content = "zero\none\nthree\n"
+	current[1].Content = "B\n"
+	update[0].Content = "one\n2\n"
+	merged, conflicts = mergeFiles(base, current, update, "revision 4")
+	assert.Equal(t, []string{"a.sh", "b.sh"}, conflicts)
+	assert.Equal(t, "zero\none\n<<<<<<< revision 4\nthree\n=======\n2\n>>>>>>> update\n", merged[0].Content)
+	assert.Equal(t, "B\n", merged[3].Content)
+
+	result := mergeSnippet(&Revision{Body: "x", Files: base}, &MarkdownSnippet{Body: "x", Files: current}, &CreateMDReq{Body: "x", Files: update})
+	assert.Equal(t, 2, result.Conflicts)
+	assert.Nil(t, mergeSnippet(&Revision{Files: base}, &MarkdownSnippet{Files: current}, &CreateMDReq{}).Files)
+}
+
+// Test_MergeUpdate
+// Updates based on an older revision merge cleanly or
+// report conflicts without saving anything.
+func Test_MergeUpdate(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := context.Background()
+
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Runbook", Body: "# Steps\none\ntwo\n"})
+	assert.Nil(t, err)
+	update := func(body string, base int64) (*MarkdownSnippet, error) {
+		return mdService.UpdateMarkdownSnippet(ctx, &UpdateMDReq{
+			CreateMDReq:  CreateMDReq{Title: "Runbook", Body: body},
+			ID:           snippet.ID,
+			UpdateKey:    snippet.UpdateKey,
+			BaseRevision: base,
+		})
+	}
+
+	_, err = update("# Steps\nzero\none\ntwo\n", 1)
+	assert.Nil(t, err)
+	merged, err := update("# Steps\none\ntwo\nthree\n", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "# Steps\nzero\none\ntwo\nthree\n", merged.Body)
+	assert.Equal(t, int64(3), merged.Revision)
+
+	_, err = update("# Steps\nzero\none\n2\n", 2)
+	conflict := new(MergeConflictError)
+	assert.True(t, errors.As(err, &conflict))
+	assert.Equal(t, int64(3), conflict.Revision)
+	assert.Contains(t, conflict.Body, "one\n<<<<<<< revision 3\ntwo\nthree\n=======\n2\n>>>>>>> update\n")
+
+	_, err = update("# Steps", 9)
+	assert.True(t, errors.Is(err, ErrInvalidRevision))
+
+	revisions, err := mdService.ListRevisions(ctx, snippet.ID)
+	assert.Nil(t, err)
+	assert.Len(t, revisions, 3)
+	assert.Equal(t, SourceMerge, revisions[0].Source)
+}
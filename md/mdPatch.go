@@ -0,0 +1,337 @@
+package md
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Patch media types accepted by PATCH /md/{id}.
+const (
+	MergePatchType = "application/merge-patch+json"
+	DiffType       = "text/x-diff"
+	PatchType      = "text/x-patch"
+	LineEditsType  = "application/vnd.mdsnips.line-edits+json"
+)
+
+// PatchConflictError
+// Reports lines a patch expected that the body does not contain.
+type PatchConflictError struct {
+	// Hunk or edit number, starting at 1.
+	Hunk int
+	// Body line the hunk was applied at, starting at 1.
+	Line int
+	// Expected line, and the line found there.
+	Expected string
+	Found    string
+}
+
+func (e *PatchConflictError) Error() string {
+	return fmt.Sprintf("hunk %d does not apply at line %d: expected %q, found %q", e.Hunk, e.Line, e.Expected, e.Found)
+}
+
+// Unwrap
+// Patch conflicts are conflicts, so errors.Is(err, ErrConflict) holds.
+func (e *PatchConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// Patch
+// A partial change applied to the current title and body of a snippet.
+type Patch interface {
+	// Apply changes snippet's title and body in place.
+	Apply(snippet *MarkdownSnippet) error
+}
+
+// MergePatch
+// An RFC 7396 JSON Merge Patch of the snippet title and body.
+type MergePatch struct {
+	// Replacement title, unchanged when nil.
+	Title *string `json:"title,omitempty" example:"SouLxBurN Is Awesome!"`
+	// Replacement body, unchanged when nil.
+	Body *string `json:"body,omitempty" example:"# Markdown Snippet\nSome Text"`
+}
+
+// ParseMergePatch
+// Parses a merge patch document. Only title and body may be
+// patched, and neither may be removed with null.
+func ParseMergePatch(data []byte) (*MergePatch, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+	}
+	patch := new(MergePatch)
+	for name, value := range fields {
+		var target **string
+		switch name {
+		case "title":
+			target = &patch.Title
+		case "body":
+			target = &patch.Body
+		default:
+			return nil, fmt.Errorf("%w: `%s` cannot be patched", ErrInvalidPatch, name)
+		}
+		if string(value) == "null" {
+			return nil, fmt.Errorf("%w: `%s` cannot be removed", ErrInvalidPatch, name)
+		}
+		if err := json.Unmarshal(value, target); err != nil {
+			return nil, fmt.Errorf("%w: `%s` must be a string", ErrInvalidPatch, name)
+		}
+	}
+	return patch, nil
+}
+
+// Apply
+// Replaces the fields set on the patch.
+func (p *MergePatch) Apply(snippet *MarkdownSnippet) error {
+	if p.Title != nil {
+		snippet.Title = *p.Title
+	}
+	if p.Body != nil {
+		snippet.Body = *p.Body
+	}
+	return nil
+}
+
+// LineEdit
+// Replaces lines Start to End of the body, inclusive and counted from 1.
+// An End of Start-1 inserts Lines before Start without replacing any.
+type LineEdit struct {
+	// First line replaced.
+	Start int `json:"start" validate:"min=1" example:"3"`
+	// Last line replaced.
+	End int `json:"end" validate:"min=0" example:"4"`
+	// Lines that must currently be at Start to End, checked when set.
+	Expect []string `json:"expect,omitempty"`
+	// Replacement lines.
+	Lines []string `json:"lines"`
+}
+
+// LineEdits
+// Line range edits of the body, all relative to the current body.
+type LineEdits struct {
+	Edits []LineEdit `json:"edits" validate:"required,min=1,max=100,dive"`
+}
+
+// Apply
+// Applies every edit to the body. Edits may not overlap.
+func (p *LineEdits) Apply(snippet *MarkdownSnippet) error {
+	lines, eol := splitLines(snippet.Body)
+	edits := append([]LineEdit(nil), p.Edits...)
+	order := make([]int, len(edits))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return edits[order[i]].Start < edits[order[j]].Start })
+
+	var result []string
+	next := 1
+	for _, i := range order {
+		edit := edits[i]
+		switch {
+		case edit.End < edit.Start-1:
+			return fmt.Errorf("%w: edit %d ends before it starts", ErrInvalidPatch, i+1)
+		case edit.Start-1 > len(lines) || edit.End > len(lines):
+			return fmt.Errorf("%w: edit %d is past the last line %d", ErrInvalidPatch, i+1, len(lines))
+		case edit.Start < next:
+			return fmt.Errorf("%w: edit %d overlaps another edit", ErrInvalidPatch, i+1)
+		}
+		current := lines[edit.Start-1 : edit.End]
+		if edit.Expect != nil {
+			if err := expectLines(i+1, edit.Start, edit.Expect, current); err != nil {
+				return err
+			}
+		}
+		result = append(result, lines[next-1:edit.Start-1]...)
+		result = append(result, edit.Lines...)
+		next = edit.End + 1
+	}
+	result = append(result, lines[next-1:]...)
+	snippet.Body = joinLines(result, eol)
+	return nil
+}
+
+// expectLines
+// Returns a PatchConflictError for the first of expected
+// missing from found, which starts at line.
+func expectLines(hunk int, line int, expected []string, found []string) error {
+	for i, want := range expected {
+		got := ""
+		if i < len(found) {
+			got = found[i]
+		}
+		if i >= len(found) || got != want {
+			return &PatchConflictError{Hunk: hunk, Line: line + i, Expected: want, Found: got}
+		}
+	}
+	if len(found) > len(expected) {
+		return &PatchConflictError{Hunk: hunk, Line: line + len(expected), Found: found[len(expected)]}
+	}
+	return nil
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// hunk
+// One hunk of a unified diff.
+type hunk struct {
+	oldStart, oldCount int
+	newCount           int
+	// Old side lines, context and removals.
+	old []string
+	// New side lines, context and additions.
+	new []string
+	// Whether either side ends without a newline.
+	oldNoEOL, newNoEOL bool
+}
+
+// UnifiedDiff
+// A unified diff of the body, as produced by `diff -u` or `git diff`.
+// Hunks must apply exactly at the lines they name.
+type UnifiedDiff struct {
+	hunks []*hunk
+}
+
+// ParseUnifiedDiff
+// Parses the hunks of a single file unified diff,
+// skipping any file headers before the first hunk.
+func ParseUnifiedDiff(diff string) (*UnifiedDiff, error) {
+	patch := new(UnifiedDiff)
+	var current *hunk
+	var last byte
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if match := hunkHeader.FindStringSubmatch(line); match != nil {
+			if err := current.check(); err != nil {
+				return nil, err
+			}
+			current = &hunk{
+				oldStart: atoi(match[1], 0),
+				oldCount: atoi(match[2], 1),
+				newCount: atoi(match[4], 1),
+			}
+			patch.hunks = append(patch.hunks, current)
+			continue
+		}
+		if current == nil {
+			// File headers such as diff, index, --- and +++.
+			continue
+		}
+		if len(line) == 0 {
+			// Editors commonly strip the space of empty context lines.
+			line = " "
+		}
+		switch line[0] {
+		case ' ':
+			current.old = append(current.old, line[1:])
+			current.new = append(current.new, line[1:])
+		case '-':
+			current.old = append(current.old, line[1:])
+		case '+':
+			current.new = append(current.new, line[1:])
+		case '\\':
+			// \ No newline at end of file, for the line before.
+			current.oldNoEOL = current.oldNoEOL || last != '+'
+			current.newNoEOL = current.newNoEOL || last != '-'
+			continue
+		default:
+			return nil, fmt.Errorf("%w: line %d is not part of a hunk", ErrInvalidPatch, n)
+		}
+		last = line[0]
+	}
+	if err := current.check(); err != nil {
+		return nil, err
+	}
+	if len(patch.hunks) == 0 {
+		return nil, fmt.Errorf("%w: no hunks found", ErrInvalidPatch)
+	}
+	return patch, nil
+}
+
+// check
+// Returns an error when the hunk's lines do not match its header.
+func (h *hunk) check() error {
+	if h == nil {
+		return nil
+	}
+	if len(h.old) != h.oldCount || len(h.new) != h.newCount {
+		return fmt.Errorf("%w: hunk at line %d has %d old and %d new lines, header says %d and %d",
+			ErrInvalidPatch, h.oldStart, len(h.old), len(h.new), h.oldCount, h.newCount)
+	}
+	return nil
+}
+
+// Apply
+// Applies every hunk to the body, checking their context and removed lines.
+func (p *UnifiedDiff) Apply(snippet *MarkdownSnippet) error {
+	lines, eol := splitLines(snippet.Body)
+	var result []string
+	next := 1
+	for i, h := range p.hunks {
+		// Hunks adding to an empty range name the line before it.
+		start := h.oldStart
+		if h.oldCount > 0 {
+			start--
+		}
+		if start+1 < next || start+h.oldCount > len(lines) {
+			return &PatchConflictError{Hunk: i + 1, Line: start + 1, Expected: firstLine(h.old), Found: lineAt(lines, start)}
+		}
+		if err := expectLines(i+1, start+1, h.old, lines[start:start+h.oldCount]); err != nil {
+			return err
+		}
+		result = append(result, lines[next-1:start]...)
+		result = append(result, h.new...)
+		next = start + h.oldCount + 1
+		if next-1 == len(lines) {
+			eol = !h.newNoEOL
+		}
+	}
+	result = append(result, lines[next-1:]...)
+	snippet.Body = joinLines(result, eol)
+	return nil
+}
+
+// splitLines
+// Splits body into lines, reporting whether it ends with a newline.
+func splitLines(body string) ([]string, bool) {
+	if body == "" {
+		return nil, false
+	}
+	eol := strings.HasSuffix(body, "\n")
+	return strings.Split(strings.TrimSuffix(body, "\n"), "\n"), eol
+}
+
+// joinLines
+// Joins lines into a body, ending it with a newline when eol is set.
+func joinLines(lines []string, eol bool) string {
+	body := strings.Join(lines, "\n")
+	if eol && len(lines) > 0 {
+		body += "\n"
+	}
+	return body
+}
+
+func lineAt(lines []string, i int) string {
+	if i >= 0 && i < len(lines) {
+		return lines[i]
+	}
+	return ""
+}
+
+func firstLine(lines []string) string {
+	return lineAt(lines, 0)
+}
+
+func atoi(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
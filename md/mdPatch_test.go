@@ -0,0 +1,175 @@
+package md
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_MergePatch
+// Only title and body may be set, omitted fields are unchanged.
+func Test_MergePatch(t *testing.T) {
+	patch, err := ParseMergePatch([]byte(`{"title":"Renamed"}`))
+	assert.Nil(t, err)
+	snippet := &MarkdownSnippet{Title: "Runbook", Body: "# Body"}
+	assert.Nil(t, patch.Apply(snippet))
+	assert.Equal(t, "Renamed", snippet.Title)
+	assert.Equal(t, "# Body", snippet.Body)
+
+	for _, doc := range []string{`{"title":null}`, `{"updateKey":"k"}`, `{"body":3}`, `[]`} {
+		_, err := ParseMergePatch([]byte(doc))
+		assert.True(t, errors.Is(err, ErrInvalidPatch), doc)
+	}
+}
+
+// Test_UnifiedDiff
+// Hunks apply at their stated lines, including
+// pure insertions and missing trailing newlines.
+func Test_UnifiedDiff(t *testing.T) {
+	diff := `--- a/snippet.md
++++ b/snippet.md
+@@ -1,3 +1,3 @@
+ # Title
+-old line
++new line
+
+@@ -5,0 +6,1 @@
++appended
+@@ -6 +7 @@
+-last
+\ No newline at end of file
++last
+`
+	patch, err := ParseUnifiedDiff(diff)
+	assert.Nil(t, err)
+	snippet := &MarkdownSnippet{Body: "# Title\nold line\n\nfour\nfive\nlast"}
+	assert.Nil(t, patch.Apply(snippet))
+	assert.Equal(t, "# Title\nnew line\n\nfour\nfive\nappended\nlast\n", snippet.Body)
+
+	empty, err := ParseUnifiedDiff("@@ -0,0 +1,2 @@\n+# New\n+text\n")
+	assert.Nil(t, err)
+	snippet = &MarkdownSnippet{}
+	assert.Nil(t, empty.Apply(snippet))
+	assert.Equal(t, "# New\ntext\n", snippet.Body)
+}
+
+// Test_UnifiedDiffConflict
+// Context that does not match the body reports where it failed.
+func Test_UnifiedDiffConflict(t *testing.T) {
+	patch, err := ParseUnifiedDiff("@@ -2,2 +2,2 @@\n keep\n-remove\n+add\n")
+	assert.Nil(t, err)
+	snippet := &MarkdownSnippet{Body: "one\nkeep\nchanged\n"}
+	err = patch.Apply(snippet)
+
+	conflict := new(PatchConflictError)
+	assert.True(t, errors.As(err, &conflict))
+	assert.True(t, errors.Is(err, ErrConflict))
+	assert.Equal(t, 1, conflict.Hunk)
+	assert.Equal(t, 3, conflict.Line)
+	assert.Equal(t, "remove", conflict.Expected)
+	assert.Equal(t, "changed", conflict.Found)
+	assert.Equal(t, "one\nkeep\nchanged\n", snippet.Body)
+
+	for _, diff := range []string{"", "@@ -1,2 +1,1 @@\n a\n", "@@ -1 +1 @@\n-a\n+b\n*c\n"} {
+		_, err := ParseUnifiedDiff(diff)
+		assert.True(t, errors.Is(err, ErrInvalidPatch), diff)
+	}
+}
+
+// Test_LineEdits
+// Edits apply relative to the original body, in any order,
+// and are rejected when they overlap or expect other lines.
+func Test_LineEdits(t *testing.T) {
+	patch := &LineEdits{Edits: []LineEdit{
+		{Start: 4, End: 4, Lines: []string{"D"}},
+		{Start: 2, End: 1, Lines: []string{"inserted"}},
+		{Start: 2, End: 3, Expect: []string{"b", "c"}, Lines: nil},
+	}}
+	snippet := &MarkdownSnippet{Body: "a\nb\nc\nd\n"}
+	assert.Nil(t, patch.Apply(snippet))
+	assert.Equal(t, "a\ninserted\nD\n", snippet.Body)
+
+	overlap := &LineEdits{Edits: []LineEdit{{Start: 1, End: 2}, {Start: 2, End: 2}}}
+	assert.True(t, errors.Is(overlap.Apply(&MarkdownSnippet{Body: "a\nb\n"}), ErrInvalidPatch))
+
+	past := &LineEdits{Edits: []LineEdit{{Start: 3, End: 3}}}
+	assert.True(t, errors.Is(past.Apply(&MarkdownSnippet{Body: "a\nb\n"}), ErrInvalidPatch))
+
+	mismatch := &LineEdits{Edits: []LineEdit{{Start: 1, End: 1, Expect: []string{"x"}, Lines: []string{"y"}}}}
+	conflict := new(PatchConflictError)
+	assert.True(t, errors.As(mismatch.Apply(&MarkdownSnippet{Body: "a\n"}), &conflict))
+	assert.Equal(t, "a", conflict.Found)
+}
+
+// Test_PatchHandlerValidation
+// Unsupported media types and malformed patches are
+// rejected before the snippet is looked up.
+func Test_PatchHandlerValidation(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: api.ErrorHandler})
+	InitMDHandlers(SetupUnreachableMDService(t, config.Timeouts{})).ConfigureRoutes(app)
+
+	request := func(contentType string, key string, body string) int {
+		req := httptest.NewRequest(http.MethodPatch, "/md/id", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, contentType)
+		if key != "" {
+			req.Header.Set("X-Update-Key", key)
+		}
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusBadRequest, request(MergePatchType, "", `{"title":"T"}`))
+	assert.Equal(t, http.StatusUnsupportedMediaType, request(fiber.MIMEApplicationJSON, "key", `{"title":"T"}`))
+	assert.Equal(t, http.StatusBadRequest, request(MergePatchType+"; charset=utf-8", "key", `{"id":"other"}`))
+	assert.Equal(t, http.StatusBadRequest, request(DiffType, "key", "not a diff"))
+	assert.Equal(t, http.StatusBadRequest, request(LineEditsType, "key", `{"edits":[]}`))
+}
+
+// Test_PatchMarkdownSnippet
+// Patches save a revision, conflicts and invalid results leave the snippet unchanged.
+func Test_PatchMarkdownSnippet(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := context.Background()
+
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Runbook", Body: "# Steps\none\ntwo\n"})
+	assert.Nil(t, err)
+
+	title := "Renamed"
+	patched, err := mdService.PatchMarkdownSnippet(ctx, snippet.ID, &MergePatch{Title: &title})
+	assert.Nil(t, err)
+	assert.Equal(t, "Renamed", patched.Title)
+	assert.Equal(t, snippet.Body, patched.Body)
+	assert.Equal(t, int64(2), patched.Revision)
+
+	diff, err := ParseUnifiedDiff("@@ -2,2 +2,2 @@\n one\n-two\n+three\n")
+	assert.Nil(t, err)
+	patched, err = mdService.PatchMarkdownSnippet(ctx, snippet.ID, diff)
+	assert.Nil(t, err)
+	assert.Equal(t, "# Steps\none\nthree\n", patched.Body)
+	assert.Equal(t, int64(3), patched.Revision)
+
+	_, err = mdService.PatchMarkdownSnippet(ctx, snippet.ID, diff)
+	assert.True(t, errors.Is(err, ErrConflict))
+
+	empty := ""
+	_, err = mdService.PatchMarkdownSnippet(ctx, snippet.ID, &MergePatch{Title: &empty})
+	assert.True(t, errors.As(err, new(api.ValidationErrors)))
+
+	revisions, err := mdService.ListRevisions(ctx, snippet.ID)
+	assert.Nil(t, err)
+	assert.Len(t, revisions, 3)
+	assert.Equal(t, SourcePatch, revisions[0].Source)
+
+	_, err = mdService.PatchMarkdownSnippet(ctx, "missing", diff)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
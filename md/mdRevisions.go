@@ -0,0 +1,394 @@
+package md
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/soulxburn/mdsnips/api"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// patchAttempts Times a patch is applied before a
+// concurrently updated snippet is reported as a conflict.
+const patchAttempts = 3
+
+// RevisionsCollection Collection of saved snippet revisions,
+// kept beside the markdown collection.
+const RevisionsCollection = "revisions"
+
+// Revision sources, recording which write saved a revision.
+const (
+	SourceCreate = "create"
+	SourceUpdate = "update"
+	SourcePatch  = "patch"
+	SourceMerge  = "merge"
+	SourceCollab = "collab"
+	SourceFork   = "fork"
+)
+
+// Revision
+// A saved version of a snippet's title and body.
+type Revision struct {
+	// Markdown snippet guid.
+	SnippetID string `json:"snippetId" bson:"snippetId" format:"uuid"`
+	// Revision number, starting at 1 and incremented by every save.
+	Revision int64 `json:"revision" bson:"revision" example:"3"`
+	// Markdown snippet title.
+	Title string `json:"title" bson:"title" example:"SouLxBurN Is Awesome!"`
+	// Markdown body, omitted when listing revisions.
+	Body string `json:"body,omitempty" bson:"body,omitempty" example:"# Markdown Snippet\nSome Text"`
+	// Named files beside the body, omitted when listing revisions.
+	Files []SnippetFile `json:"files,omitempty" bson:"files,omitempty"`
+	// Write that saved the revision, one of create, fork, update, patch, merge or collab.
+	Source string `json:"source" bson:"source" example:"update"`
+	// Date the revision was saved.
+	CreateDate time.Time `json:"createDate" bson:"createDate" format:"date-time"`
+	// Owning tenant, only set with filter tenancy.
+	TenantID string `json:"-" bson:"tenantId,omitempty"`
+	// Date the snippet was deleted, unset unless it is in the trash.
+	DeletedAt *time.Time `json:"-" bson:"deletedAt,omitempty"`
+	// Blob store key of a body too large to keep in Mongo.
+	BodyRef string `json:"-" bson:"bodyRef,omitempty"`
+}
+
+// RevisionIndexModels
+// Returns the revisions collection indexes.
+// Applied to the configured database by migration 3.
+func RevisionIndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{
+				{Key: "tenantId", Value: bsonx.Int32(1)},
+				{Key: "snippetId", Value: bsonx.Int32(1)},
+				{Key: "revision", Value: bsonx.Int32(-1)},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+}
+
+// ListRevisions
+// Returns the revisions of a snippet without their bodies or files, newest first.
+// Returns ErrNotFound when the snippet has none.
+func (m *MDService) ListRevisions(ctx context.Context, mdID string) ([]Revision, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Search)
+	defer cancel()
+	ctx, end := startOperation(ctx, "listRevisions")
+	defer end()
+
+	revisions := make([]Revision, 0)
+	filter := m.active(bson.D{{Key: "snippetId", Value: mdID}})
+	opts := options.Find().
+		SetProjection(bson.M{"body": 0, "files": 0}).
+		SetSort(bson.D{{Key: "revision", Value: -1}})
+	cursor, err := m.revisions().Find(ctx, filter, opts)
+	if err != nil {
+		recordMongoError(ctx, "listRevisions", mdID, err)
+		return nil, err
+	}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		recordMongoError(ctx, "listRevisions", mdID, err)
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, snippetError("listRevisions", mdID, ErrNotFound)
+	}
+	return revisions, nil
+}
+
+// GetRevision
+// Returns revision number rev of a snippet.
+// Returns ErrNotFound when it does not exist.
+func (m *MDService) GetRevision(ctx context.Context, mdID string, rev int64) (*Revision, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Read)
+	defer cancel()
+	ctx, end := startOperation(ctx, "getRevision")
+	defer end()
+
+	revision := new(Revision)
+	filter := m.active(bson.D{{Key: "snippetId", Value: mdID}, {Key: "revision", Value: rev}})
+	if err := m.revisions().FindOne(ctx, filter).Decode(revision); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, snippetError("getRevision", mdID, ErrNotFound)
+		}
+		recordMongoError(ctx, "getRevision", mdID, err)
+		return nil, err
+	}
+	if err := m.loadBody(ctx, revision.BodyRef, &revision.Body); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// CurrentRevision Revision number standing for the current snippet.
+const CurrentRevision int64 = -1
+
+// DiffParams
+// Revisions and options of a snippet diff.
+type DiffParams struct {
+	// Revision to compare from, the one before To when 0.
+	From int64
+	// Revision to compare to, the current snippet when 0.
+	To int64
+	// Unchanged lines shown around each change.
+	Context int
+	// Whether changed lines record their word changes.
+	Words bool
+}
+
+// DiffRevisions
+// Returns the changes between two revisions of a snippet.
+// Either revision may be CurrentRevision. Comparing from the
+// revision before the first compares with an empty snippet.
+// Returns ErrNotFound when the snippet or a revision does not exist.
+func (m *MDService) DiffRevisions(ctx context.Context, mdID string, params DiffParams) (*SnippetDiff, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Read)
+	defer cancel()
+	ctx, end := startOperation(ctx, "diff")
+	defer end()
+
+	if params.To == 0 {
+		params.To = CurrentRevision
+	}
+	to, err := m.revisionAt(ctx, mdID, params.To)
+	if err != nil {
+		return nil, err
+	}
+	from := &Revision{SnippetID: mdID}
+	switch {
+	case params.From == 0 && to.Revision > 1:
+		params.From = to.Revision - 1
+		fallthrough
+	case params.From != 0:
+		if from, err = m.revisionAt(ctx, mdID, params.From); err != nil {
+			return nil, err
+		}
+	}
+
+	return CompareRevisions(from, to, params), nil
+}
+
+// CompareRevisions
+// Returns the changes from revision from to revision to,
+// with the context and words of params. A zero from
+// compares with an empty snippet.
+func CompareRevisions(from *Revision, to *Revision, params DiffParams) *SnippetDiff {
+	diff := &SnippetDiff{
+		SnippetID: to.SnippetID,
+		From:      from.Revision,
+		To:        to.Revision,
+		FromTitle: from.Title,
+		ToTitle:   to.Title,
+	}
+	diff.Hunks, diff.Additions, diff.Deletions = diffBodies(from.Body, to.Body, params.Context, params.Words)
+	diff.Unified = renderUnified(diff.Hunks, revisionName(from.Revision), revisionName(to.Revision))
+	return diff
+}
+
+// revisionAt
+// Returns revision rev of a snippet, reading the
+// snippet itself for CurrentRevision.
+func (m *MDService) revisionAt(ctx context.Context, mdID string, rev int64) (*Revision, error) {
+	if rev != CurrentRevision {
+		return m.GetRevision(ctx, mdID, rev)
+	}
+	snippet, err := m.GetMarkdownSnippet(ctx, mdID)
+	if err != nil {
+		return nil, err
+	}
+	return &Revision{
+		SnippetID: snippet.ID,
+		Revision:  snippet.Revision,
+		Title:     snippet.Title,
+		Body:      snippet.Body,
+	}, nil
+}
+
+func revisionName(rev int64) string {
+	return "revision " + strconv.FormatInt(rev, 10)
+}
+
+// SaveSnapshot
+// Replaces the body of a snippet with a collaborative editing
+// snapshot edited from revision base, saving it as a new revision.
+// Changes saved since base are merged into the snapshot, the
+// returned snippet then carries the merged body.
+// Returns ErrNotFound when the snippet does not exist, ErrInvalidRevision
+// when base is unknown, or a *MergeConflictError when the changes overlap.
+func (m *MDService) SaveSnapshot(ctx context.Context, mdID string, body string, base int64) (*MarkdownSnippet, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Write)
+	defer cancel()
+	ctx, end := startOperation(ctx, "snapshot")
+	defer end()
+
+	for attempt := 1; ; attempt++ {
+		current, err := m.GetMarkdownSnippet(ctx, mdID)
+		if err != nil {
+			return nil, err
+		}
+		merged := body
+		if current.Revision != base {
+			revision, err := m.GetRevision(ctx, mdID, base)
+			if errors.Is(err, ErrNotFound) {
+				return nil, snippetError("snapshot", mdID,
+					fmt.Errorf("%w: base revision %d not found", ErrInvalidRevision, base))
+			}
+			if err != nil {
+				return nil, err
+			}
+			// The session edits the body only, title and files keep their current values.
+			result := mergeSnippet(revision, current, &CreateMDReq{Title: revision.Title, Body: body})
+			if result.Conflicts > 0 {
+				return nil, snippetError("snapshot", mdID, result)
+			}
+			merged = result.Body
+		}
+
+		snippet, err := m.revise(ctx, "snapshot", mdID, bson.D{{Key: "body", Value: merged}}, SourceCollab, current.Revision)
+		if errors.Is(err, ErrConflict) && attempt < patchAttempts {
+			continue
+		}
+		return snippet, err
+	}
+}
+
+// PatchMarkdownSnippet
+// Applies patch to the current title and body of a snippet, saving
+// the result as a new revision. When another write saves a revision
+// first, the patch is applied again to the newer one.
+// Returns ErrNotFound when the snippet does not exist, ErrInvalidPatch
+// or a *PatchConflictError when the patch does not apply, and
+// ValidationErrors when the result is not a valid snippet.
+func (m *MDService) PatchMarkdownSnippet(ctx context.Context, mdID string, patch Patch) (*MarkdownSnippet, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Write)
+	defer cancel()
+	ctx, end := startOperation(ctx, "patch")
+	defer end()
+
+	for attempt := 1; ; attempt++ {
+		current, err := m.GetMarkdownSnippet(ctx, mdID)
+		if err != nil {
+			return nil, err
+		}
+		patched := *current
+		if err := patch.Apply(&patched); err != nil {
+			return nil, snippetError("patch", mdID, err)
+		}
+		if errs := api.ValidateStruct(&CreateMDReq{Title: patched.Title, Body: patched.Body}); errs != nil {
+			return nil, snippetError("patch", mdID, errs)
+		}
+
+		set := bson.D{{Key: "title", Value: patched.Title}, {Key: "body", Value: patched.Body}}
+		snippet, err := m.revise(ctx, "patch", mdID, set, SourcePatch, current.Revision)
+		if errors.Is(err, ErrConflict) && attempt < patchAttempts {
+			continue
+		}
+		return snippet, err
+	}
+}
+
+// revise
+// Applies set to a snippet, incrementing its revision, and saves the
+// result as a revision from source. The write, the revision, its audit
+// entry and the outbox event share a transaction where supported.
+// A non-zero expected revision must still be the latest, ErrConflict
+// is returned when another revision was saved since. Large bodies
+// are stored before the write.
+func (m *MDService) revise(ctx context.Context, op string, mdID string, set bson.D, source string, expected int64) (*MarkdownSnippet, error) {
+	snippet := new(MarkdownSnippet)
+	filter := m.active(bson.D{{Key: "id", Value: mdID}})
+	if expected > 0 {
+		filter = append(filter, bson.E{Key: "revision", Value: expected})
+	}
+	update, bodyRef, err := m.storeSet(ctx, mdID, set)
+	if err != nil {
+		return nil, err
+	}
+	// The snippet before the update is returned for auditing,
+	// the updated snippet is derived from it.
+	opts := options.FindOneAndUpdate().SetProjection(bson.M{"updateKey": 0})
+
+	var event *Event
+	err = m.withTransaction(ctx, func(ctx context.Context) error {
+		before := new(MarkdownSnippet)
+		if err := m.getMarkdownCollection().FindOneAndUpdate(ctx, filter, update, opts).Decode(before); err != nil {
+			return err
+		}
+		if err := m.loadBody(ctx, before.BodyRef, &before.Body); err != nil {
+			return err
+		}
+		if err := applySet(before, set, snippet); err != nil {
+			return err
+		}
+		if setsBody(set) {
+			snippet.BodyRef = bodyRef
+		}
+		if _, err := m.revisions().InsertOne(ctx, m.newRevision(snippet, source).stored()); err != nil {
+			return err
+		}
+		if err := m.audit(ctx, m.newAuditEntry(ctx, source, mdID, before, snippet)); err != nil {
+			return err
+		}
+		event = m.newEvent(EventUpdated, snippet.ID, snippet)
+		return m.publish(ctx, event)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments) && expected > 0:
+			return nil, snippetError(op, mdID, ErrConflict)
+		case errors.Is(err, mongo.ErrNoDocuments):
+			return nil, snippetError(op, mdID, ErrNotFound)
+		}
+		recordMongoError(ctx, op, mdID, err)
+		return nil, err
+	}
+	m.live.publishLocal(event)
+
+	return snippet, nil
+}
+
+// applySet
+// Sets after to before with the fields of set applied
+// and its revision incremented, as revise updates it.
+func applySet(before *MarkdownSnippet, set interface{}, after *MarkdownSnippet) error {
+	raw, err := bson.Marshal(set)
+	if err != nil {
+		return err
+	}
+	*after = *before
+	// Decoding reuses the slice, copy it so before is left unchanged.
+	after.Files = append([]SnippetFile(nil), before.Files...)
+	if err := bson.Unmarshal(raw, after); err != nil {
+		return err
+	}
+	after.Revision++
+	return nil
+}
+
+// newRevision
+// Returns the revision recording snippet as saved by source.
+func (m *MDService) newRevision(snippet *MarkdownSnippet, source string) *Revision {
+	return &Revision{
+		SnippetID:  snippet.ID,
+		Revision:   snippet.Revision,
+		Title:      snippet.Title,
+		Body:       snippet.Body,
+		Files:      snippet.Files,
+		Source:     source,
+		CreateDate: time.Now(),
+		TenantID:   m.filterTenantID(),
+		BodyRef:    snippet.BodyRef,
+	}
+}
+
+// revisions
+// Returns the revisions collection beside the scoped markdown collection.
+func (m *MDService) revisions() *mongo.Collection {
+	return m.getMarkdownCollection().Database().Collection(RevisionsCollection)
+}
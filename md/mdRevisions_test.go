@@ -0,0 +1,73 @@
+package md
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_Revisions
+// Creates, updates and snapshots each save a revision,
+// deleting the snippet removes them.
+func Test_Revisions(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := context.Background()
+
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Runbook", Body: "# v1"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), snippet.Revision)
+
+	updated, err := mdService.UpdateMarkdownSnippet(ctx, &UpdateMDReq{CreateMDReq: CreateMDReq{Title: "Runbook", Body: "# v2"}, ID: snippet.ID, UpdateKey: snippet.UpdateKey})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), updated.Revision)
+
+	saved, err := mdService.SaveSnapshot(ctx, snippet.ID, "# v3", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), saved.Revision)
+	assert.Equal(t, "Runbook", saved.Title)
+
+	revisions, err := mdService.ListRevisions(ctx, snippet.ID)
+	assert.Nil(t, err)
+	assert.Len(t, revisions, 3)
+	assert.Equal(t, []string{SourceCollab, SourceUpdate, SourceCreate}, []string{revisions[0].Source, revisions[1].Source, revisions[2].Source})
+	assert.Empty(t, revisions[0].Body)
+
+	revision, err := mdService.GetRevision(ctx, snippet.ID, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "# v2", revision.Body)
+
+	assert.Nil(t, mdService.DeleteMarkdownSnippet(ctx, snippet.ID, snippet.UpdateKey))
+	_, err = mdService.ListRevisions(ctx, snippet.ID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = mdService.SaveSnapshot(ctx, snippet.ID, "# v4", 3)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+// Test_SaveSnapshotMerge
+// Snapshots edited from an older revision are merged
+// with the changes saved since, or rejected when they overlap.
+func Test_SaveSnapshotMerge(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := context.Background()
+
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Runbook", Body: "one\ntwo\nthree"})
+	assert.Nil(t, err)
+	_, err = mdService.UpdateMarkdownSnippet(ctx, &UpdateMDReq{CreateMDReq: CreateMDReq{Title: "Runbook", Body: "one\ntwo\nthree!"}, ID: snippet.ID, UpdateKey: snippet.UpdateKey})
+	assert.Nil(t, err)
+
+	saved, err := mdService.SaveSnapshot(ctx, snippet.ID, "one!\ntwo\nthree", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), saved.Revision)
+	assert.Equal(t, "one!\ntwo\nthree!", saved.Body)
+
+	_, err = mdService.SaveSnapshot(ctx, snippet.ID, "one?\ntwo\nthree", 1)
+	var conflict *MergeConflictError
+	assert.True(t, errors.As(err, &conflict))
+	assert.True(t, errors.Is(err, ErrConflict))
+	_, err = mdService.SaveSnapshot(ctx, snippet.ID, "one", 9)
+	assert.True(t, errors.Is(err, ErrInvalidRevision))
+}
@@ -8,9 +8,16 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/soulxburn/mdsnips/blob"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/logging"
+	"github.com/soulxburn/mdsnips/metrics"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -18,8 +25,28 @@ const (
 	collection = "markdown"
 )
 
+var tracer = otel.Tracer("github.com/soulxburn/mdsnips/md")
+
+// listProjection Snippet fields returned by listings, without the body.
+var listProjection = bson.M{"id": 1, "title": 1, "createDate": 1, "forkedFrom": 1}
+
 type MDService struct {
-	client *mongo.Client
+	client   *mongo.Client
+	mongo    config.Mongo
+	tenancy  config.Tenant
+	timeouts config.Timeouts
+	// Attachment size limits and caching.
+	attachments config.Attachments
+	// Store of large bodies and attachments, nil keeps them in Mongo.
+	blobs blob.Store
+	// Bodies larger than this many bytes are kept in blobs.
+	offloadSize int64
+	// Tenant the service is scoped to, empty when tenancy is disabled.
+	tenant string
+	// Whether writes and their outbox events can share a transaction.
+	transactions *transactions
+	// Live subscribers of snippet events.
+	live *LiveBus
 }
 
 type SortBy string
@@ -46,47 +73,139 @@ type MDSearchParams struct {
 
 // InitMDService Creates an instance of a MDService
 // Requires a reference to a mongo.Client instance
-func InitMDService(mClient *mongo.Client) *MDService {
-	return &MDService{client: mClient}
+// Database and collection names default to the package constants
+// when not provided by cfg.
+func InitMDService(mClient *mongo.Client, cfg *config.Config) *MDService {
+	mongoCfg := cfg.Mongo
+	if mongoCfg.Database == "" {
+		mongoCfg.Database = database
+	}
+	if mongoCfg.Collection == "" {
+		mongoCfg.Collection = collection
+	}
+	return &MDService{
+		client:       mClient,
+		mongo:        mongoCfg,
+		tenancy:      cfg.Tenant,
+		timeouts:     withDefaultTimeouts(cfg.Timeouts),
+		attachments:  withDefaultAttachments(cfg.Attachments),
+		offloadSize:  cfg.Blob.OffloadSize,
+		transactions: new(transactions),
+		live:         NewLiveBus(cfg.Live),
+	}
+}
+
+// withDefaultTimeouts
+// Fills unset operation budgets with a 5 second default,
+// 5 minutes for exports.
+func withDefaultTimeouts(timeouts config.Timeouts) config.Timeouts {
+	for _, budget := range []*time.Duration{&timeouts.Read, &timeouts.Write, &timeouts.Search} {
+		if *budget <= 0 {
+			*budget = 5 * time.Second
+		}
+	}
+	if timeouts.Export <= 0 {
+		timeouts.Export = 5 * time.Minute
+	}
+	return timeouts
+}
+
+// ForTenant
+// Returns a copy of the MDService with every query scoped to tenant.
+func (m *MDService) ForTenant(tenant string) *MDService {
+	scoped := *m
+	scoped.tenant = tenant
+	return &scoped
 }
 
 // CreateMarkdownSnippet
 // Errors are returned to the caller
-func (m *MDService) CreateMarkdownSnippet(mdSnip *CreateMDReq) (*MarkdownSnippet, error) {
-	mdCollection := getMarkdownCollection(m.client)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+func (m *MDService) CreateMarkdownSnippet(ctx context.Context, mdSnip *CreateMDReq) (*MarkdownSnippet, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Write)
 	defer cancel()
+	ctx, end := startOperation(ctx, "create")
+	defer end()
 
-	newSnip := &MarkdownSnippet{
-		ID:         createMDID(mdSnip.Title, mdSnip.Body),
-		Body:       mdSnip.Body,
-		Title:      mdSnip.Title,
-		UpdateKey:  createUpdateKey(mdSnip.Body),
+	newSnip := m.newSnippet(mdSnip.Title, mdSnip.Body)
+	newSnip.Files = prepareFiles(mdSnip.Files)
+	if err := m.insert(ctx, "create", newSnip, SourceCreate, SourceCreate); err != nil {
+		return nil, err
+	}
+	return newSnip, nil
+}
+
+// newSnippet
+// Returns a new snippet at revision 1 with a generated id and update key.
+func (m *MDService) newSnippet(title string, body string) *MarkdownSnippet {
+	return &MarkdownSnippet{
+		ID:         createMDID(title, body),
+		Body:       body,
+		Title:      title,
+		UpdateKey:  createUpdateKey(body),
 		CreateDate: time.Now(),
+		Revision:   1,
+		TenantID:   m.filterTenantID(),
 	}
+}
 
-	_, err := mdCollection.InsertOne(ctx, newSnip)
+// insert
+// Inserts a new snippet with its first revision from source, audited
+// as action. The write, the revision, its audit entry and the outbox
+// event share a transaction where supported. Large bodies are stored
+// first, and removed again when the snippet is not created.
+func (m *MDService) insert(ctx context.Context, op string, newSnip *MarkdownSnippet, source string, action string) error {
+	if err := m.offloadBody(ctx, newSnip); err != nil {
+		return err
+	}
+	event := m.newEvent(EventCreated, newSnip.ID, newSnip)
+	entry := m.newAuditEntry(ctx, action, newSnip.ID, nil, newSnip)
+	err := m.withTransaction(ctx, func(ctx context.Context) error {
+		if _, err := m.getMarkdownCollection().InsertOne(ctx, newSnip.stored()); err != nil {
+			return err
+		}
+		if _, err := m.revisions().InsertOne(ctx, m.newRevision(newSnip, source).stored()); err != nil {
+			return err
+		}
+		if err := m.audit(ctx, entry); err != nil {
+			return err
+		}
+		return m.publish(ctx, event)
+	})
 	if err != nil {
-		return nil, err
+		if newSnip.BodyRef != "" {
+			m.discardBlobs(ctx, newSnip.ID)
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return snippetError(op, newSnip.ID, ErrConflict)
+		}
+		recordMongoError(ctx, op, newSnip.ID, err)
+		return err
 	}
-
-	return newSnip, nil
+	m.live.publishLocal(event)
+	return nil
 }
 
 // GetMarkdownSnippet
+// Returns ErrNotFound when the snippet does not exist.
 // Errors are returned to the caller
-func (m *MDService) GetMarkdownSnippet(mdID string) (*MarkdownSnippet, error) {
-	mdCollection := getMarkdownCollection(m.client)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+func (m *MDService) GetMarkdownSnippet(ctx context.Context, mdID string) (*MarkdownSnippet, error) {
+	mdCollection := m.getMarkdownCollection()
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Read)
 	defer cancel()
+	ctx, end := startOperation(ctx, "get")
+	defer end()
 
 	snippet := new(MarkdownSnippet)
-	filter := bson.D{{Key: "id", Value: mdID}}
+	filter := m.active(bson.D{{Key: "id", Value: mdID}})
 	opts := options.FindOne().SetProjection(bson.M{"updateKey": 0})
 	if err := mdCollection.FindOne(ctx, filter, opts).Decode(snippet); err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, nil
+			return nil, snippetError("get", mdID, ErrNotFound)
 		}
+		recordMongoError(ctx, "get", mdID, err)
+		return nil, err
+	}
+	if err := m.loadBody(ctx, snippet.BodyRef, &snippet.Body); err != nil {
 		return nil, err
 	}
 	return snippet, nil
@@ -96,23 +215,27 @@ func (m *MDService) GetMarkdownSnippet(mdID string) (*MarkdownSnippet, error) {
 // GetAllMarkdownSnippets
 // Gets all Markdown Snippets without body
 // Errors are returned to the caller
-func (m *MDService) GetAllMarkdownSnippets() ([]MDListItem, error) {
-	mdCollection := getMarkdownCollection(m.client)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+func (m *MDService) GetAllMarkdownSnippets(ctx context.Context) ([]MDListItem, error) {
+	mdCollection := m.getMarkdownCollection()
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Search)
 	defer cancel()
+	ctx, end := startOperation(ctx, "getAll")
+	defer end()
 
 	snippets := make([]MDListItem, 0)
-	filter := bson.D{}
-	opts := options.Find().SetProjection(bson.M{"id": 1, "title": 1, "createDate": 1})
+	filter := m.active(bson.D{})
+	opts := options.Find().SetProjection(listProjection)
 	cursor, err := mdCollection.Find(ctx, filter, opts)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, nil
 		}
+		recordMongoError(ctx, "getAll", "", err)
 		return nil, err
 	}
 
 	if err = cursor.All(ctx, &snippets); err != nil {
+		recordMongoError(ctx, "getAll", "", err)
 		return nil, err
 	}
 
@@ -122,27 +245,18 @@ func (m *MDService) GetAllMarkdownSnippets() ([]MDListItem, error) {
 // SearchMarkdownSnippets
 // Searches through all Markdown Snippets and returns then without their body.
 // Errors are returned to the caller
-func (m *MDService) SearchMarkdownSnippets(searchParams MDSearchParams) ([]MDListItem, error) {
-	mdCollection := getMarkdownCollection(m.client)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+func (m *MDService) SearchMarkdownSnippets(ctx context.Context, searchParams MDSearchParams) ([]MDListItem, error) {
+	mdCollection := m.getMarkdownCollection()
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Search)
 	defer cancel()
-
-	sortby := bson.D{}
-	switch searchParams.SortBy {
-	case CreateDate_ASC:
-		sortby = bson.D{{Key: "createDate", Value: 1}}
-	case CreateDate_DESC:
-		sortby = bson.D{{Key: "createDate", Value: -1}}
-	}
+	ctx, end := startOperation(ctx, "search")
+	defer end()
 
 	snippets := make([]MDListItem, 0)
-	filter := bson.D{}
-	if searchParams.Text != "" {
-		filter = bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: searchParams.Text}}}}
-	}
+	filter := m.searchFilter(searchParams)
 	opts := options.Find()
-	opts.SetProjection(bson.M{"id": 1, "title": 1, "createDate": 1})
-	opts.SetSort(sortby)
+	opts.SetProjection(listProjection)
+	opts.SetSort(searchSort(searchParams))
 	opts.SetSkip(searchParams.Skip)
 	opts.SetLimit(searchParams.Limit)
 	cursor, err := mdCollection.Find(ctx, filter, opts)
@@ -150,80 +264,282 @@ func (m *MDService) SearchMarkdownSnippets(searchParams MDSearchParams) ([]MDLis
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, nil
 		}
+		recordMongoError(ctx, "search", "", err)
 		return nil, err
 	}
 
 	if err = cursor.All(ctx, &snippets); err != nil {
+		recordMongoError(ctx, "search", "", err)
 		return nil, err
 	}
 
 	return snippets, nil
 }
 
+// CreateMarkdownSnippets
+// Inserts each of reqs like CreateMarkdownSnippet, with its revision,
+// audit entry and event in one transaction, so one failure does not
+// prevent the rest. Returns the snippets and per request errors, both
+// indexed like reqs.
+func (m *MDService) CreateMarkdownSnippets(ctx context.Context, reqs []*CreateMDReq) ([]*MarkdownSnippet, []error) {
+	snippets := make([]*MarkdownSnippet, len(reqs))
+	errs := make([]error, len(reqs))
+	ctx, end := startOperation(ctx, "createMany")
+	defer end()
+
+	for i, req := range reqs {
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+			continue
+		}
+		snippet := m.newSnippet(req.Title, req.Body+strconv.Itoa(i))
+		snippet.Body = req.Body
+		snippet.Files = prepareFiles(req.Files)
+		if errs[i] = m.insertWithTimeout(ctx, snippet); errs[i] == nil {
+			snippets[i] = snippet
+		}
+	}
+	return snippets, errs
+}
+
+// insertWithTimeout
+// Inserts an imported snippet within the write timeout.
+func (m *MDService) insertWithTimeout(ctx context.Context, snippet *MarkdownSnippet) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Write)
+	defer cancel()
+	return m.insert(ctx, "createMany", snippet, SourceCreate, AuditImport)
+}
+
+// offloaded
+// Returns the ids of snippets with their body in the blob store.
+func offloaded(snippets []*MarkdownSnippet) []string {
+	var ids []string
+	for _, snippet := range snippets {
+		if snippet != nil && snippet.BodyRef != "" {
+			ids = append(ids, snippet.ID)
+		}
+	}
+	return ids
+}
+
 // UpdateMarkdownSnippet
+// Returns the updated snippet, or ErrNotFound when it does not exist.
+// Each update is saved as a new revision.
 // Errors are returned to the caller
-func (m *MDService) UpdateMarkdownSnippet(patch *UpdateMDReq) error {
-	mdCollection := getMarkdownCollection(m.client)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+func (m *MDService) UpdateMarkdownSnippet(ctx context.Context, patch *UpdateMDReq) (*MarkdownSnippet, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Write)
 	defer cancel()
+	ctx, end := startOperation(ctx, "update")
+	defer end()
 
-	// This probably isn't the best way to do this.
-	type updateSnippet struct {
-		Title string `bson:"title"`
-		Body  string `bson:"body"`
+	if patch.BaseRevision > 0 {
+		return m.mergeUpdate(ctx, patch)
 	}
 
 	// Update Fields
-	updates := updateSnippet{patch.Title, patch.Body}
+	updates := updateFields(patch.Title, patch.Body, patch.Files)
 
-	filter := bson.D{{Key: "id", Value: patch.ID}}
-	update := bson.M{"$set": updates}
-	if _, err := mdCollection.UpdateOne(ctx, filter, update); err != nil {
-		return err
-	}
+	return m.revise(ctx, "update", patch.ID, updates, SourceUpdate, 0)
+}
 
-	return nil
+// updateFields
+// Returns the $set of an update replacing the title and body,
+// and the files unless they were omitted.
+func updateFields(title string, body string, files []SnippetFile) bson.D {
+	set := bson.D{{Key: "title", Value: title}, {Key: "body", Value: body}}
+	if files != nil {
+		set = append(set, bson.E{Key: "files", Value: prepareFiles(files)})
+	}
+	return set
 }
 
 // ValidateIdAndKey
 // Fetch snippet by Id and validate against updateKey
-func (m *MDService) ValidateIdAndKey(mdID string, updateKey string) bool {
-	mdCollection := getMarkdownCollection(m.client)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+// Returns ErrNotFound or ErrInvalidKey when validation fails.
+func (m *MDService) ValidateIdAndKey(ctx context.Context, mdID string, updateKey string) error {
+	return m.validateKey(ctx, m.active(bson.D{{Key: "id", Value: mdID}}), mdID, updateKey)
+}
+
+// validateKey
+// Validates updateKey against the snippet matching filter.
+// Returns ErrNotFound or ErrInvalidKey when validation fails.
+func (m *MDService) validateKey(ctx context.Context, filter bson.D, mdID string, updateKey string) error {
+	mdCollection := m.getMarkdownCollection()
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Read)
 	defer cancel()
+	ctx, end := startOperation(ctx, "validate")
+	defer end()
 
 	snippet := make(map[string]string)
-	filter := bson.D{{Key: "id", Value: mdID}}
 	opts := options.FindOne().SetProjection(bson.M{"updateKey": 1})
 	if err := mdCollection.FindOne(ctx, filter, opts).Decode(snippet); err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return false
+			return snippetError("validate", mdID, ErrNotFound)
 		}
-		return false
+		recordMongoError(ctx, "validate", mdID, err)
+		return err
 	}
-	return updateKey == snippet["updateKey"]
+	if updateKey != snippet["updateKey"] {
+		return snippetError("validate", mdID, ErrInvalidKey)
+	}
+	return nil
 }
 
 // DeleteMarkdownSnippet
+// Moves the snippet and its revisions to the trash, hiding them until
+// restored or purged. Returns ErrNotFound when the snippet does not exist.
 // Errors are returned to the caller
-func (m *MDService) DeleteMarkdownSnippet(mdID string, updateKey string) error {
-	mdCollection := getMarkdownCollection(m.client)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+func (m *MDService) DeleteMarkdownSnippet(ctx context.Context, mdID string, updateKey string) error {
+	mdCollection := m.getMarkdownCollection()
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Write)
 	defer cancel()
-
-	filter := bson.D{{Key: "id", Value: mdID}}
-	if _, err := mdCollection.DeleteOne(ctx, filter); err != nil {
+	ctx, end := startOperation(ctx, "delete")
+	defer end()
+
+	filter := m.active(bson.D{{Key: "id", Value: mdID}})
+	trash := bson.D{{Key: "$set", Value: bson.D{{Key: "deletedAt", Value: time.Now()}}}}
+	opts := options.FindOneAndUpdate().SetProjection(bson.M{"title": 1, "body": 1, "bodyRef": 1, "files": 1})
+	event := m.newEvent(EventDeleted, mdID, nil)
+	err := m.withTransaction(ctx, func(ctx context.Context) error {
+		before := new(MarkdownSnippet)
+		if err := mdCollection.FindOneAndUpdate(ctx, filter, trash, opts).Decode(before); err != nil {
+			return err
+		}
+		if err := m.loadBody(ctx, before.BodyRef, &before.Body); err != nil {
+			return err
+		}
+		revisions := m.scoped(bson.D{{Key: "snippetId", Value: mdID}})
+		if _, err := m.revisions().UpdateMany(ctx, revisions, trash); err != nil {
+			return err
+		}
+		if err := m.audit(ctx, m.newAuditEntry(ctx, AuditDelete, mdID, before, nil)); err != nil {
+			return err
+		}
+		return m.publish(ctx, event)
+	})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return snippetError("delete", mdID, ErrNotFound)
+		}
+		recordMongoError(ctx, "delete", mdID, err)
 		return err
 	}
+	m.live.publishLocal(event)
 
 	return nil
 }
 
+// ExportMarkdownSnippets
+// Returns a cursor over every snippet matching params, including
+// bodies and excluding update keys. A zero Limit exports everything.
+// The cursor must be closed, it is bounded by the export time budget.
+func (m *MDService) ExportMarkdownSnippets(ctx context.Context, params MDSearchParams) (*SnippetCursor, error) {
+	mdCollection := m.getMarkdownCollection()
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Export)
+	ctx, end := startOperation(ctx, "export")
+
+	opts := options.Find().
+		SetProjection(bson.M{"updateKey": 0}).
+		SetSort(searchSort(params)).
+		SetSkip(params.Skip).
+		SetLimit(params.Limit)
+	cursor, err := mdCollection.Find(ctx, m.searchFilter(params), opts)
+	if err != nil {
+		recordMongoError(ctx, "export", "", err)
+		end()
+		cancel()
+		return nil, err
+	}
+	return &SnippetCursor{ctx: ctx, cursor: cursor, mdService: m, close: func() {
+		end()
+		cancel()
+	}}, nil
+}
+
+// searchFilter
+// Returns the scoped filter matching params, excluding the trash.
+// Bodies moved to the blob store are not in Mongo, so $text only
+// matches those snippets by title and files.
+func (m *MDService) searchFilter(params MDSearchParams) bson.D {
+	filter := bson.D{}
+	if params.Text != "" {
+		filter = bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: params.Text}}}}
+	}
+	return m.active(filter)
+}
+
+// searchSort
+// Returns the sort order requested by params.
+func searchSort(params MDSearchParams) bson.D {
+	switch params.SortBy {
+	case CreateDate_ASC:
+		return bson.D{{Key: "createDate", Value: 1}}
+	case CreateDate_DESC:
+		return bson.D{{Key: "createDate", Value: -1}}
+	}
+	return bson.D{}
+}
+
 // getMarkdownCollection
-// Accepts a mongo.Client reference
-// Returns a reference to the `mdsnips.markdown` collection.
-func getMarkdownCollection(mClient *mongo.Client) *mongo.Collection {
-	return mClient.Database("mdsnips").Collection("markdown")
+// Returns a reference to the markdown collection for the scoped tenant.
+// Tenant databases are created with their indexes by the migrations.
+func (m *MDService) getMarkdownCollection() *mongo.Collection {
+	if m.tenancy.Mode != config.TenancyDatabase {
+		return m.client.Database(m.mongo.Database).Collection(m.mongo.Collection)
+	}
+
+	return m.client.Database(m.tenancy.DatabasePrefix + m.tenant).Collection(m.mongo.Collection)
+}
+
+// scoped
+// Restricts filter to the scoped tenant when using filter isolation.
+func (m *MDService) scoped(filter bson.D) bson.D {
+	if m.tenancy.Mode != config.TenancyFilter {
+		return filter
+	}
+	return append(filter, bson.E{Key: "tenantId", Value: m.tenant})
+}
+
+// active
+// Restricts filter to the scoped tenant's snippets that are not in the trash.
+func (m *MDService) active(filter bson.D) bson.D {
+	return append(m.scoped(filter), bson.E{Key: "deletedAt", Value: bson.D{{Key: "$exists", Value: false}}})
+}
+
+// filterTenantID
+// Returns the tenantId to persist on new documents.
+func (m *MDService) filterTenantID() string {
+	if m.tenancy.Mode != config.TenancyFilter {
+		return ""
+	}
+	return m.tenant
+}
+
+// startOperation
+// Starts a trace span and latency timer for a service operation,
+// call the returned func once the operation completes.
+func startOperation(ctx context.Context, op string) (context.Context, func()) {
+	ctx, span := tracer.Start(ctx, "MDService."+op)
+	observe := metrics.ObserveMongo(op)
+	return ctx, func() {
+		observe()
+		span.End()
+	}
+}
+
+// recordMongoError
+// Logs a failed Mongo operation with the request id carried by ctx,
+// counts it against op and records it on the operation span.
+func recordMongoError(ctx context.Context, op string, mdID string, err error) {
+	metrics.MongoErrors.WithLabelValues(op).Inc()
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	entry := logging.FromContext(ctx).WithError(err).WithField("op", op)
+	if mdID != "" {
+		entry = entry.WithField("snippetId", mdID)
+	}
+	entry.Error("Mongo operation failed")
 }
 
 // createUpdateKey
@@ -2,13 +2,18 @@ package md
 
 import (
 	"context"
+	"errors"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/soulxburn/mdsnips/client"
+	"github.com/soulxburn/mdsnips/config"
 	"github.com/soulxburn/mdsnips/testutils"
 
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // SetupMDService
@@ -16,10 +21,17 @@ import (
 // returns a MDService connected to the Mongo Test Container,
 // and a cleanup function for tearing down the container.
 func SetupMDService(t *testing.T) (*MDService, func(t *testing.T)) {
+	return SetupMDServiceWithConfig(t, &config.Config{})
+}
+
+// SetupMDServiceWithConfig
+// SetupMDService using the provided configuration.
+func SetupMDServiceWithConfig(t *testing.T, cfg *config.Config) (*MDService, func(t *testing.T)) {
 	// Initialize
+	testutils.SkipContainers(t)
 	mCont, err := testutils.SetupMongoTestContainer()
 	if err != nil {
-		log.Fatal("Failed to initialize mongo container")
+		t.Fatalf("Failed to initialize mongo container: %s", err)
 	}
 
 	mClient, err := client.InitMongoClient(mCont.ConnectionString)
@@ -27,7 +39,7 @@ func SetupMDService(t *testing.T) (*MDService, func(t *testing.T)) {
 		log.Fatal("Failed to connection to mongo container")
 	}
 
-	return InitMDService(mClient), func(t *testing.T) {
+	return InitMDService(mClient, cfg), func(t *testing.T) {
 		mCont.Container.Terminate(context.Background())
 	}
 }
@@ -40,14 +52,14 @@ func Test_CreateMarkdownSnippet(t *testing.T) {
 	expectedBody := "# Title\n##Subhead\nDetails...Details...Details..."
 	req := &CreateMDReq{Body: expectedBody}
 
-	snippet, err := mdService.CreateMarkdownSnippet(req)
+	snippet, err := mdService.CreateMarkdownSnippet(context.Background(), req)
 	assert.Nil(t, err)
 	assert.NotNil(t, snippet)
 	assert.NotEmpty(t, snippet.ID)
 	assert.NotEmpty(t, snippet.CreateDate)
 	assert.Equal(t, expectedBody, snippet.Body)
 
-	persistedSnip, err := mdService.GetMarkdownSnippet(snippet.ID)
+	persistedSnip, err := mdService.GetMarkdownSnippet(context.Background(), snippet.ID)
 	assert.Nil(t, err)
 	assert.NotNil(t, persistedSnip)
 	assert.NotEmpty(t, persistedSnip.ID)
@@ -66,12 +78,12 @@ func Test_UpdateMarkdownSnippet(t *testing.T) {
 	req := &CreateMDReq{Body: initialBody}
 
 	// Execute
-	initialSnip, err := mdService.CreateMarkdownSnippet(req)
+	initialSnip, err := mdService.CreateMarkdownSnippet(context.Background(), req)
 	assert.Nil(t, err)
 	assert.Equal(t, initialBody, initialSnip.Body)
 
-	upReq := &UpdateMDReq{ID: initialSnip.ID, Body: updateBody}
-	updatedSnip, err := mdService.UpdateMarkdownSnippet(upReq)
+	upReq := &UpdateMDReq{ID: initialSnip.ID, CreateMDReq: CreateMDReq{Body: updateBody}}
+	updatedSnip, err := mdService.UpdateMarkdownSnippet(context.Background(), upReq)
 	assert.Nil(t, err)
 	assert.Equal(t, initialSnip.ID, updatedSnip.ID)
 	assert.Equal(t, initialSnip.CreateDate.Unix(), updatedSnip.CreateDate.Unix())
@@ -88,13 +100,92 @@ func Test_GetAllMarkdownSnippets(t *testing.T) {
 	req := &CreateMDReq{Body: expectedBody}
 
 	for i := 0; i < 5; i++ {
-		_, err := mdService.CreateMarkdownSnippet(req)
+		_, err := mdService.CreateMarkdownSnippet(context.Background(), req)
 		assert.Nil(t, err)
 	}
 
-	persistedSnips, err := mdService.GetAllMarkdownSnippets()
+	persistedSnips, err := mdService.GetAllMarkdownSnippets(context.Background())
 	assert.Nil(t, err)
 	assert.NotNil(t, persistedSnips)
 	assert.NotEmpty(t, persistedSnips)
 	assert.Len(t, persistedSnips, 5)
 }
+
+// Test_TenantFilterScoping
+// Snippets created by one tenant should not be visible to another
+// when using filter isolation.
+func Test_TenantFilterScoping(t *testing.T) {
+	mdService, cleanup := SetupMDServiceWithConfig(t, &config.Config{
+		Tenant: config.Tenant{Mode: config.TenancyFilter},
+	})
+	defer cleanup(t)
+	req := &CreateMDReq{Title: "Tenant", Body: "# Tenant"}
+
+	snippet, err := mdService.ForTenant("acme").CreateMarkdownSnippet(context.Background(), req)
+	assert.Nil(t, err)
+
+	owned, err := mdService.ForTenant("acme").GetMarkdownSnippet(context.Background(), snippet.ID)
+	assert.Nil(t, err)
+	assert.NotNil(t, owned)
+
+	other, err := mdService.ForTenant("globex").GetMarkdownSnippet(context.Background(), snippet.ID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.Nil(t, other)
+
+	otherSnips, err := mdService.ForTenant("globex").GetAllMarkdownSnippets(context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, otherSnips)
+}
+
+// SetupUnreachableMDService
+// Returns a MDService whose mongo client points at a closed port,
+// so every operation blocks in server selection until its context ends.
+func SetupUnreachableMDService(t *testing.T, timeouts config.Timeouts) *MDService {
+	opts := options.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetServerSelectionTimeout(time.Minute)
+	mClient, err := mongo.Connect(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Failed to create mongo client: %s", err)
+	}
+	t.Cleanup(func() { mClient.Disconnect(context.Background()) })
+
+	return InitMDService(mClient, &config.Config{Timeouts: timeouts})
+}
+
+// Test_CancelledContext
+// Cancelling the caller context should abort in-flight operations.
+func Test_CancelledContext(t *testing.T) {
+	mdService := SetupUnreachableMDService(t, config.Timeouts{Read: time.Minute})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	snippet, err := mdService.GetMarkdownSnippet(ctx, "missing")
+	assert.Nil(t, snippet)
+	assert.True(t, errors.Is(err, context.Canceled), "expected context.Canceled, got %v", err)
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
+
+// Test_OperationTimeoutBudget
+// Operations should fail once their configured budget is spent,
+// even when the caller context has no deadline.
+func Test_OperationTimeoutBudget(t *testing.T) {
+	mdService := SetupUnreachableMDService(t, config.Timeouts{Search: 50 * time.Millisecond})
+
+	start := time.Now()
+	_, err := mdService.SearchMarkdownSnippets(context.Background(), MDSearchParams{Limit: 10})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "expected context.DeadlineExceeded, got %v", err)
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
+
+// Test_CallerDeadlinePropagates
+// A caller deadline shorter than the operation budget should win.
+func Test_CallerDeadlinePropagates(t *testing.T) {
+	mdService := SetupUnreachableMDService(t, config.Timeouts{Write: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Title", Body: "Body"})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "expected context.DeadlineExceeded, got %v", err)
+}
@@ -0,0 +1,93 @@
+package md
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/soulxburn/mdsnips/logging"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	snippetCountDesc = prometheus.NewDesc(
+		"mdsnips_snippets",
+		"Number of stored markdown snippets.",
+		nil, nil,
+	)
+	snippetBytesDesc = prometheus.NewDesc(
+		"mdsnips_snippet_bytes",
+		"Total bytes of stored snippet titles, bodies and files.",
+		nil, nil,
+	)
+)
+
+// StatsCollector
+// prometheus.Collector reporting snippet counts and stored bytes,
+// queried from Mongo on every scrape.
+type StatsCollector struct {
+	mdService *MDService
+}
+
+// NewStatsCollector Creates a StatsCollector for the MDService collection.
+func NewStatsCollector(mdService *MDService) *StatsCollector {
+	return &StatsCollector{mdService: mdService}
+}
+
+// Describe implements prometheus.Collector
+func (s *StatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- snippetCountDesc
+	ch <- snippetBytesDesc
+}
+
+// Collect implements prometheus.Collector
+func (s *StatsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stats, err := s.mdService.collectStats(ctx)
+	if err != nil {
+		logging.Logger.WithError(err).Warn("Failed to collect snippet stats")
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(snippetCountDesc, prometheus.GaugeValue, float64(stats.Count))
+	ch <- prometheus.MustNewConstMetric(snippetBytesDesc, prometheus.GaugeValue, float64(stats.Bytes))
+}
+
+// snippetStats
+// Snippet count and stored title, body and file bytes.
+type snippetStats struct {
+	Count int64 `bson:"count"`
+	Bytes int64 `bson:"bytes"`
+}
+
+// collectStats
+// Aggregates the count and size of every snippet in scope, excluding the trash.
+func (m *MDService) collectStats(ctx context.Context) (*snippetStats, error) {
+	mdCollection := m.getMarkdownCollection()
+	pipeline := bson.A{
+		bson.M{"$match": m.active(bson.D{})},
+		bson.M{"$group": bson.M{
+			"_id":   nil,
+			"count": bson.M{"$sum": 1},
+			"bytes": bson.M{"$sum": bson.M{"$add": bson.A{
+				bson.M{"$strLenBytes": "$title"},
+				bson.M{"$strLenBytes": "$body"},
+				bson.M{"$sum": "$files.size"},
+			}}},
+		}},
+	}
+	cursor, err := mdCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	stats := new(snippetStats)
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(stats); err != nil {
+			return nil, err
+		}
+	}
+	return stats, cursor.Err()
+}
@@ -0,0 +1,104 @@
+package md
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+// writeEventStream
+// Writes sub's events to w as Server-Sent Events, with a comment
+// line every heartbeat. Returns once the snippet is deleted,
+// the subscription ends or the client goes away.
+func writeEventStream(w *bufio.Writer, sub *Subscription, heartbeat time.Duration) error {
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	if _, err := fmt.Fprint(w, ": connected\n\n"); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case event := <-sub.Events():
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if event.Type == EventDeleted {
+				return nil
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		case <-sub.Done():
+			return nil
+		}
+	}
+}
+
+// writeWebSocket
+// Sends sub's events to conn as JSON text messages, pinging every
+// heartbeat. Clients missing two pongs are disconnected.
+// Returns once the snippet is deleted, the subscription ends
+// or the client goes away.
+func writeWebSocket(conn *websocket.Conn, sub *Subscription, heartbeat time.Duration) error {
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	// Reading is required to process pongs and the close handshake,
+	// client messages are otherwise ignored.
+	gone := make(chan struct{})
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	closeWith := func(code int, text string) error {
+		return conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(heartbeat))
+	}
+	for {
+		select {
+		case event := <-sub.Events():
+			conn.SetWriteDeadline(time.Now().Add(heartbeat))
+			if err := conn.WriteJSON(event); err != nil {
+				return err
+			}
+			if event.Type == EventDeleted {
+				return closeWith(websocket.CloseNormalClosure, "snippet deleted")
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(heartbeat)); err != nil {
+				return err
+			}
+		case <-sub.Done():
+			return closeWith(websocket.CloseGoingAway, "")
+		case <-gone:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package md
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/logging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// TrashIndexModels
+// Returns the deletedAt index of the markdown and revisions
+// collections, covering only deleted documents.
+// Applied to the configured database by migration 4.
+func TrashIndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bsonx.Doc{{Key: "deletedAt", Value: bsonx.Int32(1)}},
+			Options: options.Index().SetSparse(true),
+		},
+	}
+}
+
+// RestoreMarkdownSnippet
+// Restores a snippet and its revisions from the trash.
+// Returns ErrNotFound when the snippet is not in the trash,
+// or ErrInvalidKey when updateKey does not match.
+func (m *MDService) RestoreMarkdownSnippet(ctx context.Context, mdID string, updateKey string) (*MarkdownSnippet, error) {
+	filter := m.trashed(bson.D{{Key: "id", Value: mdID}})
+	if err := m.validateKey(ctx, filter, mdID, updateKey); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Write)
+	defer cancel()
+	ctx, end := startOperation(ctx, "restore")
+	defer end()
+
+	snippet := new(MarkdownSnippet)
+	restore := bson.D{{Key: "$unset", Value: bson.D{{Key: "deletedAt", Value: ""}}}}
+	opts := options.FindOneAndUpdate().
+		SetProjection(bson.M{"updateKey": 0}).
+		SetReturnDocument(options.After)
+
+	var event *Event
+	err := m.withTransaction(ctx, func(ctx context.Context) error {
+		if err := m.getMarkdownCollection().FindOneAndUpdate(ctx, filter, restore, opts).Decode(snippet); err != nil {
+			return err
+		}
+		if err := m.loadBody(ctx, snippet.BodyRef, &snippet.Body); err != nil {
+			return err
+		}
+		revisions := m.scoped(bson.D{{Key: "snippetId", Value: mdID}})
+		if _, err := m.revisions().UpdateMany(ctx, revisions, restore); err != nil {
+			return err
+		}
+		if err := m.audit(ctx, m.newAuditEntry(ctx, AuditRestore, mdID, nil, snippet)); err != nil {
+			return err
+		}
+		event = m.newEvent(EventRestored, mdID, snippet)
+		return m.publish(ctx, event)
+	})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, snippetError("restore", mdID, ErrNotFound)
+		}
+		recordMongoError(ctx, "restore", mdID, err)
+		return nil, err
+	}
+	m.live.publishLocal(event)
+
+	return snippet, nil
+}
+
+// ListTrash
+// Returns the snippets in the trash without their body,
+// most recently deleted first.
+func (m *MDService) ListTrash(ctx context.Context) ([]MDListItem, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Search)
+	defer cancel()
+	ctx, end := startOperation(ctx, "listTrash")
+	defer end()
+
+	snippets := make([]MDListItem, 0)
+	opts := options.Find().
+		SetProjection(bson.M{"id": 1, "title": 1, "createDate": 1, "deletedAt": 1}).
+		SetSort(bson.D{{Key: "deletedAt", Value: -1}})
+	cursor, err := m.getMarkdownCollection().Find(ctx, m.trashed(bson.D{}), opts)
+	if err != nil {
+		recordMongoError(ctx, "listTrash", "", err)
+		return nil, err
+	}
+	if err := cursor.All(ctx, &snippets); err != nil {
+		recordMongoError(ctx, "listTrash", "", err)
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// trashed
+// Restricts filter to the scoped tenant's snippets in the trash,
+// excluding those the Purger has started removing.
+func (m *MDService) trashed(filter bson.D) bson.D {
+	return append(m.scoped(filter),
+		bson.E{Key: "deletedAt", Value: bson.D{{Key: "$exists", Value: true}}},
+		bson.E{Key: "purging", Value: bson.D{{Key: "$exists", Value: false}}})
+}
+
+// Purger
+// Permanently removes snippets, their revisions and attachments,
+// that have been in the trash for longer than the retention period.
+type Purger struct {
+	mdService *MDService
+	cfg       config.Trash
+}
+
+// NewPurger Creates a Purger of every tenant's trash
+// Requires a reference to a MDService and the trash settings
+func NewPurger(mdService *MDService, cfg config.Trash) *Purger {
+	if cfg.PurgeInterval <= 0 {
+		cfg.PurgeInterval = time.Hour
+	}
+	return &Purger{mdService: mdService, cfg: cfg}
+}
+
+// Run
+// Purges expired snippets every purge interval until ctx is cancelled.
+// Returns immediately when the retention period is zero.
+func (p *Purger) Run(ctx context.Context) {
+	if p.cfg.Retention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(p.cfg.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		purged, err := p.Purge(ctx)
+		if err != nil {
+			logging.Logger.WithError(err).Warn("Failed to purge trash")
+		}
+		if purged > 0 {
+			logging.Logger.WithField("snippets", purged).Info("Purged trash")
+		}
+	}
+}
+
+// Purge
+// Removes every snippet deleted before the retention period, across
+// all tenants. Returns the number of snippets removed.
+func (p *Purger) Purge(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.mdService.timeouts.Export)
+	defer cancel()
+	ctx, end := startOperation(ctx, "purge")
+	defer end()
+
+	databases, err := p.databases(ctx)
+	if err != nil {
+		return 0, err
+	}
+	expired := bson.D{{Key: "deletedAt", Value: bson.D{{Key: "$lt", Value: time.Now().Add(-p.cfg.Retention)}}}}
+	var purged int64
+	for _, name := range databases {
+		db := p.mdService.client.Database(name)
+		count, err := p.purgeSnippets(ctx, db, expired)
+		purged += count
+		if err != nil {
+			return purged, err
+		}
+		if _, err := db.Collection(RevisionsCollection).DeleteMany(ctx, expired); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// purgeSnippets
+// Removes the snippets in db matching expired, their attachments and blobs.
+// Each snippet is marked purging first, so it can no longer be restored,
+// and removed last, so a failed purge is finished by the next one.
+// Returns the number of snippets removed.
+func (p *Purger) purgeSnippets(ctx context.Context, db *mongo.Database, expired bson.D) (int64, error) {
+	snippets := db.Collection(p.mdService.mongo.Collection)
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "id": 1, "tenantId": 1})
+	cursor, err := snippets.Find(ctx, expired, opts)
+	if err != nil {
+		return 0, err
+	}
+	var docs []struct {
+		ObjectID interface{} `bson:"_id"`
+		ID       string      `bson:"id"`
+		TenantID string      `bson:"tenantId"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, doc := range docs {
+		// A restore racing the purge either wins, leaving nothing
+		// to mark, or finds the snippet marked and nothing to restore.
+		filter := append(bson.D{{Key: "_id", Value: doc.ObjectID}}, expired...)
+		mark := bson.D{{Key: "$set", Value: bson.D{{Key: "purging", Value: true}}}}
+		result, err := snippets.UpdateOne(ctx, filter, mark)
+		if err != nil {
+			return purged, err
+		}
+		if result.MatchedCount == 0 {
+			continue
+		}
+		if err := deleteAttachments(ctx, db, doc.ID, doc.TenantID); err != nil {
+			return purged, err
+		}
+		if err := p.tenantService(db, doc.TenantID).deleteBlobs(ctx, doc.ID); err != nil {
+			return purged, err
+		}
+		if _, err := snippets.DeleteOne(ctx, bson.D{{Key: "_id", Value: doc.ObjectID}}); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// tenantService
+// Returns the MDService scoped to the tenant owning a snippet in db.
+func (p *Purger) tenantService(db *mongo.Database, tenantID string) *MDService {
+	m := p.mdService
+	switch m.tenancy.Mode {
+	case config.TenancyDatabase:
+		return m.ForTenant(strings.TrimPrefix(db.Name(), m.tenancy.DatabasePrefix))
+	case config.TenancyFilter:
+		return m.ForTenant(tenantID)
+	default:
+		return m.ForTenant("")
+	}
+}
+
+// databases
+// Returns the databases holding snippets, one per tenant with database tenancy.
+func (p *Purger) databases(ctx context.Context) ([]string, error) {
+	m := p.mdService
+	if m.tenancy.Mode != config.TenancyDatabase {
+		return []string{m.mongo.Database}, nil
+	}
+	prefix := "^" + regexp.QuoteMeta(m.tenancy.DatabasePrefix)
+	return m.client.ListDatabaseNames(ctx, bson.D{{Key: "name", Value: bson.D{{Key: "$regex", Value: prefix}}}})
+}
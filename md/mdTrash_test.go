@@ -0,0 +1,126 @@
+package md
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Test_Trash
+// Deleted snippets are hidden from reads and writes
+// until restored, and gone once purged.
+func Test_Trash(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := context.Background()
+
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Trash", Body: "# Trash"})
+	assert.Nil(t, err)
+	_, err = mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Keep", Body: "# Keep"})
+	assert.Nil(t, err)
+	assert.Nil(t, mdService.DeleteMarkdownSnippet(ctx, snippet.ID, snippet.UpdateKey))
+
+	_, err = mdService.GetMarkdownSnippet(ctx, snippet.ID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.True(t, errors.Is(mdService.ValidateIdAndKey(ctx, snippet.ID, snippet.UpdateKey), ErrNotFound))
+	_, err = mdService.ListRevisions(ctx, snippet.ID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	all, err := mdService.GetAllMarkdownSnippets(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, all, 1)
+	assert.True(t, errors.Is(mdService.DeleteMarkdownSnippet(ctx, snippet.ID, snippet.UpdateKey), ErrNotFound))
+
+	trash, err := mdService.ListTrash(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, trash, 1)
+	assert.Equal(t, snippet.ID, trash[0].ID)
+	assert.NotNil(t, trash[0].DeletedAt)
+
+	_, err = mdService.RestoreMarkdownSnippet(ctx, snippet.ID, "wrong")
+	assert.True(t, errors.Is(err, ErrInvalidKey))
+	restored, err := mdService.RestoreMarkdownSnippet(ctx, snippet.ID, snippet.UpdateKey)
+	assert.Nil(t, err)
+	assert.Nil(t, restored.DeletedAt)
+	assert.Empty(t, restored.UpdateKey)
+	revisions, err := mdService.ListRevisions(ctx, snippet.ID)
+	assert.Nil(t, err)
+	assert.Len(t, revisions, 1)
+
+	assert.Nil(t, mdService.DeleteMarkdownSnippet(ctx, snippet.ID, snippet.UpdateKey))
+	purged, err := NewPurger(mdService, config.Trash{Retention: time.Hour}).Purge(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), purged)
+	purged, err = NewPurger(mdService, config.Trash{Retention: time.Nanosecond}).Purge(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	_, err = mdService.RestoreMarkdownSnippet(ctx, snippet.ID, snippet.UpdateKey)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	trash, err = mdService.ListTrash(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, trash)
+}
+
+// Test_PurgeResumes
+// A snippet left marked by a failed purge cannot be
+// restored, and is removed by the next purge.
+func Test_PurgeResumes(t *testing.T) {
+	mdService, cleanup := SetupMDService(t)
+	defer cleanup(t)
+	ctx := context.Background()
+
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &CreateMDReq{Title: "Trash", Body: "# Trash"})
+	assert.Nil(t, err)
+	assert.Nil(t, mdService.DeleteMarkdownSnippet(ctx, snippet.ID, snippet.UpdateKey))
+	mark := bson.D{{Key: "$set", Value: bson.D{{Key: "purging", Value: true}}}}
+	_, err = mdService.getMarkdownCollection().UpdateOne(ctx, bson.D{{Key: "id", Value: snippet.ID}}, mark)
+	assert.Nil(t, err)
+
+	_, err = mdService.RestoreMarkdownSnippet(ctx, snippet.ID, snippet.UpdateKey)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	trash, err := mdService.ListTrash(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, trash)
+
+	purged, err := NewPurger(mdService, config.Trash{Retention: time.Nanosecond}).Purge(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), purged)
+}
+
+// Test_ListTrashHandlerValidation
+// Listing the trash of a multi-tenant service requires a configured tenant.
+func Test_ListTrashHandlerValidation(t *testing.T) {
+	mdService := SetupUnreachableMDService(t, config.Timeouts{})
+	mdService.tenancy = config.Tenant{Mode: config.TenancyDatabase, Names: []string{"acme"}}
+	app := fiber.New(fiber.Config{ErrorHandler: api.ErrorHandler})
+	InitMDHandlers(mdService).ConfigureAdminRoutes(app.Group("/admin"))
+
+	for _, path := range []string{"/admin/trash", "/admin/trash?tenant=globex"} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, path)
+	}
+}
+
+// Test_RestoreHandlerValidation
+// Restoring requires the update key.
+func Test_RestoreHandlerValidation(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: api.ErrorHandler})
+	InitMDHandlers(SetupUnreachableMDService(t, config.Timeouts{})).ConfigureRoutes(app)
+
+	req := httptest.NewRequest(http.MethodPost, "/md/id/restore", strings.NewReader(`{}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,62 @@
+package md
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SnippetCursor
+// Streams snippets one at a time from a Mongo cursor.
+type SnippetCursor struct {
+	ctx     context.Context
+	cursor  *mongo.Cursor
+	current *MarkdownSnippet
+	err     error
+	close   func()
+	// Reads bodies kept in the blob store.
+	mdService *MDService
+}
+
+// Next
+// Advances to the next snippet, returning false once
+// the cursor is exhausted or an error occurred.
+func (c *SnippetCursor) Next() bool {
+	if c.err != nil || !c.cursor.Next(c.ctx) {
+		return false
+	}
+	c.current = new(MarkdownSnippet)
+	if c.err = c.cursor.Decode(c.current); c.err != nil {
+		return false
+	}
+	if c.err = c.mdService.loadBody(c.ctx, c.current.BodyRef, &c.current.Body); c.err != nil {
+		return false
+	}
+	return true
+}
+
+// Snippet
+// Returns the snippet Next advanced to.
+func (c *SnippetCursor) Snippet() *MarkdownSnippet {
+	return c.current
+}
+
+// Err
+// Returns the error that stopped iteration, if any.
+func (c *SnippetCursor) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	if err := c.cursor.Err(); err != nil {
+		recordMongoError(c.ctx, "export", "", err)
+		return err
+	}
+	return nil
+}
+
+// Close
+// Releases the cursor and ends the export operation.
+func (c *SnippetCursor) Close() error {
+	defer c.close()
+	return c.cursor.Close(context.Background())
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+const namespace = "mdsnips"
+
+var (
+	// HTTPRequests counts completed requests by route, method and status.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Completed HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPDuration observes request latency by route, method and status.
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// MongoDuration observes Mongo latency per MDService operation.
+	MongoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_duration_seconds",
+		Help:      "Mongo operation latency per MDService method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"op"})
+
+	// MongoErrors counts failed Mongo operations per MDService operation.
+	MongoErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_errors_total",
+		Help:      "Failed Mongo operations per MDService method.",
+	}, []string{"op"})
+
+	// RateLimited counts requests rejected by the rate limiter.
+	RateLimited = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by the rate limiter.",
+	})
+)
+
+// Registry
+// Registry holding every mdsnips metric plus the Go and process collectors.
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		MongoDuration,
+		MongoErrors,
+		RateLimited,
+	)
+}
+
+// ObserveMongo
+// Starts timing a Mongo operation, call the returned func once it completes.
+//
+//	defer metrics.ObserveMongo("get")()
+func ObserveMongo(op string) func() {
+	start := time.Now()
+	return func() {
+		MongoDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler
+// Serves the Registry in Prometheus exposition format.
+func Handler() fiber.Handler {
+	handler := fasthttpadaptor.NewFastHTTPHandler(
+		promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}),
+	)
+	return func(ctx *fiber.Ctx) error {
+		handler(ctx.Context())
+		return nil
+	}
+}
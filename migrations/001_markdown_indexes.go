@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/md"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// markdownIndexes
+// Creates the markdown text, createDate and tenantId indexes.
+// The index specs are frozen as first released, later changes to
+// md.IndexModels belong to their own migrations, such as 7.
+var markdownIndexes = Migration{
+	Version:     1,
+	Description: "Create markdown collection indexes",
+	Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+		collection := db.Collection(cfg.Mongo.Collection)
+		models := baselineIndexModels(cfg.Tenant.Mode == config.TenancyFilter)
+		// Tenant databases indexed on first use, before tenant migrations,
+		// already hold the extended text index under the same name.
+		existing, err := md.IndexNames(ctx, collection)
+		if err != nil {
+			return err
+		}
+		if existing[md.TextIndexName] {
+			models = models[1:]
+		}
+		_, err = collection.Indexes().CreateMany(ctx, models)
+		return err
+	},
+}
+
+// baselineIndexModels
+// Returns the markdown collection indexes as created by migration 1,
+// with a text index over the title and body only.
+func baselineIndexModels(tenantFilter bool) []mongo.IndexModel {
+	index := []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{
+				{Key: "title", Value: bsonx.String("text")},
+				{Key: "body", Value: bsonx.String("text")},
+			},
+			Options: options.Index().SetName(md.TextIndexName),
+		},
+		{
+			Keys: bsonx.Doc{{Key: "createDate", Value: bsonx.Int32(1)}},
+		},
+	}
+	if tenantFilter {
+		index = append(index, mongo.IndexModel{
+			Keys: bsonx.Doc{
+				{Key: "tenantId", Value: bsonx.Int32(1)},
+				{Key: "createDate", Value: bsonx.Int32(1)},
+			},
+		})
+	}
+	return index
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/md"
+	"github.com/soulxburn/mdsnips/webhooks"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// webhookIndexes
+// Creates the outbox, webhook and delivery log indexes.
+// Dispatched events expire after 7 days, deliveries after 30.
+var webhookIndexes = Migration{
+	Version:     2,
+	Description: "Create outbox, webhook and delivery indexes",
+	Shared:      true,
+	Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+		indexes := map[string][]mongo.IndexModel{
+			md.OutboxCollection: {
+				{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "dispatchedAt", Value: 1}, {Key: "createDate", Value: 1}}},
+				{Keys: bson.D{{Key: "dispatchedAt", Value: 1}}, Options: options.Index().
+					SetName("dispatchedAt_ttl").
+					SetExpireAfterSeconds(7 * 24 * 60 * 60)},
+			},
+			webhooks.WebhooksCollection: {
+				{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "createDate", Value: 1}}},
+			},
+			webhooks.DeliveriesCollection: {
+				{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "dedupeKey", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+				{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+				{Keys: bson.D{{Key: "webhookId", Value: 1}, {Key: "createDate", Value: -1}}},
+				{Keys: bson.D{{Key: "createDate", Value: 1}}, Options: options.Index().
+					SetExpireAfterSeconds(30 * 24 * 60 * 60)},
+			},
+		}
+		for collection, models := range indexes {
+			if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"context"
+	"time"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/md"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// revisions
+// Creates the revision indexes and saves existing snippets
+// as their first revision.
+var revisions = Migration{
+	Version:     3,
+	Description: "Create revision indexes and backfill first revisions",
+	Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+		revisions := db.Collection(md.RevisionsCollection)
+		if _, err := revisions.Indexes().CreateMany(ctx, md.RevisionIndexModels()); err != nil {
+			return err
+		}
+
+		markdown := db.Collection(cfg.Mongo.Collection)
+		cursor, err := markdown.Find(ctx, bson.D{{Key: "revision", Value: bson.D{{Key: "$exists", Value: false}}}})
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+		for cursor.Next(ctx) {
+			snippet := new(md.MarkdownSnippet)
+			if err := cursor.Decode(snippet); err != nil {
+				return err
+			}
+			revision := &md.Revision{
+				SnippetID:  snippet.ID,
+				Revision:   1,
+				Title:      snippet.Title,
+				Body:       snippet.Body,
+				Source:     md.SourceCreate,
+				CreateDate: time.Now(),
+				TenantID:   snippet.TenantID,
+			}
+			filter := bson.D{{Key: "tenantId", Value: snippet.TenantID}, {Key: "snippetId", Value: snippet.ID}, {Key: "revision", Value: 1}}
+			if snippet.TenantID == "" {
+				filter[0].Value = nil
+			}
+			if _, err := revisions.ReplaceOne(ctx, filter, revision, options.Replace().SetUpsert(true)); err != nil {
+				return err
+			}
+			update := bson.D{{Key: "$set", Value: bson.D{{Key: "revision", Value: 1}}}}
+			if _, err := markdown.UpdateOne(ctx, bson.D{{Key: "_id", Value: cursor.Current.Lookup("_id")}}, update); err != nil {
+				return err
+			}
+		}
+		return cursor.Err()
+	},
+}
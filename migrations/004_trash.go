@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/md"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// trash
+// Creates the deletedAt indexes the trash purger queries.
+var trash = Migration{
+	Version:     4,
+	Description: "Create trash indexes",
+	Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+		for _, name := range []string{cfg.Mongo.Collection, md.RevisionsCollection} {
+			if _, err := db.Collection(name).Indexes().CreateMany(ctx, md.TrashIndexModels()); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
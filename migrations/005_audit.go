@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/md"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// audit
+// Creates the audit log query indexes.
+// Entry expiry follows MDSNIPS_AUDIT_RETENTION, applied on start.
+var audit = Migration{
+	Version:     5,
+	Description: "Create audit log indexes",
+	Shared:      true,
+	Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+		_, err := db.Collection(md.AuditCollection).Indexes().CreateMany(ctx, md.AuditIndexModels())
+		return err
+	},
+}
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/md"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// forks
+// Creates the forkedFrom index listing a snippet's forks.
+var forks = Migration{
+	Version:     6,
+	Description: "Create fork index",
+	Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+		_, err := db.Collection(cfg.Mongo.Collection).Indexes().CreateMany(ctx, md.ForkIndexModels())
+		return err
+	},
+}
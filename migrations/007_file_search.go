@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/md"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fileSearch
+// Rebuilds the markdown text index to cover file contents.
+var fileSearch = Migration{
+	Version:     7,
+	Description: "Extend the text index to snippet files",
+	Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+		return md.UpgradeTextIndex(ctx, db.Collection(cfg.Mongo.Collection))
+	},
+}
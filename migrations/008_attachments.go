@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/md"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// attachments
+// Creates the index keeping attachment names unique within a snippet.
+var attachments = Migration{
+	Version:     8,
+	Description: "Create attachment index",
+	Up: func(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+		_, err := db.Collection(md.AttachmentsBucket+".files").Indexes().CreateMany(ctx, md.AttachmentIndexModels())
+		return err
+	},
+}
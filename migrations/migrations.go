@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/soulxburn/mdsnips/config"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Migration
+// A versioned schema change applied once per database.
+type Migration struct {
+	// Strictly increasing version number.
+	Version int
+	// Short human readable summary.
+	Description string
+	// Applies to the configured database only, for collections
+	// shared by every tenant, and not to tenant databases.
+	Shared bool
+	// Applies the change. Must be safe to re-run if it fails part way.
+	Up func(ctx context.Context, db *mongo.Database, cfg *config.Config) error
+}
+
+// All
+// Every registered migration, in version order.
+// Tenant databases receive those that are not Shared.
+var All = []Migration{
+	markdownIndexes,
+	webhookIndexes,
+	revisions,
+	trash,
+	audit,
+	forks,
+	fileSearch,
+	attachments,
+}
@@ -0,0 +1,297 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/logging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Collection recording applied migrations.
+	migrationsCollection = "schema_migrations"
+	// Collection holding the migration lock document.
+	lockCollection = "schema_migrations_lock"
+	lockID         = "migrations"
+)
+
+// ErrLocked is returned when the migration lock could not be acquired in time.
+var ErrLocked = errors.New("migrations are locked by another process")
+
+// ErrLockLost is returned when the migration lock expired or was taken over mid-run.
+var ErrLockLost = errors.New("migration lock lost")
+
+// AppliedMigration
+// Record stored in `schema_migrations` for each applied migration.
+type AppliedMigration struct {
+	Version     int       `bson:"version"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+	DurationMs  int64     `bson:"durationMs"`
+}
+
+// Migrator
+// Applies pending migrations to the configured database.
+type Migrator struct {
+	db         *mongo.Database
+	cfg        *config.Config
+	migrations []Migration
+	// Identifies this process as the lock holder.
+	owner string
+	// Locks not renewed for this long are considered abandoned.
+	// Held locks are renewed every third of it.
+	lockTTL time.Duration
+}
+
+// NewMigrator Creates a Migrator for every registered migration.
+func NewMigrator(mClient *mongo.Client, cfg *config.Config) *Migrator {
+	return newMigrator(mClient.Database(cfg.Mongo.Database), cfg, All)
+}
+
+// NewTenantMigrator Creates a Migrator for the database of tenant,
+// with the migrations that are not Shared.
+func NewTenantMigrator(mClient *mongo.Client, cfg *config.Config, tenant string) *Migrator {
+	var migrations []Migration
+	for _, migration := range All {
+		if !migration.Shared {
+			migrations = append(migrations, migration)
+		}
+	}
+	return newMigrator(mClient.Database(cfg.Tenant.DatabasePrefix+tenant), cfg, migrations)
+}
+
+// NewMigrators
+// Returns the Migrator of the configured database, followed by
+// one for each configured tenant when tenants have their own database.
+func NewMigrators(mClient *mongo.Client, cfg *config.Config) []*Migrator {
+	migrators := []*Migrator{NewMigrator(mClient, cfg)}
+	if cfg.Tenant.Mode == config.TenancyDatabase {
+		for _, tenant := range cfg.Tenant.Names {
+			migrators = append(migrators, NewTenantMigrator(mClient, cfg, tenant))
+		}
+	}
+	return migrators
+}
+
+// Database Returns the name of the database migrated.
+func (m *Migrator) Database() string {
+	return m.db.Name()
+}
+
+// newMigrator Creates a Migrator for the provided migrations.
+func newMigrator(db *mongo.Database, cfg *config.Config, migrations []Migration) *Migrator {
+	hostname, _ := os.Hostname()
+	return &Migrator{
+		db:         db,
+		cfg:        cfg,
+		migrations: migrations,
+		owner:      hostname + "-" + uuid.NewString(),
+		lockTTL:    5 * time.Minute,
+	}
+}
+
+// Pending
+// Returns the migrations not yet applied, in version order.
+// This is the dry-run mode, nothing is modified.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	if err := validate(m.migrations); err != nil {
+		return nil, err
+	}
+
+	cursor, err := m.db.Collection(migrationsCollection).Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	var applied []AppliedMigration
+	if err := cursor.All(ctx, &applied); err != nil {
+		return nil, err
+	}
+
+	done := make(map[int]bool)
+	for _, a := range applied {
+		done[a.Version] = true
+	}
+	pending := make([]Migration, 0)
+	for _, migration := range m.migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up
+// Applies pending migrations in order while holding the migration lock,
+// waiting for the lock until ctx ends. Returns the applied migrations.
+// Stops at the first failure, leaving later migrations pending, and
+// with ErrLockLost when the lock could not be renewed.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.acquireLock(ctx); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	lost := make(chan error, 1)
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		m.renewLock(ctx, cancel, lost)
+	}()
+	defer func() {
+		cancel()
+		<-renewed
+		m.releaseLock()
+	}()
+	// lockLost returns the reason the lock was lost in place of
+	// the cancellation it caused, or err when it is still held.
+	lockLost := func(err error) error {
+		select {
+		case lostErr := <-lost:
+			return lostErr
+		default:
+			return err
+		}
+	}
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, lockLost(err)
+	}
+
+	applied := make([]Migration, 0, len(pending))
+	for _, migration := range pending {
+		if err := ctx.Err(); err != nil {
+			return applied, lockLost(err)
+		}
+		log := logging.Logger.WithField("version", migration.Version).WithField("description", migration.Description)
+		start := time.Now()
+		if err := migration.Up(ctx, m.db, m.cfg); err != nil {
+			err = lockLost(err)
+			log.WithError(err).Error("Migration failed")
+			return applied, fmt.Errorf("migration %d: %w", migration.Version, err)
+		}
+
+		record := AppliedMigration{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now(),
+			DurationMs:  time.Since(start).Milliseconds(),
+		}
+		if _, err := m.db.Collection(migrationsCollection).InsertOne(ctx, record); err != nil {
+			return applied, fmt.Errorf("migration %d: recording: %w", migration.Version, lockLost(err))
+		}
+		log.WithField("durationMs", record.DurationMs).Info("Migration applied")
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// acquireLock
+// Takes the migration lock, retrying until ctx ends.
+// Expired locks left by crashed processes are taken over.
+func (m *Migrator) acquireLock(ctx context.Context) error {
+	locks := m.db.Collection(lockCollection)
+	for {
+		now := time.Now()
+		filter := bson.M{
+			"_id": lockID,
+			"$or": bson.A{
+				bson.M{"owner": m.owner},
+				bson.M{"expiresAt": bson.M{"$lt": now}},
+			},
+		}
+		update := bson.M{"$set": bson.M{"owner": m.owner, "expiresAt": now.Add(m.lockTTL)}}
+		_, err := locks.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+
+		logging.Logger.Info("Waiting for migration lock")
+		select {
+		case <-ctx.Done():
+			return ErrLocked
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// renewLock
+// Extends the migration lock every third of its TTL until ctx ends.
+// When another process has taken the lock, or it could not be renewed
+// before expiring, sends ErrLockLost on lost and cancels the run.
+func (m *Migrator) renewLock(ctx context.Context, cancel context.CancelFunc, lost chan<- error) {
+	interval := m.lockTTL / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	expiresAt := time.Now().Add(m.lockTTL)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		filter := bson.M{"_id": lockID, "owner": m.owner}
+		update := bson.M{"$set": bson.M{"expiresAt": now.Add(m.lockTTL)}}
+		result, err := m.db.Collection(lockCollection).UpdateOne(ctx, filter, update)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err == nil && result.MatchedCount == 1:
+			expiresAt = now.Add(m.lockTTL)
+			continue
+		case err == nil:
+			err = fmt.Errorf("%w: taken over by another process", ErrLockLost)
+		case now.Add(interval).Before(expiresAt):
+			logging.Logger.WithError(err).Warn("Failed to renew migration lock")
+			continue
+		default:
+			err = fmt.Errorf("%w: %s", ErrLockLost, err)
+		}
+		lost <- err
+		cancel()
+		return
+	}
+}
+
+// releaseLock
+// Releases the migration lock when held by this Migrator.
+func (m *Migrator) releaseLock() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	filter := bson.M{"_id": lockID, "owner": m.owner}
+	if _, err := m.db.Collection(lockCollection).DeleteOne(ctx, filter); err != nil {
+		logging.Logger.WithError(err).Error("Failed to release migration lock")
+	}
+}
+
+// validate
+// Ensures migration versions are positive, unique and in order.
+func validate(migrations []Migration) error {
+	if !sort.SliceIsSorted(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	}) {
+		return errors.New("migrations are not in version order")
+	}
+	for i, migration := range migrations {
+		if migration.Version < 1 {
+			return fmt.Errorf("migration version %d must be positive", migration.Version)
+		}
+		if i > 0 && migrations[i-1].Version == migration.Version {
+			return fmt.Errorf("duplicate migration version %d", migration.Version)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soulxburn/mdsnips/client"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/md"
+	"github.com/soulxburn/mdsnips/testutils"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// SetupMigrationDB
+// Creates a Mongo Test Container and returns a database on it,
+// and a cleanup function for tearing down the container.
+func SetupMigrationDB(t *testing.T) (*mongo.Database, func()) {
+	testutils.SkipContainers(t)
+	mCont, err := testutils.SetupMongoTestContainer()
+	if err != nil {
+		t.Fatalf("Failed to initialize mongo container: %s", err)
+	}
+	mClient, err := client.InitMongoClient(mCont.ConnectionString)
+	if err != nil {
+		t.Fatalf("Failed to connection to mongo container: %s", err)
+	}
+	return mClient.Database("mdsnips"), func() {
+		mCont.Container.Terminate(context.Background())
+	}
+}
+
+// Test_Validate
+// Migrations must be ordered with unique positive versions.
+func Test_Validate(t *testing.T) {
+	noop := func(context.Context, *mongo.Database, *config.Config) error { return nil }
+	assert.Nil(t, validate(All))
+	assert.Nil(t, validate([]Migration{{Version: 1, Up: noop}, {Version: 3, Up: noop}}))
+	assert.NotNil(t, validate([]Migration{{Version: 2, Up: noop}, {Version: 1, Up: noop}}))
+	assert.NotNil(t, validate([]Migration{{Version: 1, Up: noop}, {Version: 1, Up: noop}}))
+	assert.NotNil(t, validate([]Migration{{Version: 0, Up: noop}}))
+}
+
+// Test_Up
+// Pending migrations should be applied once, in order,
+// and stop at the first failure.
+func Test_Up(t *testing.T) {
+	db, cleanup := SetupMigrationDB(t)
+	defer cleanup()
+	cfg := &config.Config{Mongo: config.Mongo{Database: "mdsnips", Collection: "markdown"}}
+
+	var order []int
+	record := func(version int, err error) Migration {
+		return Migration{Version: version, Up: func(context.Context, *mongo.Database, *config.Config) error {
+			order = append(order, version)
+			return err
+		}}
+	}
+	migrator := newMigrator(db, cfg, []Migration{record(1, nil), record(2, nil), record(3, errors.New("boom"))})
+
+	pending, err := migrator.Pending(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, pending, 3)
+
+	applied, err := migrator.Up(context.Background())
+	assert.NotNil(t, err)
+	assert.Len(t, applied, 2)
+	assert.Equal(t, []int{1, 2, 3}, order)
+
+	pending, err = migrator.Pending(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, pending, 1)
+	assert.Equal(t, 3, pending[0].Version)
+}
+
+// Test_UpLocked
+// A held lock should block other migrators until it expires.
+func Test_UpLocked(t *testing.T) {
+	db, cleanup := SetupMigrationDB(t)
+	defer cleanup()
+	cfg := &config.Config{Mongo: config.Mongo{Database: "mdsnips", Collection: "markdown"}}
+
+	holder := newMigrator(db, cfg, All)
+	assert.Nil(t, holder.acquireLock(context.Background()))
+
+	waiter := newMigrator(db, cfg, All)
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	_, err := waiter.Up(ctx)
+	assert.Equal(t, ErrLocked, err)
+
+	holder.releaseLock()
+	applied, err := waiter.Up(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, applied, len(All))
+}
+
+// Test_UpLockLost
+// The lock is renewed while migrations run,
+// and losing it stops the run with ErrLockLost.
+func Test_UpLockLost(t *testing.T) {
+	db, cleanup := SetupMigrationDB(t)
+	defer cleanup()
+	cfg := &config.Config{Mongo: config.Mongo{Database: "mdsnips", Collection: "markdown"}}
+
+	var thief *Migrator
+	slow := Migration{Version: 1, Up: func(ctx context.Context, _ *mongo.Database, _ *config.Config) error {
+		// Outlives the TTL, so only renewal keeps the lock.
+		time.Sleep(900 * time.Millisecond)
+		waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		if err := thief.acquireLock(waitCtx); err != ErrLocked {
+			return errors.New("lock was not renewed")
+		}
+		return nil
+	}}
+	steal := Migration{Version: 2, Up: func(ctx context.Context, _ *mongo.Database, _ *config.Config) error {
+		filter := bson.M{"_id": lockID}
+		if _, err := db.Collection(lockCollection).UpdateOne(ctx, filter, bson.M{"$set": bson.M{"owner": thief.owner}}); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	migrator := newMigrator(db, cfg, []Migration{slow, steal})
+	migrator.lockTTL = 300 * time.Millisecond
+	thief = newMigrator(db, cfg, nil)
+
+	applied, err := migrator.Up(context.Background())
+	assert.True(t, errors.Is(err, ErrLockLost), "%v", err)
+	assert.Len(t, applied, 1)
+}
+
+// Test_UpFromBaseline
+// Migrations 1 through 7 apply over the text index created before
+// migrations existed, and extend it to snippet files.
+func Test_UpFromBaseline(t *testing.T) {
+	db, cleanup := SetupMigrationDB(t)
+	defer cleanup()
+	cfg := &config.Config{Mongo: config.Mongo{Database: "mdsnips", Collection: "markdown"}}
+	ctx := context.Background()
+
+	baseline := []mongo.IndexModel{
+		{Keys: bsonx.Doc{{Key: "title", Value: bsonx.String("text")}, {Key: "body", Value: bsonx.String("text")}}},
+		{Keys: bsonx.Doc{{Key: "createDate", Value: bsonx.Int32(1)}}},
+	}
+	_, err := db.Collection(cfg.Mongo.Collection).Indexes().CreateMany(ctx, baseline)
+	assert.Nil(t, err)
+
+	applied, err := newMigrator(db, cfg, All[:7]).Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, 7)
+
+	cursor, err := db.Collection(cfg.Mongo.Collection).Indexes().List(ctx)
+	assert.Nil(t, err)
+	var indexes []struct {
+		Name    string           `bson:"name"`
+		Weights map[string]int32 `bson:"weights"`
+	}
+	assert.Nil(t, cursor.All(ctx, &indexes))
+	found := false
+	for _, index := range indexes {
+		if index.Name == md.TextIndexName {
+			found = true
+			assert.Contains(t, index.Weights, "files.content")
+		}
+	}
+	assert.True(t, found)
+}
+
+// Test_NewMigrators
+// Tenant databases get their own migrator, without the shared migrations.
+func Test_NewMigrators(t *testing.T) {
+	mClient, err := mongo.NewClient()
+	assert.Nil(t, err)
+	cfg := &config.Config{
+		Mongo:  config.Mongo{Database: "mdsnips", Collection: "markdown"},
+		Tenant: config.Tenant{Mode: config.TenancyDatabase, DatabasePrefix: "mdsnips_", Names: []string{"acme", "globex"}},
+	}
+
+	migrators := NewMigrators(mClient, cfg)
+	assert.Len(t, migrators, 3)
+	assert.Equal(t, "mdsnips", migrators[0].Database())
+	assert.Equal(t, All, migrators[0].migrations)
+	assert.Equal(t, "mdsnips_acme", migrators[1].Database())
+	assert.Equal(t, "mdsnips_globex", migrators[2].Database())
+	for _, migration := range migrators[1].migrations {
+		assert.False(t, migration.Shared, migration.Description)
+	}
+	assert.Len(t, migrators[1].migrations, len(All)-2)
+
+	cfg.Tenant.Mode = config.TenancyFilter
+	assert.Len(t, NewMigrators(mClient, cfg), 1)
+}
+
+// Test_UpTenantDatabase
+// Tenant databases indexed on first use, before they were
+// migrated, keep their extended text index.
+func Test_UpTenantDatabase(t *testing.T) {
+	db, cleanup := SetupMigrationDB(t)
+	defer cleanup()
+	cfg := &config.Config{
+		Mongo:  config.Mongo{Database: "mdsnips", Collection: "markdown"},
+		Tenant: config.Tenant{Mode: config.TenancyDatabase, DatabasePrefix: "mdsnips_", Names: []string{"acme"}},
+	}
+	ctx := context.Background()
+	tenantDB := db.Client().Database("mdsnips_acme")
+	_, err := tenantDB.Collection(cfg.Mongo.Collection).Indexes().CreateMany(ctx, md.IndexModels(false))
+	assert.Nil(t, err)
+
+	applied, err := NewTenantMigrator(db.Client(), cfg, "acme").Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, len(All)-2)
+	assert.Nil(t, md.CheckIndexes(ctx, db.Client(), cfg))
+}
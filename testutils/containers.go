@@ -6,11 +6,22 @@ import (
 	"os"
 	"path"
 	"runtime"
+	"testing"
 
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
+// SkipContainers
+// Skips t when container tests are opted out of with `go test -short`
+// or MDSNIPS_SKIP_CONTAINER_TESTS, otherwise a container that
+// fails to start should fail the test.
+func SkipContainers(t testing.TB) {
+	if testing.Short() || os.Getenv("MDSNIPS_SKIP_CONTAINER_TESTS") != "" {
+		t.Skip("Container tests disabled")
+	}
+}
+
 // MongoTestContainer
 // Container - Reference to GenericContainer Object
 // ConnectionString - ConnectionString to be passed to mongo client
@@ -60,3 +71,53 @@ func SetupMongoTestContainer() (*MongoTestContainer, error) {
 	connString := fmt.Sprintf("mongodb://%s:%s", ip, port)
 	return &MongoTestContainer{Container: mCont, ConnectionString: connString}, nil
 }
+
+// MinioTestContainer
+// Container - Reference to GenericContainer Object
+// Endpoint - host:port of the S3 API
+// AccessKey, SecretKey - Root credentials of the server
+// NOTE: Terminate the Container reference when done.
+type MinioTestContainer struct {
+	Container testcontainers.Container
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+}
+
+// SetupMinioTestContainer Creates a MinIO test container
+// for testing integrations with S3-compatible storage.
+func SetupMinioTestContainer() (*MinioTestContainer, error) {
+	ctx := context.Background()
+	accessKey, secretKey := "mdsnips", "mdsnips-secret"
+	req := testcontainers.ContainerRequest{
+		Image:        "minio/minio",
+		ExposedPorts: []string{"9000"},
+		Cmd:          []string{"server", "/data"},
+		Env: map[string]string{
+			"MINIO_ROOT_USER":     accessKey,
+			"MINIO_ROOT_PASSWORD": secretKey,
+		},
+		WaitingFor: wait.ForHTTP("/minio/health/ready").WithPort("9000"),
+	}
+
+	mCont, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ip, err := mCont.Host(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := mCont.MappedPort(ctx, "9000")
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s:%s", ip, port.Port())
+	return &MinioTestContainer{Container: mCont, Endpoint: endpoint, AccessKey: accessKey, SecretKey: secretKey}, nil
+}
@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+)
+
+// Test_FileExporter
+// Spans should be written to the configured file on shutdown.
+func Test_FileExporter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Init(context.Background(), config.Tracing{
+		Exporter:    ExporterFile,
+		File:        file,
+		SampleRatio: 1,
+		ServiceName: "mdsnips-test",
+	})
+	assert.Nil(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+	span.End()
+	assert.Nil(t, shutdown(context.Background()))
+
+	contents, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	assert.Contains(t, string(contents), `"Name":"test-span"`)
+	assert.Contains(t, string(contents), "mdsnips-test")
+}
+
+// Test_UnknownExporter
+// Unknown exporters should be rejected.
+func Test_UnknownExporter(t *testing.T) {
+	_, err := Init(context.Background(), config.Tracing{Exporter: "jaeger"})
+	assert.NotNil(t, err)
+}
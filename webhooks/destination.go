@@ -0,0 +1,100 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenDestination Returned when a delivery would
+// connect to a loopback, private or otherwise internal address.
+var ErrForbiddenDestination = errors.New("webhook destination address is not allowed")
+
+// blockedNetworks Address ranges deliveries may not reach
+// unless allowed by configuration: unspecified, private, shared,
+// loopback, link-local, benchmarking, multicast and reserved.
+var blockedNetworks = parseNetworks([]string{
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8",
+	"169.254.0.0/16", "172.16.0.0/12", "192.0.0.0/24", "192.168.0.0/16",
+	"198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8",
+})
+
+// destinationGuard
+// Checks the address each delivery connection is made to,
+// after name resolution, so DNS cannot point a webhook
+// at an internal service.
+type destinationGuard struct {
+	allowed []*net.IPNet
+}
+
+// newDeliveryClient
+// Returns the HTTP client sending deliveries, refusing connections
+// to blocked addresses outside the allowed CIDR ranges. Deliveries
+// are never sent through a proxy, whose address the guard would check
+// in place of the webhook's.
+func newDeliveryClient(timeout time.Duration, allowed []string) *http.Client {
+	guard := &destinationGuard{allowed: parseNetworks(allowed)}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   guard.control,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
+
+// control
+// net.Dialer Control hook rejecting connections to blocked addresses.
+func (g *destinationGuard) control(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !g.permitted(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, host)
+	}
+	return nil
+}
+
+// permitted
+// Reports whether ip is outside the blocked ranges, or allowed.
+func (g *destinationGuard) permitted(ip net.IP) bool {
+	for _, network := range g.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseNetworks
+// Parses CIDR ranges, skipping invalid ones,
+// which config validation reports at startup.
+func parseNetworks(cidrs []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
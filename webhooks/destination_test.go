@@ -0,0 +1,52 @@
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_DestinationGuard
+// Internal addresses are refused unless their range is allowed.
+func Test_DestinationGuard(t *testing.T) {
+	guard := &destinationGuard{allowed: parseNetworks([]string{"10.1.0.0/16"})}
+	for ip, permitted := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"10.1.2.3":         true,
+		"10.2.0.1":         false,
+		"127.0.0.1":        false,
+		"169.254.169.254":  false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"::ffff:127.0.0.1": false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+	} {
+		assert.Equal(t, permitted, guard.permitted(net.ParseIP(ip)), ip)
+	}
+}
+
+// Test_DeliveryClient
+// Deliveries to loopback fail at dial time unless loopback is allowed.
+func Test_DeliveryClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	_, err := newDeliveryClient(time.Second, nil).Post(server.URL, "application/json", nil)
+	assert.True(t, errors.Is(err, ErrForbiddenDestination))
+
+	resp, err := newDeliveryClient(time.Second, []string{"127.0.0.0/8"}).Post(server.URL, "application/json", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+}
@@ -0,0 +1,328 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/logging"
+	"github.com/soulxburn/mdsnips/md"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// workers Number of deliveries attempted concurrently.
+const workers = 4
+
+// Dispatcher
+// Relays outbox events into webhook deliveries and sends
+// due deliveries, retrying failures with exponential backoff.
+// Several dispatchers may run against the same database.
+type Dispatcher struct {
+	db     *mongo.Database
+	cfg    config.Webhooks
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewDispatcher
+// Returns a Dispatcher for the configured database,
+// filling unset settings with their defaults.
+func NewDispatcher(mClient *mongo.Client, cfg *config.Config) *Dispatcher {
+	database := cfg.Mongo.Database
+	if database == "" {
+		database = "mdsnips"
+	}
+	settings := cfg.Webhooks
+	if settings.PollInterval <= 0 {
+		settings.PollInterval = time.Second
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = 10 * time.Second
+	}
+	if settings.MaxAttempts <= 0 {
+		settings.MaxAttempts = 8
+	}
+	if settings.RetryInitial <= 0 {
+		settings.RetryInitial = 30 * time.Second
+	}
+	if settings.RetryMax <= 0 {
+		settings.RetryMax = time.Hour
+	}
+	return &Dispatcher{
+		db:     mClient.Database(database),
+		cfg:    settings,
+		client: newDeliveryClient(settings.Timeout, settings.AllowedNetworks),
+		now:    time.Now,
+	}
+}
+
+// Run
+// Polls the outbox and pending deliveries until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := d.Relay(ctx); err != nil && ctx.Err() == nil {
+			logging.Logger.WithError(err).Error("Failed to relay outbox events")
+		}
+		if err := d.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			logging.Logger.WithError(err).Error("Failed to deliver webhooks")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay
+// Creates a delivery for every webhook subscribed to each pending
+// outbox event, then marks the event dispatched. Relaying an event
+// twice, e.g. after a crash, creates its deliveries once.
+func (d *Dispatcher) Relay(ctx context.Context) error {
+	outbox := d.db.Collection(md.OutboxCollection)
+	opts := options.Find().SetSort(bson.D{{Key: "createDate", Value: 1}}).SetLimit(100)
+	for {
+		cursor, err := outbox.Find(ctx, bson.M{"dispatchedAt": bson.M{"$exists": false}}, opts)
+		if err != nil {
+			return err
+		}
+		var events []*md.Event
+		if err := cursor.All(ctx, &events); err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		for _, event := range events {
+			if err := d.relay(ctx, event); err != nil {
+				return err
+			}
+			update := bson.M{"$set": bson.M{"dispatchedAt": d.now()}}
+			if _, err := outbox.UpdateOne(ctx, bson.M{"id": event.ID}, update); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// relay
+// Creates the deliveries of event.
+func (d *Dispatcher) relay(ctx context.Context, event *md.Event) error {
+	cursor, err := d.db.Collection(WebhooksCollection).Find(ctx, bson.M{"tenant": event.Tenant})
+	if err != nil {
+		return err
+	}
+	var webhooks []*Webhook
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := d.now()
+	var deliveries []interface{}
+	for _, webhook := range webhooks {
+		if !webhook.subscribes(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, &Delivery{
+			ID:            uuid.NewString(),
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        StatusPending,
+			Attempts:      []Attempt{},
+			NextAttemptAt: &now,
+			CreateDate:    now,
+			Tenant:        event.Tenant,
+			DedupeKey:     event.ID + ":" + webhook.ID,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	_, err = d.db.Collection(DeliveriesCollection).InsertMany(ctx, deliveries, options.InsertMany().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(writeErr) {
+				return err
+			}
+		}
+		return nil
+	}
+	return err
+}
+
+// DeliverDue
+// Sends every pending delivery whose next attempt is due.
+func (d *Dispatcher) DeliverDue(ctx context.Context) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				delivery, err := d.claim(ctx)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if delivery == nil {
+					return
+				}
+				if err := d.deliver(ctx, delivery); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// claim
+// Locks the next due delivery for this dispatcher,
+// returning nil when none is due.
+func (d *Dispatcher) claim(ctx context.Context) (*Delivery, error) {
+	now := d.now()
+	filter := bson.M{
+		"status":        StatusPending,
+		"nextAttemptAt": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"lockedUntil": bson.M{"$exists": false}},
+			bson.M{"lockedUntil": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"lockedUntil": now.Add(2 * d.cfg.Timeout)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	delivery := new(Delivery)
+	err := d.db.Collection(DeliveriesCollection).FindOneAndUpdate(ctx, filter, update, opts).Decode(delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return delivery, err
+}
+
+// deliver
+// Makes one attempt at delivery and records its outcome.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *Delivery) error {
+	webhook := new(Webhook)
+	err := d.db.Collection(WebhooksCollection).FindOne(ctx, bson.M{"id": delivery.WebhookID}).Decode(webhook)
+	var attempt Attempt
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		attempt = Attempt{Date: d.now(), Error: "webhook deleted"}
+		delivery.Attempts = append(delivery.Attempts, attempt)
+		return d.finish(ctx, delivery, StatusFailed, nil)
+	case err != nil:
+		return err
+	}
+
+	attempt = d.send(ctx, webhook, delivery)
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	log := logging.Logger.WithField("deliveryId", delivery.ID).
+		WithField("webhookId", webhook.ID).
+		WithField("attempt", len(delivery.Attempts))
+
+	if attempt.Error == "" {
+		log.Debug("Webhook delivered")
+		return d.finish(ctx, delivery, StatusSucceeded, nil)
+	}
+	if len(delivery.Attempts) >= d.cfg.MaxAttempts {
+		log.WithField("error", attempt.Error).Warn("Webhook delivery failed, giving up")
+		return d.finish(ctx, delivery, StatusFailed, nil)
+	}
+	next := d.now().Add(d.backoff(len(delivery.Attempts)))
+	log.WithField("error", attempt.Error).WithField("nextAttemptAt", next).Info("Webhook delivery failed, retrying")
+	return d.finish(ctx, delivery, StatusPending, &next)
+}
+
+// send
+// POSTs the signed delivery payload to the webhook URL.
+func (d *Dispatcher) send(ctx context.Context, webhook *Webhook, delivery *Delivery) Attempt {
+	start := d.now()
+	attempt := Attempt{Date: start}
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "mdsnips-webhooks")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, start, body))
+
+	resp, err := d.client.Do(req)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// finish
+// Records the attempts made, the resulting status and the
+// next attempt date, releasing the claim on the delivery.
+func (d *Dispatcher) finish(ctx context.Context, delivery *Delivery, status string, next *time.Time) error {
+	set := bson.M{"status": status, "attempts": delivery.Attempts}
+	unset := bson.M{"lockedUntil": ""}
+	if next != nil {
+		set["nextAttemptAt"] = *next
+	} else {
+		unset["nextAttemptAt"] = ""
+	}
+	update := bson.M{"$set": set, "$unset": unset}
+	_, err := d.db.Collection(DeliveriesCollection).UpdateOne(ctx, bson.M{"id": delivery.ID}, update)
+	return err
+}
+
+// backoff
+// Returns the wait after attempt, doubling from
+// RetryInitial up to RetryMax.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.cfg.RetryInitial
+	for i := 1; i < attempt && wait < d.cfg.RetryMax; i++ {
+		wait *= 2
+	}
+	if wait > d.cfg.RetryMax {
+		wait = d.cfg.RetryMax
+	}
+	return wait
+}
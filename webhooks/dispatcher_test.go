@@ -0,0 +1,130 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulxburn/mdsnips/client"
+	"github.com/soulxburn/mdsnips/config"
+	"github.com/soulxburn/mdsnips/md"
+	"github.com/soulxburn/mdsnips/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// SetupDispatcher
+// Creates a Mongo Test Container and returns a webhooks Service,
+// MDService and Dispatcher on it, and a cleanup function.
+func SetupDispatcher(t *testing.T) (*Service, *md.MDService, *Dispatcher, func()) {
+	testutils.SkipContainers(t)
+	mCont, err := testutils.SetupMongoTestContainer()
+	if err != nil {
+		t.Fatalf("Failed to initialize mongo container: %s", err)
+	}
+	mClient, err := client.InitMongoClient(mCont.ConnectionString)
+	if err != nil {
+		t.Fatalf("Failed to connection to mongo container: %s", err)
+	}
+	// Test receivers listen on loopback.
+	cfg := &config.Config{Webhooks: config.Webhooks{MaxAttempts: 2, RetryInitial: time.Millisecond,
+		AllowedNetworks: []string{"127.0.0.0/8", "::1/128"}}}
+	return InitService(mClient, cfg), md.InitMDService(mClient, cfg), NewDispatcher(mClient, cfg), func() {
+		mCont.Container.Terminate(context.Background())
+	}
+}
+
+// receiver
+// Records signed deliveries, answering with status.
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := ioutil.ReadAll(req.Body)
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	w.WriteHeader(r.status)
+}
+
+// Test_DeliverSigned
+// Snippet writes are delivered to subscribed webhooks, signed.
+func Test_DeliverSigned(t *testing.T) {
+	service, mdService, dispatcher, cleanup := SetupDispatcher(t)
+	defer cleanup()
+	ctx := context.Background()
+	recv := &receiver{status: http.StatusOK}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	webhook, err := service.CreateWebhook(ctx, &CreateWebhookReq{URL: server.URL, Events: []string{md.EventCreated}})
+	assert.Nil(t, err)
+	snippet, err := mdService.CreateMarkdownSnippet(ctx, &md.CreateMDReq{Title: "Hook", Body: "# Hook"})
+	assert.Nil(t, err)
+	assert.Nil(t, mdService.DeleteMarkdownSnippet(ctx, snippet.ID, snippet.UpdateKey))
+
+	assert.Nil(t, dispatcher.Relay(ctx))
+	assert.Nil(t, dispatcher.Relay(ctx))
+	assert.Nil(t, dispatcher.DeliverDue(ctx))
+
+	assert.Len(t, recv.requests, 1)
+	req := recv.requests[0]
+	assert.Equal(t, md.EventCreated, req.Header.Get(EventHeader))
+	assert.Nil(t, Verify(webhook.Secret, req.Header.Get(SignatureHeader), recv.bodies[0], time.Minute))
+	event := new(md.Event)
+	assert.Nil(t, json.Unmarshal(recv.bodies[0], event))
+	assert.Equal(t, snippet.ID, event.SnippetID)
+	assert.Empty(t, event.Snippet.UpdateKey)
+
+	deliveries, err := service.ListDeliveries(ctx, webhook.ID, 10)
+	assert.Nil(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, StatusSucceeded, deliveries[0].Status)
+	assert.Equal(t, http.StatusOK, deliveries[0].Attempts[0].StatusCode)
+}
+
+// Test_RetryAndRedeliver
+// Failed deliveries retry up to MaxAttempts and can be redelivered.
+func Test_RetryAndRedeliver(t *testing.T) {
+	service, mdService, dispatcher, cleanup := SetupDispatcher(t)
+	defer cleanup()
+	ctx := context.Background()
+	recv := &receiver{status: http.StatusInternalServerError}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	webhook, err := service.CreateWebhook(ctx, &CreateWebhookReq{URL: server.URL})
+	assert.Nil(t, err)
+	_, err = mdService.CreateMarkdownSnippet(ctx, &md.CreateMDReq{Title: "Retry", Body: "# Retry"})
+	assert.Nil(t, err)
+
+	assert.Nil(t, dispatcher.Relay(ctx))
+	assert.Nil(t, dispatcher.DeliverDue(ctx))
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, dispatcher.DeliverDue(ctx))
+
+	deliveries, err := service.ListDeliveries(ctx, webhook.ID, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, StatusFailed, deliveries[0].Status)
+	assert.Len(t, deliveries[0].Attempts, 2)
+
+	recv.status = http.StatusNoContent
+	redelivery, err := service.Redeliver(ctx, deliveries[0].ID)
+	assert.Nil(t, err)
+	assert.Nil(t, dispatcher.DeliverDue(ctx))
+	assert.Len(t, recv.requests, 3)
+
+	deliveries, err = service.ListDeliveries(ctx, webhook.ID, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, redelivery.ID, deliveries[0].ID)
+	assert.Equal(t, StatusSucceeded, deliveries[0].Status)
+}
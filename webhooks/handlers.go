@@ -0,0 +1,169 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/soulxburn/mdsnips/api"
+	"github.com/soulxburn/mdsnips/logging"
+)
+
+type Handlers struct {
+	service *Service
+}
+
+// InitHandlers Creates an instance of Handlers
+// Requires a reference to a webhooks.Service instance
+func InitHandlers(service *Service) *Handlers {
+	return &Handlers{service: service}
+}
+
+// scoped
+// Returns the Service scoped to the request tenant.
+func (h *Handlers) scoped(ctx *fiber.Ctx) *Service {
+	return h.service.ForTenant(api.Tenant(ctx))
+}
+
+// ConfigureRoutes
+// Attaches the webhook subscription and delivery log routes.
+func (h *Handlers) ConfigureRoutes(app *fiber.App) {
+	app.Post("/webhooks", h.CreateWebhookHandler)
+	app.Get("/webhooks", h.ListWebhooksHandler)
+	app.Post("/webhooks/deliveries/:id/redeliver", h.RedeliverHandler)
+	app.Get("/webhooks/:id", h.GetWebhookHandler)
+	app.Delete("/webhooks/:id", h.DeleteWebhookHandler)
+	app.Get("/webhooks/:id/deliveries", h.ListDeliveriesHandler)
+}
+
+// CreateWebhookHandler POST - Subscribes a URL to snippet events
+// @Summary Create a webhook
+// @Description Deliveries are POSTed as JSON events, signed in the `X-MDSnips-Signature` header
+// @Description as `t=<unix>,v1=<hex HMAC-SHA256 of "<t>.<body>">` using the returned secret.
+// @Accept json
+// @Produce json
+// @Tags webhooks
+// @Success 201 {object} Webhook
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /webhooks [post]
+// @Param message body CreateWebhookReq true "Post Body"
+func (h *Handlers) CreateWebhookHandler(ctx *fiber.Ctx) error {
+	req := new(CreateWebhookReq)
+	if err := ctx.BodyParser(req); err != nil {
+		logging.FromContext(ctx.UserContext()).WithError(err).Warn("Failed to parse CreateWebhook")
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+	if errs := api.ValidateStruct(req); errs != nil {
+		return errs
+	}
+
+	webhook, err := h.scoped(ctx).CreateWebhook(ctx.UserContext(), req)
+	if err != nil {
+		return httpError(err)
+	}
+
+	ctx.Status(http.StatusCreated)
+	return ctx.JSON(webhook)
+}
+
+// ListWebhooksHandler GET - Lists webhooks
+// @Summary List webhooks
+// @Produce json
+// @Tags webhooks
+// @Success 200 {object} []Webhook
+// @Failure 500 {object} api.ErrorResponse
+// @Router /webhooks [get]
+func (h *Handlers) ListWebhooksHandler(ctx *fiber.Ctx) error {
+	webhooks, err := h.scoped(ctx).ListWebhooks(ctx.UserContext())
+	if err != nil {
+		return httpError(err)
+	}
+	return ctx.JSON(webhooks)
+}
+
+// GetWebhookHandler GET - Retrieves a webhook
+// @Summary Retrieve a webhook
+// @Produce json
+// @Tags webhooks
+// @Success 200 {object} Webhook
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /webhooks/{id} [get]
+// @Param id path string true "Webhook ID"
+func (h *Handlers) GetWebhookHandler(ctx *fiber.Ctx) error {
+	webhook, err := h.scoped(ctx).GetWebhook(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return httpError(err)
+	}
+	return ctx.JSON(webhook)
+}
+
+// DeleteWebhookHandler DELETE - Removes a webhook
+// @Summary Delete a webhook
+// @Tags webhooks
+// @Success 204
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /webhooks/{id} [delete]
+// @Param id path string true "Webhook ID"
+func (h *Handlers) DeleteWebhookHandler(ctx *fiber.Ctx) error {
+	if err := h.scoped(ctx).DeleteWebhook(ctx.UserContext(), ctx.Params("id")); err != nil {
+		return httpError(err)
+	}
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// ListDeliveriesHandler GET - Webhook delivery log
+// @Summary List recent deliveries of a webhook, newest first
+// @Produce json
+// @Tags webhooks
+// @Success 200 {object} []Delivery
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /webhooks/{id}/deliveries [get]
+// @Param id path string true "Webhook ID"
+// @Param limit query int false "Number of Deliveries" default(20)
+func (h *Handlers) ListDeliveriesHandler(ctx *fiber.Ctx) error {
+	limit, err := strconv.ParseInt(ctx.Query("limit", "20"), 10, 64)
+	if err != nil || limit < 1 || limit > 100 {
+		return fiber.NewError(http.StatusBadRequest, "limit: invalid value")
+	}
+
+	deliveries, err := h.scoped(ctx).ListDeliveries(ctx.UserContext(), ctx.Params("id"), limit)
+	if err != nil {
+		return httpError(err)
+	}
+	return ctx.JSON(deliveries)
+}
+
+// RedeliverHandler POST - Resends a past delivery
+// @Summary Queue a new delivery of a past event
+// @Produce json
+// @Tags webhooks
+// @Success 202 {object} Delivery
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /webhooks/deliveries/{id}/redeliver [post]
+// @Param id path string true "Delivery ID"
+func (h *Handlers) RedeliverHandler(ctx *fiber.Ctx) error {
+	delivery, err := h.scoped(ctx).Redeliver(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return httpError(err)
+	}
+	ctx.Status(http.StatusAccepted)
+	return ctx.JSON(delivery)
+}
+
+// httpError
+// Maps webhook errors onto fiber errors,
+// any other error is passed through as an internal error.
+func httpError(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return fiber.NewError(http.StatusNotFound, err.Error())
+	}
+	return err
+}
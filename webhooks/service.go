@@ -0,0 +1,187 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/soulxburn/mdsnips/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Service
+// Manages webhook subscriptions and their delivery log.
+type Service struct {
+	db       *mongo.Database
+	timeouts config.Timeouts
+	// Tenant the service is scoped to, empty when tenancy is disabled.
+	tenant string
+}
+
+// InitService Creates an instance of a Service
+// Requires a reference to a mongo.Client instance
+func InitService(mClient *mongo.Client, cfg *config.Config) *Service {
+	database := cfg.Mongo.Database
+	if database == "" {
+		database = "mdsnips"
+	}
+	timeouts := cfg.Timeouts
+	if timeouts.Read <= 0 {
+		timeouts.Read = 5 * time.Second
+	}
+	if timeouts.Write <= 0 {
+		timeouts.Write = 5 * time.Second
+	}
+	return &Service{db: mClient.Database(database), timeouts: timeouts}
+}
+
+// ForTenant
+// Returns a copy of the Service scoped to tenant.
+func (s *Service) ForTenant(tenant string) *Service {
+	scoped := *s
+	scoped.tenant = tenant
+	return &scoped
+}
+
+// CreateWebhook
+// Subscribes req.URL to events, returning
+// the webhook with its generated signing secret.
+func (s *Service) CreateWebhook(ctx context.Context, req *CreateWebhookReq) (*Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	events := req.Events
+	if events == nil {
+		events = []string{}
+	}
+	webhook := &Webhook{
+		ID:         uuid.NewString(),
+		URL:        req.URL,
+		Events:     events,
+		Secret:     hex.EncodeToString(secret),
+		CreateDate: time.Now(),
+		Tenant:     s.tenant,
+	}
+	if _, err := s.db.Collection(WebhooksCollection).InsertOne(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// ListWebhooks
+// Returns every webhook of the tenant, without secrets.
+func (s *Service) ListWebhooks(ctx context.Context) ([]*Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	webhooks := make([]*Webhook, 0)
+	opts := options.Find().
+		SetProjection(bson.M{"secret": 0}).
+		SetSort(bson.D{{Key: "createDate", Value: 1}})
+	cursor, err := s.db.Collection(WebhooksCollection).Find(ctx, bson.M{"tenant": s.tenant}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, cursor.All(ctx, &webhooks)
+}
+
+// GetWebhook
+// Returns webhook id without its secret, or ErrNotFound.
+func (s *Service) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	webhook := new(Webhook)
+	filter := bson.M{"id": id, "tenant": s.tenant}
+	opts := options.FindOne().SetProjection(bson.M{"secret": 0})
+	if err := s.db.Collection(WebhooksCollection).FindOne(ctx, filter, opts).Decode(webhook); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("webhook %s: %w", id, ErrNotFound)
+		}
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook
+// Unsubscribes webhook id. Pending deliveries fail on their next attempt.
+func (s *Service) DeleteWebhook(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	result, err := s.db.Collection(WebhooksCollection).DeleteOne(ctx, bson.M{"id": id, "tenant": s.tenant})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("webhook %s: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// ListDeliveries
+// Returns the most recent deliveries to webhook id, newest first.
+func (s *Service) ListDeliveries(ctx context.Context, webhookID string, limit int64) ([]*Delivery, error) {
+	if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	deliveries := make([]*Delivery, 0)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createDate", Value: -1}}).
+		SetLimit(limit)
+	filter := bson.M{"webhookId": webhookID, "tenant": s.tenant}
+	cursor, err := s.db.Collection(DeliveriesCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, cursor.All(ctx, &deliveries)
+}
+
+// Redeliver
+// Queues a new delivery of the event sent by delivery id,
+// to the same webhook, regardless of how the original ended.
+func (s *Service) Redeliver(ctx context.Context, id string) (*Delivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	deliveries := s.db.Collection(DeliveriesCollection)
+	original := new(Delivery)
+	if err := deliveries.FindOne(ctx, bson.M{"id": id, "tenant": s.tenant}).Decode(original); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("delivery %s: %w", id, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	redelivery := &Delivery{
+		ID:            uuid.NewString(),
+		WebhookID:     original.WebhookID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        StatusPending,
+		Attempts:      []Attempt{},
+		NextAttemptAt: &now,
+		RedeliveryOf:  original.ID,
+		CreateDate:    now,
+		Tenant:        original.Tenant,
+	}
+	if _, err := deliveries.InsertOne(ctx, redelivery); err != nil {
+		return nil, err
+	}
+	return redelivery, nil
+}
@@ -0,0 +1,66 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Delivery request headers.
+const (
+	SignatureHeader = "X-MDSnips-Signature"
+	EventHeader     = "X-MDSnips-Event"
+	DeliveryHeader  = "X-MDSnips-Delivery"
+)
+
+// ErrInvalidSignature Returned by Verify when a signature does not match.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign
+// Returns the X-MDSnips-Signature value for body sent at timestamp:
+// `t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">`.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", t, signature(secret, t, body))
+}
+
+// Verify
+// Checks an X-MDSnips-Signature header against body, rejecting
+// signatures older than tolerance to prevent replays. Receivers
+// should call it with the raw request body before parsing it.
+func Verify(secret string, header string, body []byte, tolerance time.Duration) error {
+	var t, v1 string
+	for _, part := range strings.Split(header, ",") {
+		switch {
+		case strings.HasPrefix(part, "t="):
+			t = part[len("t="):]
+		case strings.HasPrefix(part, "v1="):
+			v1 = part[len("v1="):]
+		}
+	}
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || v1 == "" {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 && time.Since(time.Unix(unix, 0)) > tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(v1), []byte(signature(secret, t, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signature
+// Returns the hex HMAC-SHA256 of "<t>.<body>" keyed by secret.
+func signature(secret, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
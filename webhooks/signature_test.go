@@ -0,0 +1,40 @@
+package webhooks
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_SignVerify
+// Signatures verify against the signed body only,
+// within the timestamp tolerance.
+func Test_SignVerify(t *testing.T) {
+	body := []byte(`{"type":"snippet.created"}`)
+	header := Sign("secret", time.Now(), body)
+
+	assert.Nil(t, Verify("secret", header, body, 5*time.Minute))
+	assert.True(t, errors.Is(Verify("other", header, body, 5*time.Minute), ErrInvalidSignature))
+	assert.True(t, errors.Is(Verify("secret", header, []byte(`{}`), 5*time.Minute), ErrInvalidSignature))
+	assert.True(t, errors.Is(Verify("secret", "v1=abc", body, 0), ErrInvalidSignature))
+
+	stale := Sign("secret", time.Now().Add(-time.Hour), body)
+	assert.True(t, errors.Is(Verify("secret", stale, body, 5*time.Minute), ErrInvalidSignature))
+	assert.Nil(t, Verify("secret", stale, body, 0))
+}
+
+// Test_Backoff
+// Waits double from RetryInitial up to RetryMax.
+func Test_Backoff(t *testing.T) {
+	d := &Dispatcher{}
+	d.cfg.RetryInitial = 30 * time.Second
+	d.cfg.RetryMax = 5 * time.Minute
+
+	assert.Equal(t, 30*time.Second, d.backoff(1))
+	assert.Equal(t, time.Minute, d.backoff(2))
+	assert.Equal(t, 4*time.Minute, d.backoff(4))
+	assert.Equal(t, 5*time.Minute, d.backoff(5))
+	assert.Equal(t, 5*time.Minute, d.backoff(50))
+}
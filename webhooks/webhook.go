@@ -0,0 +1,106 @@
+package webhooks
+
+import (
+	"errors"
+	"time"
+)
+
+// Webhook collections, in the configured database.
+const (
+	WebhooksCollection   = "webhooks"
+	DeliveriesCollection = "webhook_deliveries"
+)
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// ErrNotFound Returned when a webhook or delivery does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Webhook
+// A subscription delivering snippet lifecycle events to a URL.
+type Webhook struct {
+	// Webhook guid.
+	ID string `json:"id" bson:"id" format:"uuid"`
+	// Endpoint receiving event deliveries.
+	URL string `json:"url" bson:"url" example:"https://bot.example.com/mdsnips"`
+	// Subscribed event types, every type when empty.
+	Events []string `json:"events" bson:"events" example:"snippet.created,snippet.updated"`
+	// HMAC-SHA256 signing secret, only returned when created.
+	Secret string `json:"secret,omitempty" bson:"secret" example:"4f1c0a..."`
+	// Date the webhook was created.
+	CreateDate time.Time `json:"createDate" bson:"createDate" format:"date-time"`
+	// Owning tenant, empty when tenancy is disabled.
+	Tenant string `json:"-" bson:"tenant"`
+}
+
+// CreateWebhookReq
+type CreateWebhookReq struct {
+	// Endpoint receiving event deliveries.
+	URL string `json:"url" validate:"required,url,max=2048" example:"https://bot.example.com/mdsnips"`
+	// Event types to subscribe to, every type when empty.
+	Events []string `json:"events" validate:"dive,oneof=snippet.created snippet.updated snippet.deleted snippet.restored" example:"snippet.created"`
+}
+
+// subscribes
+// Reports whether the webhook subscribes to eventType.
+func (w *Webhook) subscribes(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range w.Events {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery
+// An event sent, or to be sent, to a webhook, with every attempt made.
+type Delivery struct {
+	// Delivery guid, sent as X-MDSnips-Delivery.
+	ID string `json:"id" bson:"id" format:"uuid"`
+	// Receiving webhook guid.
+	WebhookID string `json:"webhookId" bson:"webhookId" format:"uuid"`
+	// Delivered event guid.
+	EventID string `json:"eventId" bson:"eventId" format:"uuid"`
+	// Delivered event type.
+	EventType string `json:"eventType" bson:"eventType" example:"snippet.created"`
+	// JSON request body.
+	Payload string `json:"payload" bson:"payload"`
+	// One of pending, succeeded or failed.
+	Status string `json:"status" bson:"status" example:"succeeded"`
+	// Every attempt made, oldest first.
+	Attempts []Attempt `json:"attempts" bson:"attempts"`
+	// Date of the next attempt while pending.
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty" bson:"nextAttemptAt,omitempty" format:"date-time"`
+	// Delivery guid this resends, for redeliveries.
+	RedeliveryOf string `json:"redeliveryOf,omitempty" bson:"redeliveryOf,omitempty" format:"uuid"`
+	// Date the delivery was created.
+	CreateDate time.Time `json:"createDate" bson:"createDate" format:"date-time"`
+	// Owning tenant, empty when tenancy is disabled.
+	Tenant string `json:"-" bson:"tenant"`
+	// Identifies the original delivery of an event to a webhook,
+	// so relaying an event twice creates it once.
+	DedupeKey string `json:"-" bson:"dedupeKey,omitempty"`
+	// Claimed by a dispatcher until this date.
+	LockedUntil *time.Time `json:"-" bson:"lockedUntil,omitempty"`
+}
+
+// Attempt
+// Outcome of one delivery attempt.
+type Attempt struct {
+	// Date the attempt was made.
+	Date time.Time `json:"date" bson:"date" format:"date-time"`
+	// Response status code, 0 when no response was received.
+	StatusCode int `json:"statusCode" bson:"statusCode" example:"200"`
+	// Transport error or non 2xx status.
+	Error string `json:"error,omitempty" bson:"error,omitempty"`
+	// Round trip duration in milliseconds.
+	DurationMs int64 `json:"durationMs" bson:"durationMs" example:"42"`
+}